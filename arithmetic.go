@@ -60,32 +60,35 @@ func NewArithmetic(t ArithmeticType, a Indicator, b Indicator, o ArithmeticOpts)
 }
 
 func (i *arith) GetValueForInterval(t time.Time) *Interval {
-	// validate if needed
-	a := i.a.GetValueForInterval(t)
-	b := i.b.GetValueForInterval(t)
-	v := i.generateValue(a, b)
-	if v == nil {
+	v, ok := i.value(t)
+	if !ok {
 		return nil
 	}
 	return &Interval{
 		StartTime: t,
-		Value:     *v,
+		Value:     v,
 	}
 }
 
-func (i *arith) generateValue(ai, bi *Interval) *float64 {
-	if ai == nil || bi == nil {
+func (i *arith) value(t time.Time) (float64, bool) {
+	// validate if needed
+	a, aok := valueOf(i.a, t)
+	b, bok := valueOf(i.b, t)
+	if !aok || !bok {
 		switch i.o.NilHandlInst {
 		case NilValueReturnNil:
-			return nil
+			return 0, false
 		case NilValueReturnZero:
-			val := 0.0
-			return &val
+			return 0, true
 		}
 	}
+	return i.generateValue(a, b), true
+}
+
+func (i *arith) generateValue(av, bv float64) float64 {
 	var val decimal.Decimal
-	a := decimal.NewFromFloat(ai.Value)
-	b := decimal.NewFromFloat(bi.Value)
+	a := decimal.NewFromFloat(av)
+	b := decimal.NewFromFloat(bv)
 	switch i.t {
 	case ArithmeticAddition:
 		val = a.Add(b)
@@ -111,7 +114,7 @@ func (i *arith) generateValue(ai, bi *Interval) *float64 {
 		}
 	}
 	f64, _ := val.Float64()
-	return &f64
+	return f64
 }
 
 func (i *arith) Update(v OHLCV) error {
@@ -125,6 +128,31 @@ func (i *arith) Update(v OHLCV) error {
 	return nil
 }
 
+func (i *arith) inputs() []node {
+	return nodesOf(i.a, i.b)
+}
+
+// updateNode does nothing since values are derived from inputs when read
+func (i *arith) updateNode(v OHLCV) error {
+	return nil
+}
+
+func (i *arith) reset() {
+	resetIndicator(i.a)
+	resetIndicator(i.b)
+}
+
+// rewind does nothing since values are derived from inputs when read
+func (i *arith) rewind(t time.Time) error {
+	if err := rewindIndicator(i.a, t); err != nil {
+		return err
+	}
+	if err := rewindIndicator(i.b, t); err != nil {
+		return err
+	}
+	return nil
+}
+
 func (i *arith) ApplyOpts(opts SeriesOpts) error {
 	// validate if needed
 	if err := i.a.ApplyOpts(opts); err != nil {
@@ -135,3 +163,7 @@ func (i *arith) ApplyOpts(opts SeriesOpts) error {
 	}
 	return nil
 }
+
+func (i *arith) encodeState(e *encoder) {}
+
+func (i *arith) decodeState(d *decoder) {}
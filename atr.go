@@ -0,0 +1,114 @@
+package pine
+
+import (
+	"errors"
+	"time"
+)
+
+type atr struct {
+	valueStore
+	lookback int
+	// states include the one before the oldest kept interval so any kept
+	// interval can be generated again
+	states *ring[atrState]
+}
+
+type atrState struct {
+	Time  time.Time
+	Close float64
+	Avg   expAvg
+}
+
+// NewATR creates a new average true range indicator. True range is smoothed
+// with Wilder's moving average (RMA) like Pine's ta.atr, which uses high
+// minus low on the first interval
+func NewATR(lookback int) Indicator {
+	return &atr{
+		lookback: lookback,
+		states:   newRing[atrState](defaultMax, 0),
+	}
+}
+
+func (i *atr) Update(v OHLCV) error {
+	return i.updateNode(v)
+}
+
+func (i *atr) inputs() []node {
+	return nil
+}
+
+func (i *atr) updateNode(v OHLCV) error {
+	if i.states.len() > 0 && v.S.Before(i.states.lastTime()) {
+		// already generated
+		return nil
+	}
+	prev, ok := i.states.prior(v.S)
+	if !ok {
+		prev = atrState{
+			Avg: newRMAState(i.lookback),
+		}
+	}
+	rng := v.H - v.L
+	if !prev.Time.IsZero() {
+		rng = trueRange(v, prev.Close)
+	}
+	cur := atrState{
+		Time:  v.S,
+		Close: v.C,
+		Avg:   prev.Avg.next(rng),
+	}
+	i.states.set(v.S, cur)
+	if !cur.Avg.ready() {
+		return nil
+	}
+	i.setValue(v.S, cur.Avg.value)
+	return nil
+}
+
+func (i *atr) ApplyOpts(opts SeriesOpts) error {
+	if opts.Max < i.lookback {
+		return errors.New("SeriesOpts max cannot be less than ATR lookback value")
+	}
+	i.initStore(opts)
+	i.states = historyRing(i.states, opts)
+	return nil
+}
+
+func (i *atr) reset() {
+	i.resetStore()
+	i.states.clear()
+}
+
+func (i *atr) rewind(t time.Time) error {
+	i.rewindStore(t)
+	i.states.rewind(t)
+	return nil
+}
+
+func (i *atr) encodeState(e *encoder) {
+	i.valueStore.encode(e)
+	encodeRing(e, i.states, func(e *encoder, s atrState) {
+		s.encode(e)
+	})
+}
+
+func (i *atr) decodeState(d *decoder) {
+	i.valueStore.decode(d)
+	decodeRing(d, i.states, func(d *decoder) atrState {
+		var s atrState
+		s.decode(d)
+		return s
+	})
+}
+
+func (s atrState) encode(e *encoder) {
+	e.time(s.Time)
+	e.float(s.Close)
+	s.Avg.encode(e)
+}
+
+func (s *atrState) decode(d *decoder) {
+	s.Time = d.time()
+	s.Close = d.float()
+	s.Avg.decode(d)
+}
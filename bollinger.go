@@ -0,0 +1,191 @@
+package pine
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// BollingerBands holds the outputs of the Bollinger Bands indicator.
+// Each output can be registered on Series separately
+type BollingerBands struct {
+	// Upper is Basis plus multiplier times standard deviation
+	Upper Indicator
+	// Basis is the simple moving average of source
+	Basis Indicator
+	// Lower is Basis minus multiplier times standard deviation
+	Lower Indicator
+	// PercentB is (source - Lower) / (Upper - Lower)
+	PercentB Indicator
+	// Bandwidth is (Upper - Lower) / Basis like Pine's ta.bbw
+	Bandwidth Indicator
+}
+
+const (
+	bbOutputUpper = iota
+	bbOutputBasis
+	bbOutputLower
+	bbOutputPercentB
+	bbOutputBandwidth
+)
+
+type bb struct {
+	lookback  int
+	mult      float64
+	genvalues *ring[bbValue]
+	window    *rollingWindow
+	src       Indicator
+}
+
+// bbValue keeps outputs of an interval where PercentB and Bandwidth are NaN
+// if na
+type bbValue struct {
+	Time      time.Time
+	Upper     float64
+	Basis     float64
+	Lower     float64
+	PercentB  float64
+	Bandwidth float64
+}
+
+// NewBollingerBands creates a new Bollinger Bands indicator like Pine's ta.bb
+func NewBollingerBands(i Indicator, lookback int, mult float64) BollingerBands {
+	b := &bb{
+		src:       i,
+		lookback:  lookback,
+		mult:      mult,
+		window:    newRollingWindow(lookback),
+		genvalues: newRing[bbValue](defaultMax, 0),
+	}
+	return BollingerBands{
+		Upper:     newOutput(b, bbOutputUpper),
+		Basis:     newOutput(b, bbOutputBasis),
+		Lower:     newOutput(b, bbOutputLower),
+		PercentB:  newOutput(b, bbOutputPercentB),
+		Bandwidth: newOutput(b, bbOutputBandwidth),
+	}
+}
+
+func (i *bb) getOutput(t time.Time, idx int) (float64, bool) {
+	v, ok := i.genvalues.getTime(t)
+	if !ok {
+		return 0, false
+	}
+	switch idx {
+	case bbOutputUpper:
+		return v.Upper, true
+	case bbOutputBasis:
+		return v.Basis, true
+	case bbOutputLower:
+		return v.Lower, true
+	case bbOutputPercentB:
+		return v.PercentB, !math.IsNaN(v.PercentB)
+	case bbOutputBandwidth:
+		return v.Bandwidth, !math.IsNaN(v.Bandwidth)
+	}
+	return 0, false
+}
+
+func (i *bb) Update(v OHLCV) error {
+	if err := i.src.Update(v); err != nil {
+		return fmt.Errorf("error received from src in BollingerBands: %w", err)
+	}
+	return i.updateNode(v)
+}
+
+func (i *bb) inputs() []node {
+	return nodesOf(i.src)
+}
+
+func (i *bb) updateNode(v OHLCV) error {
+	if i.window.before(v.S) {
+		// already generated
+		return nil
+	}
+	val, ok := valueOf(i.src, v.S)
+	if !ok {
+		return nil
+	}
+	i.window.set(v.S, val)
+	i.generateBands(v.S, val)
+	return nil
+}
+
+func (i *bb) generateBands(t time.Time, src float64) {
+	if !i.window.full() {
+		return
+	}
+	basis := i.window.average()
+	dev := i.window.stdDev() * i.mult
+	gv := bbValue{
+		Time:      t,
+		Upper:     basis + dev,
+		Basis:     basis,
+		Lower:     basis - dev,
+		PercentB:  math.NaN(),
+		Bandwidth: math.NaN(),
+	}
+	if width := gv.Upper - gv.Lower; width != 0 {
+		gv.PercentB = (src - gv.Lower) / width
+	}
+	if basis != 0 {
+		gv.Bandwidth = (gv.Upper - gv.Lower) / basis
+	}
+	i.genvalues.set(t, gv)
+}
+
+func (i *bb) ApplyOpts(opts SeriesOpts) error {
+	if opts.Max < i.lookback {
+		return errors.New("SeriesOpts max cannot be less than BollingerBands lookback value")
+	}
+	if err := i.src.ApplyOpts(opts); err != nil {
+		return fmt.Errorf("error applying opts in source: %w", err)
+	}
+	if i.genvalues.capacity() != opts.Max {
+		i.genvalues = newSeriesRing[bbValue](opts)
+	}
+	i.window.applyOpts(opts)
+	return nil
+}
+
+func (i *bb) reset() {
+	resetIndicator(i.src)
+	i.window.reset()
+	i.genvalues.clear()
+}
+
+func (i *bb) rewind(t time.Time) error {
+	if err := rewindIndicator(i.src, t); err != nil {
+		return err
+	}
+	i.window.rewind(t)
+	i.genvalues.rewind(t)
+	return nil
+}
+
+func (i *bb) encodeState(e *encoder) {
+	i.window.encode(e)
+	encodeRing(e, i.genvalues, func(e *encoder, v bbValue) {
+		e.time(v.Time)
+		e.float(v.Upper)
+		e.float(v.Basis)
+		e.float(v.Lower)
+		e.float(v.PercentB)
+		e.float(v.Bandwidth)
+	})
+}
+
+func (i *bb) decodeState(d *decoder) {
+	i.window.decode(d)
+	decodeRing(d, i.genvalues, func(d *decoder) bbValue {
+		return bbValue{
+			Time:      d.time(),
+			Upper:     d.float(),
+			Basis:     d.float(),
+			Lower:     d.float(),
+			PercentB:  d.float(),
+			Bandwidth: d.float(),
+		}
+	})
+}
@@ -9,11 +9,10 @@ import (
 )
 
 type chg struct {
-	lastUpdate OHLCV
-	lookback   int
-	opts       *SeriesOpts
-	chgopts    *ChangeOpts
-	src        Indicator
+	lookback int
+	bars     *ring[struct{}]
+	chgopts  *ChangeOpts
+	src      Indicator
 }
 
 type ChangeOpts struct {
@@ -32,6 +31,7 @@ func NewChange(i Indicator, lookback int, opts *ChangeOpts) Indicator {
 	return &chg{
 		chgopts:  opts,
 		lookback: lookback,
+		bars:     newRing[struct{}](defaultMax, 0),
 		src:      i,
 	}
 }
@@ -41,7 +41,11 @@ func (i *chg) GetValueForInterval(t time.Time) *Interval {
 	if v1 == nil {
 		return nil
 	}
-	v2 := i.src.GetValueForInterval(t.Add(-1 * time.Duration(i.lookback*i.opts.Interval) * time.Second))
+	bt, ok := barsBack(i.bars, t, i.lookback)
+	if !ok {
+		return nil
+	}
+	v2 := i.src.GetValueForInterval(bt)
 	if v2 == nil {
 		// handle empty case values
 		return nil
@@ -63,6 +67,16 @@ func (i *chg) Update(v OHLCV) error {
 	if err := i.src.Update(v); err != nil {
 		return fmt.Errorf("error received from src in Change: %w", err)
 	}
+	return i.updateNode(v)
+}
+
+func (i *chg) inputs() []node {
+	return nodesOf(i.src)
+}
+
+// updateNode keeps the interval so lookback counts bars
+func (i *chg) updateNode(v OHLCV) error {
+	i.bars.set(v.S, struct{}{})
 	return nil
 }
 
@@ -73,6 +87,29 @@ func (i *chg) ApplyOpts(opts SeriesOpts) error {
 	if err := i.src.ApplyOpts(opts); err != nil {
 		return fmt.Errorf("error applying opts in source: %w", err)
 	}
-	i.opts = &opts
+	if i.bars.capacity() != opts.Max {
+		i.bars = newSeriesRing[struct{}](opts)
+	}
 	return nil
 }
+
+func (i *chg) reset() {
+	resetIndicator(i.src)
+	i.bars.clear()
+}
+
+func (i *chg) rewind(t time.Time) error {
+	if err := rewindIndicator(i.src, t); err != nil {
+		return err
+	}
+	i.bars.rewind(t)
+	return nil
+}
+
+func (i *chg) encodeState(e *encoder) {
+	encodeRing(e, i.bars, putBar)
+}
+
+func (i *chg) decodeState(d *decoder) {
+	decodeRing(d, i.bars, getBar)
+}
@@ -0,0 +1,57 @@
+// Command pine2go transpiles a Pine script into Go source adding its
+// indicators to a pine.Series
+//
+// Usage:
+//
+//	pine2go [-pkg name] [-func name] [-o file] script.pine
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/xpt-nl/pine/script"
+)
+
+func main() {
+	pkg := flag.String("pkg", "indicators", "package of the generated file")
+	fn := flag.String("func", "AddIndicators", "name of the generated function")
+	out := flag.String("o", "", "output file, standard output if empty")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: pine2go [flags] script.pine\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(flag.Arg(0), *out, script.GenerateOpts{Package: *pkg, Func: *fn}); err != nil {
+		fmt.Fprintf(os.Stderr, "pine2go: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(in, out string, opts script.GenerateOpts) error {
+	src, err := os.ReadFile(in)
+	if err != nil {
+		return err
+	}
+	sc, err := script.Compile(string(src))
+	if err != nil {
+		// script errors are prefixed with the file like compiler errors
+		return fmt.Errorf("%s:%w", in, err)
+	}
+	opts.Source = filepath.Base(in)
+	code, err := sc.Generate(opts)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(code)
+		return err
+	}
+	return os.WriteFile(out, code, 0o644)
+}
@@ -0,0 +1,221 @@
+package config
+
+import "github.com/xpt-nl/pine"
+
+var (
+	sourceParam = Param{Name: "source", Kind: ParamIndicator}
+	lengthParam = Param{Name: "length", Kind: ParamLength}
+)
+
+// single returns the outputs of a constructor with one output
+func single(i pine.Indicator) []pine.Indicator {
+	return []pine.Indicator{i}
+}
+
+// sourceLength returns a constructor of indicators of a source over length
+// intervals
+func sourceLength(fn func(i pine.Indicator, lookback int) pine.Indicator) Constructor {
+	return Constructor{
+		Params: []Param{sourceParam, lengthParam},
+		New: func(a Args) []pine.Indicator {
+			return single(fn(a.Indicator("source"), a.Int("length")))
+		},
+	}
+}
+
+// length returns a constructor of indicators of OHLCV over length intervals
+func length(fn func(lookback int) pine.Indicator) Constructor {
+	return Constructor{
+		Params: []Param{lengthParam},
+		New: func(a Args) []pine.Indicator {
+			return single(fn(a.Int("length")))
+		},
+	}
+}
+
+func cross(fn func(a, b pine.Indicator) pine.Indicator) Constructor {
+	return Constructor{
+		Params: []Param{
+			{Name: "a", Kind: ParamIndicator},
+			{Name: "b", Kind: ParamIndicator},
+		},
+		New: func(a Args) []pine.Indicator {
+			return single(fn(a.Indicator("a"), a.Indicator("b")))
+		},
+	}
+}
+
+func percentile(fn func(i pine.Indicator, lookback int, p float64) pine.Indicator) Constructor {
+	return Constructor{
+		Params: []Param{sourceParam, lengthParam, {Name: "percentage", Kind: ParamFloat}},
+		New: func(a Args) []pine.Indicator {
+			return single(fn(a.Indicator("source"), a.Int("length"), a.Float("percentage")))
+		},
+	}
+}
+
+var ohlcPropNames = []string{"open", "high", "low", "close", "volume", "hl2", "hlc3"}
+
+var arithmeticTypes = map[string]pine.ArithmeticType{
+	"add":     pine.ArithmeticAddition,
+	"sub":     pine.ArithmeticSubtraction,
+	"mul":     pine.ArithmeticMultiplication,
+	"div":     pine.ArithmeticDivision,
+	"absdiff": pine.ArithmeticAbsDiff,
+	"max":     pine.ArithmeticMax,
+	"min":     pine.ArithmeticMin,
+}
+
+var vwapAnchors = map[string]pine.VWAPAnchor{
+	"session": pine.VWAPAnchorSession,
+	"day":     pine.VWAPAnchorDay,
+	"week":    pine.VWAPAnchorWeek,
+	"month":   pine.VWAPAnchorMonth,
+}
+
+// builtins are the indicators of pine registered in every registry
+var builtins = map[string]Constructor{
+	"ohlcprop": {
+		Params: []Param{{Name: "prop", Kind: ParamString, Values: ohlcPropNames}},
+		New: func(a Args) []pine.Indicator {
+			return single(pine.NewOHLCProp(ohlcProps[a.String("prop")]))
+		},
+	},
+	"constant": {
+		Params: []Param{{Name: "value", Kind: ParamFloat}},
+		New: func(a Args) []pine.Indicator {
+			return single(pine.NewConstant(a.Float("value")))
+		},
+	},
+	"arith": {
+		Params: []Param{
+			{Name: "op", Kind: ParamString, Values: []string{"add", "sub", "mul", "div", "absdiff", "max", "min"}},
+			{Name: "a", Kind: ParamIndicator},
+			{Name: "b", Kind: ParamIndicator},
+			{Name: "nil", Kind: ParamString, Optional: true, Default: "nil", Values: []string{"nil", "zero"}},
+		},
+		New: func(a Args) []pine.Indicator {
+			opts := pine.ArithmeticOpts{}
+			if a.String("nil") == "zero" {
+				opts.NilHandlInst = pine.NilValueReturnZero
+			}
+			return single(pine.NewArithmetic(arithmeticTypes[a.String("op")], a.Indicator("a"), a.Indicator("b"), opts))
+		},
+	},
+	"change": {
+		Params: []Param{
+			sourceParam,
+			{Name: "length", Kind: ParamLength, Optional: true, Default: 1},
+			{Name: "diff", Kind: ParamString, Optional: true, Default: "diff", Values: []string{"diff", "ratio"}},
+		},
+		New: func(a Args) []pine.Indicator {
+			opts := &pine.ChangeOpts{DiffType: pine.ChangeDiffTypeDiff}
+			if a.String("diff") == "ratio" {
+				opts.DiffType = pine.ChangeDiffTypeRatio
+			}
+			return single(pine.NewChange(a.Indicator("source"), a.Int("length"), opts))
+		},
+	},
+	"previous":                sourceLength(pine.NewPrevious),
+	"sma":                     sourceLength(pine.NewSMA),
+	"ema":                     sourceLength(pine.NewEMA),
+	"rma":                     sourceLength(pine.NewRMA),
+	"wma":                     sourceLength(pine.NewWMA),
+	"hma":                     sourceLength(pine.NewHMA),
+	"vwma":                    sourceLength(pine.NewVWMA),
+	"dema":                    sourceLength(pine.NewDEMA),
+	"tema":                    sourceLength(pine.NewTEMA),
+	"zlema":                   sourceLength(pine.NewZLEMA),
+	"rsi":                     sourceLength(pine.NewRSI),
+	"stddev":                  sourceLength(pine.NewStdDev),
+	"linreg":                  sourceLength(pine.NewLinReg),
+	"median":                  sourceLength(pine.NewMedian),
+	"percentrank":             sourceLength(pine.NewPercentRank),
+	"highest":                 sourceLength(pine.NewHighest),
+	"lowest":                  sourceLength(pine.NewLowest),
+	"highestbars":             sourceLength(pine.NewHighestBars),
+	"lowestbars":              sourceLength(pine.NewLowestBars),
+	"percentile":              percentile(pine.NewPercentile),
+	"percentile_nearest_rank": percentile(pine.NewPercentileNearestRank),
+	"alma": {
+		Params: []Param{
+			sourceParam,
+			lengthParam,
+			{Name: "offset", Kind: ParamFloat, Optional: true, Default: 0.85},
+			{Name: "sigma", Kind: ParamFloat, Optional: true, Default: 6.0},
+		},
+		New: func(a Args) []pine.Indicator {
+			return single(pine.NewALMA(a.Indicator("source"), a.Int("length"), a.Float("offset"), a.Float("sigma")))
+		},
+	},
+	"swma": {
+		Params: []Param{sourceParam},
+		New: func(a Args) []pine.Indicator {
+			return single(pine.NewSWMA(a.Indicator("source")))
+		},
+	},
+	"atr": length(pine.NewATR),
+	"wpr": length(pine.NewWilliamsR),
+	"tr": {
+		New: func(a Args) []pine.Indicator {
+			return single(pine.NewTrueRange())
+		},
+	},
+	"crossover":  cross(pine.NewCrossover),
+	"crossunder": cross(pine.NewCrossunder),
+	"cross":      cross(pine.NewCross),
+	"macd": {
+		Params: []Param{
+			sourceParam,
+			{Name: "fast", Kind: ParamLength},
+			{Name: "slow", Kind: ParamLength},
+			{Name: "signal", Kind: ParamLength},
+		},
+		Outputs: []string{"macd", "signal", "histogram"},
+		New: func(a Args) []pine.Indicator {
+			m := pine.NewMACD(a.Indicator("source"), a.Int("fast"), a.Int("slow"), a.Int("signal"))
+			return []pine.Indicator{m.MACD, m.Signal, m.Histogram}
+		},
+	},
+	"bb": {
+		Params:  []Param{sourceParam, lengthParam, {Name: "mult", Kind: ParamFloat}},
+		Outputs: []string{"upper", "basis", "lower", "percentb", "bandwidth"},
+		New: func(a Args) []pine.Indicator {
+			bb := pine.NewBollingerBands(a.Indicator("source"), a.Int("length"), a.Float("mult"))
+			return []pine.Indicator{bb.Upper, bb.Basis, bb.Lower, bb.PercentB, bb.Bandwidth}
+		},
+	},
+	"kc": {
+		Params: []Param{
+			sourceParam,
+			lengthParam,
+			{Name: "mult", Kind: ParamFloat},
+			{Name: "use_true_range", Kind: ParamBool, Optional: true, Default: true},
+		},
+		Outputs: []string{"upper", "basis", "lower"},
+		New: func(a Args) []pine.Indicator {
+			kc := pine.NewKeltnerChannels(a.Indicator("source"), a.Int("length"), a.Float("mult"), a.Bool("use_true_range"))
+			return []pine.Indicator{kc.Upper, kc.Basis, kc.Lower}
+		},
+	},
+	"stoch": {
+		Params: []Param{
+			{Name: "k_length", Kind: ParamLength},
+			{Name: "k_smoothing", Kind: ParamLength, Optional: true, Default: 1},
+			{Name: "d_smoothing", Kind: ParamLength, Optional: true, Default: 3},
+		},
+		Outputs: []string{"k", "d"},
+		New: func(a Args) []pine.Indicator {
+			s := pine.NewStoch(a.Int("k_length"), a.Int("k_smoothing"), a.Int("d_smoothing"))
+			return []pine.Indicator{s.K, s.D}
+		},
+	},
+	"vwap": {
+		Params: []Param{
+			{Name: "anchor", Kind: ParamString, Optional: true, Default: "session", Values: []string{"session", "day", "week", "month"}},
+		},
+		New: func(a Args) []pine.Indicator {
+			return single(pine.NewVWAP(pine.VWAPOpts{Anchor: vwapAnchors[a.String("anchor")]}).VWAP)
+		},
+	},
+}
@@ -0,0 +1,339 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/xpt-nl/pine"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is a document of indicators
+type Config struct {
+	Indicators []Node `json:"indicators" yaml:"indicators"`
+}
+
+// Node is an indicator of a config
+type Node struct {
+	// Name is the name the indicator is referred to and added to series by.
+	// Indicators with several outputs are added as name.output for each
+	Name   string         `json:"name" yaml:"name"`
+	Type   string         `json:"type" yaml:"type"`
+	Params map[string]any `json:"params" yaml:"params"`
+	// Hidden indicators are only read by other indicators and not added to
+	// series
+	Hidden bool `json:"hidden" yaml:"hidden"`
+}
+
+// Indicator is an indicator built of a config
+type Indicator struct {
+	Name      string
+	Indicator pine.Indicator
+}
+
+// DecodeJSON decodes a JSON config rejecting unknown fields
+func DecodeJSON(data []byte) (*Config, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+	var cfg Config
+	if err := d.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("error decoding config: %w", err)
+	}
+	return &cfg, nil
+}
+
+// DecodeYAML decodes a YAML config rejecting unknown fields
+func DecodeYAML(data []byte) (*Config, error) {
+	d := yaml.NewDecoder(bytes.NewReader(data))
+	d.KnownFields(true)
+	var cfg Config
+	if err := d.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("error decoding config: %w", err)
+	}
+	return &cfg, nil
+}
+
+// ohlcProps are the OHLCV properties params can refer to
+var ohlcProps = map[string]pine.OHLCProp{
+	"open":   pine.OHLCPropOpen,
+	"high":   pine.OHLCPropHigh,
+	"low":    pine.OHLCPropLow,
+	"close":  pine.OHLCPropClose,
+	"volume": pine.OHLCPropVolume,
+	"hl2":    pine.OHLCPropHL2,
+	"hlc3":   pine.OHLCPropHLC3,
+}
+
+// Build creates the indicators of cfg which are not hidden in order. Every
+// call creates indicators not shared with earlier calls
+func (r *Registry) Build(cfg *Config) ([]Indicator, error) {
+	b := &builder{
+		registry: r,
+		nodes:    make(map[string]Node),
+		built:    make(map[string][]pine.Indicator),
+		building: make(map[string]bool),
+		props:    make(map[string]pine.Indicator),
+	}
+	for _, n := range cfg.Indicators {
+		if err := validName(n.Name); err != nil {
+			return nil, err
+		}
+		if _, ok := b.nodes[n.Name]; ok {
+			return nil, fmt.Errorf("indicator %s is declared twice", n.Name)
+		}
+		b.nodes[n.Name] = n
+	}
+	var inds []Indicator
+	for _, n := range cfg.Indicators {
+		outs, err := b.node(n.Name)
+		if err != nil {
+			return nil, err
+		}
+		if n.Hidden {
+			continue
+		}
+		outputs := r.constructors[n.Type].Outputs
+		if len(outputs) == 0 {
+			inds = append(inds, Indicator{n.Name, outs[0]})
+			continue
+		}
+		for j, out := range outputs {
+			inds = append(inds, Indicator{n.Name + "." + out, outs[j]})
+		}
+	}
+	return inds, nil
+}
+
+// Apply builds the indicators of cfg and adds them to s
+func (r *Registry) Apply(cfg *Config, s pine.Series) error {
+	inds, err := r.Build(cfg)
+	if err != nil {
+		return err
+	}
+	for _, i := range inds {
+		if err := s.AddIndicator(i.Name, i.Indicator); err != nil {
+			return fmt.Errorf("error adding %s: %w", i.Name, err)
+		}
+	}
+	return nil
+}
+
+func validName(name string) error {
+	if name == "" {
+		return fmt.Errorf("indicator has no name")
+	}
+	if strings.Contains(name, ".") {
+		return fmt.Errorf("indicator name %s must not contain a dot", name)
+	}
+	if _, ok := ohlcProps[name]; ok {
+		return fmt.Errorf("indicator name %s is reserved", name)
+	}
+	return nil
+}
+
+type builder struct {
+	registry *Registry
+	nodes    map[string]Node
+	built    map[string][]pine.Indicator
+	building map[string]bool
+	// props are shared so properties are read once per update
+	props map[string]pine.Indicator
+}
+
+// node returns the outputs of node name creating it once
+func (b *builder) node(name string) ([]pine.Indicator, error) {
+	if outs, ok := b.built[name]; ok {
+		return outs, nil
+	}
+	if b.building[name] {
+		return nil, fmt.Errorf("indicator %s: %w", name, ErrCycle)
+	}
+	b.building[name] = true
+	outs, err := b.create(b.nodes[name])
+	if err != nil {
+		return nil, fmt.Errorf("indicator %s: %w", name, err)
+	}
+	b.building[name] = false
+	b.built[name] = outs
+	return outs, nil
+}
+
+// create validates params of n and creates its indicators
+func (b *builder) create(n Node) ([]pine.Indicator, error) {
+	c, ok := b.registry.constructors[n.Type]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownType, n.Type)
+	}
+	known := make(map[string]bool, len(c.Params))
+	args := make(Args, len(c.Params))
+	for _, p := range c.Params {
+		known[p.Name] = true
+		v, ok := n.Params[p.Name]
+		if !ok {
+			if !p.Optional {
+				return nil, fmt.Errorf("%w %s: missing", ErrInvalidParam, p.Name)
+			}
+			v = p.Default
+		}
+		var err error
+		if p.Kind == ParamIndicator {
+			args[p.Name], err = b.indicator(v)
+		} else {
+			args[p.Name], err = convertParam(p, v)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("param %s: %w", p.Name, err)
+		}
+	}
+	for name := range n.Params {
+		if !known[name] {
+			return nil, fmt.Errorf("%w %s: unknown for %s", ErrInvalidParam, name, n.Type)
+		}
+	}
+	outs := c.New(args)
+	want := len(c.Outputs)
+	if want == 0 {
+		want = 1
+	}
+	if len(outs) != want {
+		return nil, fmt.Errorf("%s returned %d indicators but declares %d", n.Type, len(outs), want)
+	}
+	return outs, nil
+}
+
+// indicator returns the indicator of a reference, an inline node or a number
+func (b *builder) indicator(v any) (pine.Indicator, error) {
+	switch v := v.(type) {
+	case string:
+		return b.reference(v)
+	case map[string]any:
+		return b.inline(v)
+	}
+	if f, ok := number(v); ok {
+		return pine.NewConstant(f), nil
+	}
+	return nil, fmt.Errorf("%w: %v is not an indicator", ErrInvalidParam, v)
+}
+
+func (b *builder) reference(ref string) (pine.Indicator, error) {
+	name, output, hasOutput := strings.Cut(ref, ".")
+	if _, ok := b.nodes[name]; ok {
+		outs, err := b.node(name)
+		if err != nil {
+			return nil, err
+		}
+		outputs := b.registry.constructors[b.nodes[name].Type].Outputs
+		if !hasOutput {
+			if len(outputs) > 0 {
+				return nil, fmt.Errorf("%w: %s has outputs %s", ErrUnknownReference, name, strings.Join(outputs, ", "))
+			}
+			return outs[0], nil
+		}
+		for j, out := range outputs {
+			if out == output {
+				return outs[j], nil
+			}
+		}
+		return nil, fmt.Errorf("%w %s", ErrUnknownReference, ref)
+	}
+	prop, ok := ohlcProps[ref]
+	if !ok {
+		return nil, fmt.Errorf("%w %s", ErrUnknownReference, ref)
+	}
+	if _, ok := b.props[ref]; !ok {
+		b.props[ref] = pine.NewOHLCProp(prop)
+	}
+	return b.props[ref], nil
+}
+
+// inline creates an indicator declared in a param like
+// {type: sma, params: {source: close, length: 5}}
+func (b *builder) inline(v map[string]any) (pine.Indicator, error) {
+	var n Node
+	for key, val := range v {
+		switch key {
+		case "type":
+			n.Type, _ = val.(string)
+		case "params":
+			params, ok := val.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%w: params of inline indicator must be a map", ErrInvalidParam)
+			}
+			n.Params = params
+		default:
+			return nil, fmt.Errorf("%w: unknown field %s of inline indicator", ErrInvalidParam, key)
+		}
+	}
+	outs, err := b.create(n)
+	if err != nil {
+		return nil, fmt.Errorf("inline %s: %w", n.Type, err)
+	}
+	if len(outs) != 1 {
+		return nil, fmt.Errorf("%w: inline %s has several outputs", ErrInvalidParam, n.Type)
+	}
+	return outs[0], nil
+}
+
+// convertParam returns v as a value of the kind of p
+func convertParam(p Param, v any) (any, error) {
+	switch p.Kind {
+	case ParamLength, ParamInt:
+		f, ok := number(v)
+		if !ok || f != math.Trunc(f) || (p.Kind == ParamLength && f < 1) {
+			if p.Kind == ParamLength {
+				return nil, fmt.Errorf("%w: %v is not a positive int", ErrInvalidParam, v)
+			}
+			return nil, fmt.Errorf("%w: %v is not an int", ErrInvalidParam, v)
+		}
+		// bounded so lengths fit in an int on every platform
+		if f > math.MaxInt32 || f < math.MinInt32 {
+			return nil, fmt.Errorf("%w: %v is out of range", ErrInvalidParam, v)
+		}
+		return int(f), nil
+	case ParamFloat:
+		f, ok := number(v)
+		if !ok {
+			return nil, fmt.Errorf("%w: %v is not a number", ErrInvalidParam, v)
+		}
+		return f, nil
+	case ParamBool:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%w: %v is not a bool", ErrInvalidParam, v)
+		}
+		return b, nil
+	case ParamString:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %v is not a string", ErrInvalidParam, v)
+		}
+		if len(p.Values) == 0 {
+			return s, nil
+		}
+		for _, val := range p.Values {
+			if s == val {
+				return s, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: %s is not one of %s", ErrInvalidParam, s, strings.Join(p.Values, ", "))
+	}
+	return nil, fmt.Errorf("%w: unsupported kind %d", ErrInvalidParam, p.Kind)
+}
+
+// number returns numbers decoded from JSON or YAML as float64
+func number(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
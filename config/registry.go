@@ -0,0 +1,166 @@
+// Package config builds indicators of pine from JSON or YAML documents so
+// indicator setups can change without recompiling.
+//
+// A document lists indicators by name with a registered type and params.
+// Params of indicators refer to other indicators by name, to outputs of
+// indicators with several outputs like "macd.signal", to the OHLCV properties
+// open, high, low, close, volume, hl2 and hlc3 or to numbers which are
+// constant indicators:
+//
+//	indicators:
+//	  - name: basis
+//	    type: sma
+//	    params: {source: close, length: 20}
+//	  - name: upper
+//	    type: arith
+//	    params: {op: add, a: basis, b: 10}
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/xpt-nl/pine"
+)
+
+var (
+	// ErrUnknownType is returned for indicators of types not registered
+	ErrUnknownType = errors.New("unknown indicator type")
+	// ErrUnknownReference is returned for references to indicators that
+	// don't exist
+	ErrUnknownReference = errors.New("unknown indicator reference")
+	// ErrCycle is returned for indicators reading themselves
+	ErrCycle = errors.New("indicator reads itself")
+	// ErrInvalidParam is returned for params that are missing, unknown or
+	// of the wrong type
+	ErrInvalidParam = errors.New("invalid param")
+)
+
+// ParamKind is the type of a param
+type ParamKind int
+
+const (
+	// ParamIndicator is an indicator reference, an inline indicator or a
+	// number read as constant indicator
+	ParamIndicator ParamKind = iota
+	// ParamLength is a positive int
+	ParamLength
+	// ParamInt is an int
+	ParamInt
+	// ParamFloat is a number
+	ParamFloat
+	// ParamBool is a bool
+	ParamBool
+	// ParamString is a string which must be one of Param.Values if set
+	ParamString
+)
+
+// Param is a param of a constructor
+type Param struct {
+	Name string
+	Kind ParamKind
+	// Optional params are Default if not set. Default is required for
+	// optional params
+	Optional bool
+	Default  any
+	// Values are the accepted values of ParamString params
+	Values []string
+}
+
+// Constructor creates indicators of a type from validated params
+type Constructor struct {
+	Params []Param
+	// Outputs are the names of the indicators New returns in order if it
+	// returns more than one
+	Outputs []string
+	// New returns one indicator or one for each of Outputs
+	New func(a Args) []pine.Indicator
+}
+
+// Args are validated params by name. Every param of a constructor is set
+// to a value of its kind
+type Args map[string]any
+
+// Indicator returns ParamIndicator param name
+func (a Args) Indicator(name string) pine.Indicator {
+	return a[name].(pine.Indicator)
+}
+
+// Int returns ParamLength or ParamInt param name
+func (a Args) Int(name string) int {
+	return a[name].(int)
+}
+
+// Float returns ParamFloat param name
+func (a Args) Float(name string) float64 {
+	return a[name].(float64)
+}
+
+// Bool returns ParamBool param name
+func (a Args) Bool(name string) bool {
+	return a[name].(bool)
+}
+
+// String returns ParamString param name
+func (a Args) String(name string) string {
+	return a[name].(string)
+}
+
+// Registry maps indicator types to constructors
+type Registry struct {
+	constructors map[string]Constructor
+}
+
+// NewRegistry creates a registry with the indicators of pine
+func NewRegistry() *Registry {
+	r := &Registry{
+		constructors: make(map[string]Constructor),
+	}
+	for name, c := range builtins {
+		r.constructors[name] = c
+	}
+	return r
+}
+
+// Register adds indicator type name created by c
+func (r *Registry) Register(name string, c Constructor) error {
+	if _, ok := r.constructors[name]; ok {
+		return fmt.Errorf("indicator type %s is already registered", name)
+	}
+	if c.New == nil {
+		return fmt.Errorf("constructor of %s has no New", name)
+	}
+	seen := make(map[string]bool)
+	for _, p := range c.Params {
+		if seen[p.Name] {
+			return fmt.Errorf("param %s of %s is declared twice", p.Name, name)
+		}
+		seen[p.Name] = true
+		if !p.Optional {
+			continue
+		}
+		if p.Default == nil {
+			return fmt.Errorf("optional param %s of %s has no default", p.Name, name)
+		}
+		if p.Kind == ParamIndicator {
+			// references are resolved when indicators are built
+			continue
+		}
+		if _, err := convertParam(p, p.Default); err != nil {
+			return fmt.Errorf("default of param %s of %s: %w", p.Name, name, err)
+		}
+	}
+	r.constructors[name] = c
+	return nil
+}
+
+// Types returns the registered indicator types in alphabetical order
+func (r *Registry) Types() []string {
+	types := make([]string, 0, len(r.constructors))
+	for name := range r.constructors {
+		types = append(types, name)
+	}
+	sort.Strings(types)
+	return types
+}
@@ -19,10 +19,30 @@ func (i *constant) GetValueForInterval(t time.Time) *Interval {
 	}
 }
 
+func (i *constant) value(t time.Time) (float64, bool) {
+	return i.val, true
+}
+
 func (i *constant) Update(v OHLCV) error {
 	return nil
 }
 
+func (i *constant) inputs() []node {
+	return nil
+}
+
+func (i *constant) updateNode(v OHLCV) error {
+	return nil
+}
+
 func (i *constant) ApplyOpts(opts SeriesOpts) error {
 	return nil
 }
+
+func (i *constant) rewind(t time.Time) error {
+	return nil
+}
+
+func (i *constant) encodeState(e *encoder) {}
+
+func (i *constant) decodeState(d *decoder) {}
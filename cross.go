@@ -0,0 +1,163 @@
+package pine
+
+import (
+	"fmt"
+	"time"
+)
+
+// crossType defines the direction a cross is detected in
+type crossType int
+
+const (
+	crossTypeOver crossType = iota
+	crossTypeUnder
+	crossTypeAny
+)
+
+type cross struct {
+	valueStore
+	a Indicator
+	b Indicator
+	t crossType
+	// states include the one before the oldest kept interval so any kept
+	// interval can be generated again
+	states *ring[crossState]
+}
+
+// crossState keeps input values of an interval where OK is false if either
+// is missing
+type crossState struct {
+	A  float64
+	B  float64
+	OK bool
+}
+
+// NewCrossover generates 1 when a crosses over b and 0 otherwise like Pine's ta.crossover
+func NewCrossover(a, b Indicator) Indicator {
+	return newCross(crossTypeOver, a, b)
+}
+
+// NewCrossunder generates 1 when a crosses under b and 0 otherwise like Pine's ta.crossunder
+func NewCrossunder(a, b Indicator) Indicator {
+	return newCross(crossTypeUnder, a, b)
+}
+
+// NewCross generates 1 when a crosses b in either direction and 0 otherwise like Pine's ta.cross
+func NewCross(a, b Indicator) Indicator {
+	return newCross(crossTypeAny, a, b)
+}
+
+func newCross(t crossType, a, b Indicator) Indicator {
+	return &cross{
+		a:      a,
+		b:      b,
+		t:      t,
+		states: newRing[crossState](defaultMax, 0),
+	}
+}
+
+func (i *cross) Update(v OHLCV) error {
+	if err := i.a.Update(v); err != nil {
+		return fmt.Errorf("error updating in cross: %w", err)
+	}
+	if err := i.b.Update(v); err != nil {
+		return fmt.Errorf("error updating in cross: %w", err)
+	}
+	return i.updateNode(v)
+}
+
+func (i *cross) inputs() []node {
+	return nodesOf(i.a, i.b)
+}
+
+func (i *cross) updateNode(v OHLCV) error {
+	if i.states.len() > 0 && v.S.Before(i.states.lastTime()) {
+		// already generated
+		return nil
+	}
+	a, aok := valueOf(i.a, v.S)
+	b, bok := valueOf(i.b, v.S)
+	cur := crossState{
+		A:  a,
+		B:  b,
+		OK: aok && bok,
+	}
+	prev, _ := i.states.prior(v.S)
+	i.states.set(v.S, cur)
+	i.setValue(v.S, i.generateValue(prev, cur))
+	return nil
+}
+
+// generateValue compares current and previous values. Like Pine, any missing
+// value makes the comparison false so no cross is generated
+func (i *cross) generateValue(prev, cur crossState) float64 {
+	if !prev.OK || !cur.OK {
+		return 0
+	}
+	over := cur.A > cur.B && prev.A <= prev.B
+	under := cur.A < cur.B && prev.A >= prev.B
+	var crossed bool
+	switch i.t {
+	case crossTypeOver:
+		crossed = over
+	case crossTypeUnder:
+		crossed = under
+	case crossTypeAny:
+		crossed = over || under
+	}
+	if crossed {
+		return 1
+	}
+	return 0
+}
+
+func (i *cross) ApplyOpts(opts SeriesOpts) error {
+	if err := i.a.ApplyOpts(opts); err != nil {
+		return fmt.Errorf("error applying opts in cross: %w", err)
+	}
+	if err := i.b.ApplyOpts(opts); err != nil {
+		return fmt.Errorf("error applying opts in cross: %w", err)
+	}
+	i.initStore(opts)
+	i.states = historyRing(i.states, opts)
+	return nil
+}
+
+func (i *cross) reset() {
+	resetIndicator(i.a)
+	resetIndicator(i.b)
+	i.resetStore()
+	i.states.clear()
+}
+
+func (i *cross) rewind(t time.Time) error {
+	if err := rewindIndicator(i.a, t); err != nil {
+		return err
+	}
+	if err := rewindIndicator(i.b, t); err != nil {
+		return err
+	}
+	i.rewindStore(t)
+	i.states.rewind(t)
+	return nil
+}
+
+func (i *cross) encodeState(e *encoder) {
+	i.valueStore.encode(e)
+	encodeRing(e, i.states, func(e *encoder, s crossState) {
+		e.float(s.A)
+		e.float(s.B)
+		e.bool(s.OK)
+	})
+}
+
+func (i *cross) decodeState(d *decoder) {
+	i.valueStore.decode(d)
+	decodeRing(d, i.states, func(d *decoder) crossState {
+		return crossState{
+			A:  d.float(),
+			B:  d.float(),
+			OK: d.bool(),
+		}
+	})
+}
@@ -9,14 +9,14 @@ import (
 )
 
 type ema struct {
-	lastUpdate OHLCV
-	lookback   int
-	opts       *SeriesOpts
-	genval     map[time.Time]*TimeValue
-	srcval     map[time.Time]*TimeValue
-	genvalues  []*TimeValue
-	srcvalues  []*TimeValue
-	src        Indicator
+	valueStore
+	lookback  int
+	opts      *SeriesOpts
+	srcvalues *ring[float64]
+	// states are the generated values including the one before the oldest
+	// kept interval so any kept interval can be generated again
+	states *ring[float64]
+	src    Indicator
 }
 
 // NewEMA creates a new EMA indicator
@@ -24,119 +24,67 @@ func NewEMA(i Indicator, lookback int) Indicator {
 	return &ema{
 		src:       i,
 		lookback:  lookback,
-		genval:    make(map[time.Time]*TimeValue, lookback),
-		srcval:    make(map[time.Time]*TimeValue, lookback*2),
-		srcvalues: make([]*TimeValue, 0, lookback),
-		genvalues: make([]*TimeValue, 0, lookback*2),
+		srcvalues: newRing[float64](lookback, 0),
+		states:    newRing[float64](defaultMax, 0),
 	}
 }
 
-func (i *ema) GetValueForInterval(t time.Time) *Interval {
-	v, ok := i.genval[t]
-	if !ok {
-		return nil
-	}
-	return &Interval{
-		StartTime: t,
-		Value:     v.Value,
-	}
+// setEma sets generated value of interval t
+func (i *ema) setEma(t time.Time, v float64) {
+	i.states.set(t, v)
+	i.setValue(t, v)
 }
 
-func (i *ema) shouldUpdate(v OHLCV) bool {
-	downval := i.src.GetValueForInterval(v.S)
-	if downval == nil {
-		// src value does not exist so cannot generate
-		return false
+func (i *ema) generateEma(t time.Time) {
+	if i.srcvalues.len() < i.lookback {
+		// not enough data
+		return
 	}
-	val, ok := i.srcval[v.S]
+	last, ok := i.states.prior(t)
 	if !ok {
-		return true
-	}
-	if val.Value != downval.Value {
-		// if src value is updated and our src value cache is not updated
-		return true
-	}
-	return false
-}
-
-func (i *ema) generateEma(t time.Time) error {
-	total := len(i.srcvalues)
-	firstidx := total - i.lookback
-	// not enough data
-	if firstidx < 0 {
-		return nil
-	}
-	tv := NewTimeValue(t, 0)
-	if firstidx == 0 {
 		// get SMA for initial value
 		val := decimal.NewFromFloat(0.0)
-		for j := firstidx; j < total; j++ {
-			val = val.Add(decimal.NewFromFloat(i.srcvalues[j].Value))
-		}
-		avg := val.Div(decimal.NewFromFloat(float64(i.lookback)))
-		tv.Value, _ = avg.Float64()
-	} else if firstidx > 0 {
-		// get previous value
-		lastgen := len(i.genvalues) - 1
-		if lastgen < 0 {
-			return errors.New("Last gen value should have been there")
+		for j := i.lookback - 1; j >= 0; j-- {
+			v, _ := i.srcvalues.at(j)
+			val = val.Add(decimal.NewFromFloat(v))
 		}
-		last := i.genvalues[lastgen]
-		k := decimal.NewFromFloat(2.0).Div(decimal.NewFromFloat(float64(i.lookback + 1.0)))
-		srcval := i.src.GetValueForInterval(t)
-		if srcval == nil {
-			return errors.New("srcval cannot be obtained in EMA")
-		}
-		tv.Value, _ = decimal.NewFromFloat(srcval.Value).
-			Sub(decimal.NewFromFloat(last.Value)).
-			Mul(k).
-			Add(decimal.NewFromFloat(last.Value)).
-			Float64()
-	}
-	if len(i.genvalues) == cap(i.genvalues) {
-		var old *TimeValue
-		old, i.genvalues = i.genvalues[0], i.genvalues[1:]
-		delete(i.genval, old.Time)
+		avg, _ := val.Div(decimal.NewFromFloat(float64(i.lookback))).Float64()
+		i.setEma(t, avg)
+		return
 	}
-	i.genval[t] = tv
-	i.genvalues = append(i.genvalues, tv)
-	return nil
+	k := decimal.NewFromFloat(2.0).Div(decimal.NewFromFloat(float64(i.lookback + 1.0)))
+	srcval, _ := i.srcvalues.at(0)
+	val, _ := decimal.NewFromFloat(srcval).
+		Sub(decimal.NewFromFloat(last)).
+		Mul(k).
+		Add(decimal.NewFromFloat(last)).
+		Float64()
+	i.setEma(t, val)
 }
 
 func (i *ema) Update(v OHLCV) error {
 	if err := i.src.Update(v); err != nil {
 		return fmt.Errorf("error received from src in EMA: %w", err)
 	}
-	if !i.shouldUpdate(v) {
-		return nil
-	}
-	i.lastUpdate = v
+	return i.updateNode(v)
+}
 
-	// update src value and generate gen value
-	val := i.src.GetValueForInterval(v.S)
-	if val == nil {
-		return errors.New("expected src to provide data but none was given")
+func (i *ema) inputs() []node {
+	return nodesOf(i.src)
+}
+
+func (i *ema) updateNode(v OHLCV) error {
+	if i.srcvalues.len() > 0 && v.S.Before(i.srcvalues.lastTime()) {
+		// already generated
+		return nil
 	}
-	src, ok := i.srcval[v.S]
+	val, ok := valueOf(i.src, v.S)
 	if !ok {
-		tv := NewTimeValue(v.S, val.Value)
-		if len(i.srcvalues) >= i.lookback*2 {
-			// remove first
-			var old *TimeValue
-			old, i.srcvalues = i.srcvalues[0], i.srcvalues[1:]
-			if old == nil {
-				return errors.New("unexpected nil in ema array first el")
-			}
-			delete(i.srcval, old.Time)
-		}
-		i.srcval[v.S] = tv
-		i.srcvalues = append(i.srcvalues, tv)
-	} else if src.Value != val.Value {
-		// source value has changed
-		i.srcval[v.S].Value = src.Value
+		// src value does not exist so cannot generate
+		return nil
 	}
+	i.srcvalues.set(v.S, val)
 	i.generateEma(v.S)
-
 	return nil
 }
 
@@ -147,5 +95,38 @@ func (i *ema) ApplyOpts(opts SeriesOpts) error {
 	if err := i.src.ApplyOpts(opts); err != nil {
 		return fmt.Errorf("error applying opts in source: %w", err)
 	}
+	i.initStore(opts)
+	i.srcvalues = windowRing(i.srcvalues, i.lookback, opts)
+	i.states = historyRing(i.states, opts)
+	i.opts = &opts
 	return nil
 }
+
+func (i *ema) reset() {
+	resetIndicator(i.src)
+	i.srcvalues.clear()
+	i.states.clear()
+	i.resetStore()
+}
+
+func (i *ema) rewind(t time.Time) error {
+	if err := rewindIndicator(i.src, t); err != nil {
+		return err
+	}
+	i.srcvalues.rewind(t)
+	i.states.rewind(t)
+	i.rewindStore(t)
+	return nil
+}
+
+func (i *ema) encodeState(e *encoder) {
+	i.valueStore.encode(e)
+	encodeRing(e, i.srcvalues, putFloat)
+	encodeRing(e, i.states, putFloat)
+}
+
+func (i *ema) decodeState(d *decoder) {
+	i.valueStore.decode(d)
+	decodeRing(d, i.srcvalues, getFloat)
+	decodeRing(d, i.states, getFloat)
+}
@@ -0,0 +1,97 @@
+package pine
+
+import "time"
+
+// EventType is the kind of change an Event reports
+type EventType int
+
+const (
+	// EventBarOpened is sent when an interval is created by an exec or OHLCV
+	EventBarOpened EventType = iota
+	// EventBarUpdated is sent when an exec or OHLCV updates an interval
+	EventBarUpdated
+	// EventBarClosed is sent once when a later interval is added so the
+	// interval is final
+	EventBarClosed
+	// EventGapFilled is sent when an interval is created by EmptyInst
+	EventGapFilled
+	// EventIndicatorChanged is sent when the value of an indicator for the
+	// interval is generated or changes
+	EventIndicatorChanged
+)
+
+// Event reports a change of a series to subscribers
+type Event struct {
+	Type EventType
+	// Interval is the interval the event is for with its indicator values
+	Interval *Interval
+	// Indicator is the name of the indicator for EventIndicatorChanged
+	Indicator string
+}
+
+type subscriber struct {
+	id int
+	fn func(e Event)
+}
+
+// emittedValue is the last indicator value sent in EventIndicatorChanged
+type emittedValue struct {
+	t time.Time
+	v float64
+}
+
+func (s *series) Subscribe(fn func(e Event)) func() {
+	s.lastSub++
+	id := s.lastSub
+	s.subs = append(s.subs, subscriber{id, fn})
+	return func() {
+		// subscribers are copied so events being sent are not affected
+		subs := make([]subscriber, 0, len(s.subs))
+		for _, sub := range s.subs {
+			if sub.id != id {
+				subs = append(subs, sub)
+			}
+		}
+		s.subs = subs
+	}
+}
+
+// notify sends event typ for interval t followed by indicator changes if
+// indicators were updated
+func (s *series) notify(typ EventType, t time.Time, updated bool) {
+	if len(s.subs) == 0 {
+		return
+	}
+	itvl := s.GetValueForInterval(t)
+	if itvl == nil {
+		return
+	}
+	s.send(Event{
+		Type:     typ,
+		Interval: itvl,
+	})
+	if !updated {
+		return
+	}
+	for _, name := range s.names {
+		v := itvl.Indicators[name]
+		if v == nil {
+			continue
+		}
+		if last, ok := s.emitted[name]; ok && last.t.Equal(t) && last.v == *v {
+			continue
+		}
+		s.emitted[name] = emittedValue{t, *v}
+		s.send(Event{
+			Type:      EventIndicatorChanged,
+			Interval:  itvl,
+			Indicator: name,
+		})
+	}
+}
+
+func (s *series) send(e Event) {
+	for _, sub := range s.subs {
+		sub.fn(e)
+	}
+}
@@ -0,0 +1,47 @@
+package pine
+
+// expAvg is the running state of an exponentially weighted average which is
+// seeded with the simple average of the first length values like Pine does
+type expAvg struct {
+	alpha  float64
+	length int
+	count  int
+	sum    float64
+	value  float64
+}
+
+// newEMAState creates average state with EMA's alpha 2 / (length + 1)
+func newEMAState(length int) expAvg {
+	return expAvg{
+		alpha:  2 / float64(length+1),
+		length: length,
+	}
+}
+
+// newRMAState creates average state with Wilder's alpha 1 / length
+func newRMAState(length int) expAvg {
+	return expAvg{
+		alpha:  1 / float64(length),
+		length: length,
+	}
+}
+
+// next returns the state after applying x. The receiver is not modified so
+// the previous state can be reused when the same interval is revised
+func (e expAvg) next(x float64) expAvg {
+	if e.count < e.length {
+		e.count++
+		e.sum += x
+		if e.count == e.length {
+			e.value = e.sum / float64(e.length)
+		}
+		return e
+	}
+	e.value = e.alpha*x + (1-e.alpha)*e.value
+	return e
+}
+
+// ready returns true once enough values have been seen to generate a value
+func (e expAvg) ready() bool {
+	return e.count >= e.length
+}
@@ -4,5 +4,5 @@ go 1.18
 
 require (
 	github.com/shopspring/decimal v1.3.1
-	gonum.org/v1/gonum v0.11.0
+	gopkg.in/yaml.v3 v3.0.1
 )
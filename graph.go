@@ -0,0 +1,113 @@
+package pine
+
+import "reflect"
+
+// node is implemented by indicators of this package so series can update
+// each indicator of a dependency graph once per update in dependency order
+// rather than through every indicator reading it
+type node interface {
+	// inputs returns the nodes the node reads values of
+	inputs() []node
+	// updateNode generates values of interval v assuming inputs are updated
+	updateNode(v OHLCV) error
+}
+
+// indicatorNode wraps indicators of other packages whose inputs are unknown
+// so they are updated as a whole
+type indicatorNode struct {
+	Indicator
+}
+
+func (n *indicatorNode) inputs() []node {
+	return nil
+}
+
+func (n *indicatorNode) updateNode(v OHLCV) error {
+	return n.Update(v)
+}
+
+// asNode returns i as a node
+func asNode(i Indicator) node {
+	if n, ok := i.(node); ok {
+		return n
+	}
+	return &indicatorNode{i}
+}
+
+// nodesOf returns indicators as nodes
+func nodesOf(is ...Indicator) []node {
+	nodes := make([]node, len(is))
+	for j, i := range is {
+		nodes[j] = asNode(i)
+	}
+	return nodes
+}
+
+// graph keeps the nodes of indicators added to a series in topological order
+// so nodes are updated after their inputs and shared nodes are updated once
+type graph struct {
+	nodes []node
+	seen  map[any]bool
+}
+
+func newGraph() *graph {
+	return &graph{
+		seen: make(map[any]bool),
+	}
+}
+
+// add registers i and its inputs not registered yet and returns the nodes
+// added in topological order
+func (g *graph) add(i Indicator) []node {
+	first := len(g.nodes)
+	g.visit(asNode(i))
+	return g.nodes[first:]
+}
+
+func (g *graph) visit(n node) {
+	key := nodeKey(n)
+	if key != nil {
+		if g.seen[key] {
+			return
+		}
+		g.seen[key] = true
+	}
+	for _, in := range n.inputs() {
+		g.visit(in)
+	}
+	g.nodes = append(g.nodes, n)
+}
+
+// without returns the nodes of g not registered in other in topological order
+func (g *graph) without(other *graph) []node {
+	var nodes []node
+	for _, n := range g.nodes {
+		if key := nodeKey(n); key == nil || !other.seen[key] {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+// update updates every node once with v
+func (g *graph) update(v OHLCV) error {
+	for _, n := range g.nodes {
+		if err := n.updateNode(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nodeKey returns the identity of n or nil if it cannot be compared, in which
+// case it is never deduplicated
+func nodeKey(n node) any {
+	var key any = n
+	if w, ok := n.(*indicatorNode); ok {
+		key = w.Indicator
+	}
+	if !reflect.TypeOf(key).Comparable() {
+		return nil
+	}
+	return key
+}
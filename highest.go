@@ -0,0 +1,227 @@
+package pine
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+type extremum struct {
+	valueStore
+	lookback int
+	highest  bool
+	bars     bool
+	// values are src values of the window and, once SeriesOpts are applied,
+	// of the windows of every kept interval. Their indexes order the deque
+	values *ring[float64]
+	deque  monoDeque
+	src    Indicator
+}
+
+// NewHighest creates a new indicator with the highest value of src over lookback intervals
+func NewHighest(i Indicator, lookback int) Indicator {
+	return newExtremum(i, lookback, true, false)
+}
+
+// NewLowest creates a new indicator with the lowest value of src over lookback intervals
+func NewLowest(i Indicator, lookback int) Indicator {
+	return newExtremum(i, lookback, false, false)
+}
+
+// NewHighestBars creates a new indicator with the offset to the highest value of src
+// over lookback intervals. Like Pine's ta.highestbars offset is zero or negative
+func NewHighestBars(i Indicator, lookback int) Indicator {
+	return newExtremum(i, lookback, true, true)
+}
+
+// NewLowestBars creates a new indicator with the offset to the lowest value of src
+// over lookback intervals. Like Pine's ta.lowestbars offset is zero or negative
+func NewLowestBars(i Indicator, lookback int) Indicator {
+	return newExtremum(i, lookback, false, true)
+}
+
+func newExtremum(i Indicator, lookback int, highest, bars bool) Indicator {
+	return &extremum{
+		src:      i,
+		lookback: lookback,
+		highest:  highest,
+		bars:     bars,
+		values:   newRing[float64](lookback, 0),
+		deque:    newMonoDeque(lookback),
+	}
+}
+
+// dominates returns true if a should be kept over b. Ties keep the most recent value
+func (i *extremum) dominates(a, b float64) bool {
+	if i.highest {
+		return a >= b
+	}
+	return a <= b
+}
+
+func (i *extremum) Update(v OHLCV) error {
+	if err := i.src.Update(v); err != nil {
+		return fmt.Errorf("error received from src in extremum: %w", err)
+	}
+	return i.updateNode(v)
+}
+
+func (i *extremum) inputs() []node {
+	return nodesOf(i.src)
+}
+
+func (i *extremum) updateNode(v OHLCV) error {
+	if i.values.len() > 0 && v.S.Before(i.values.lastTime()) {
+		// already generated
+		return nil
+	}
+	val, ok := valueOf(i.src, v.S)
+	if !ok {
+		return nil
+	}
+	if i.values.len() > 0 && !i.values.lastTime().Equal(v.S) {
+		// previous interval is final so it can join the window
+		i.push(i.item(i.values.lastIndex()))
+	}
+	// current interval is kept out of the deque so revisions don't lose values
+	idx, _ := i.values.set(v.S, val)
+	// drop values that fell out of the window
+	for i.deque.size > 0 && i.deque.front().Index <= idx-i.lookback {
+		i.deque.popFront()
+	}
+	if i.values.len() < i.lookback {
+		return nil
+	}
+	cur := i.item(idx)
+	best := cur
+	if i.deque.size > 0 && !i.dominates(best.Value, i.deque.front().Value) {
+		best = i.deque.front()
+	}
+	out := best.Value
+	if i.bars {
+		out = float64(best.Index - cur.Index)
+	}
+	i.setValue(v.S, out)
+	return nil
+}
+
+// item returns the value at index of values
+func (i *extremum) item(idx int) dequeItem {
+	v, _ := i.values.get(idx)
+	return dequeItem{
+		Index: idx,
+		Value: v,
+	}
+}
+
+// push adds a final value to the deque dropping values it dominates
+func (i *extremum) push(item dequeItem) {
+	for i.deque.size > 0 && i.dominates(item.Value, i.deque.back().Value) {
+		i.deque.popBack()
+	}
+	i.deque.pushBack(item)
+}
+
+// rebuild fills the deque again with the final values of the window
+func (i *extremum) rebuild() {
+	i.deque.head = 0
+	i.deque.size = 0
+	last := i.values.lastIndex()
+	first := last - i.lookback + 1
+	if first < i.values.firstIndex() {
+		first = i.values.firstIndex()
+	}
+	for idx := first; idx < last; idx++ {
+		i.push(i.item(idx))
+	}
+}
+
+func (i *extremum) ApplyOpts(opts SeriesOpts) error {
+	if i.lookback <= 0 {
+		return errors.New("Highest/Lowest lookback must be positive")
+	}
+	if opts.Max < i.lookback {
+		return errors.New("SeriesOpts max cannot be less than Highest/Lowest lookback value")
+	}
+	if err := i.src.ApplyOpts(opts); err != nil {
+		return fmt.Errorf("error applying opts in source: %w", err)
+	}
+	i.initStore(opts)
+	i.values = windowRing(i.values, i.lookback, opts)
+	i.rebuild()
+	return nil
+}
+
+func (i *extremum) reset() {
+	resetIndicator(i.src)
+	i.resetStore()
+	i.values.clear()
+	i.rebuild()
+}
+
+func (i *extremum) rewind(t time.Time) error {
+	if err := rewindIndicator(i.src, t); err != nil {
+		return err
+	}
+	i.rewindStore(t)
+	i.values.rewind(t)
+	i.rebuild()
+	return nil
+}
+
+func (i *extremum) encodeState(e *encoder) {
+	i.valueStore.encode(e)
+	encodeRing(e, i.values, putFloat)
+}
+
+// decodeState restores values and fills the deque again
+func (i *extremum) decodeState(d *decoder) {
+	i.valueStore.decode(d)
+	decodeRing(d, i.values, getFloat)
+	i.rebuild()
+}
+
+type dequeItem struct {
+	Index int
+	Value float64
+}
+
+// monoDeque is a fixed capacity double ended queue backed by a ring buffer
+// allocated on the first push
+type monoDeque struct {
+	items    []dequeItem
+	capacity int
+	head     int
+	size     int
+}
+
+func newMonoDeque(capacity int) monoDeque {
+	return monoDeque{
+		capacity: capacity,
+	}
+}
+
+func (d *monoDeque) front() dequeItem {
+	return d.items[d.head]
+}
+
+func (d *monoDeque) back() dequeItem {
+	return d.items[(d.head+d.size-1)%d.capacity]
+}
+
+func (d *monoDeque) pushBack(v dequeItem) {
+	if d.items == nil {
+		d.items = make([]dequeItem, d.capacity)
+	}
+	d.items[(d.head+d.size)%d.capacity] = v
+	d.size++
+}
+
+func (d *monoDeque) popFront() {
+	d.head = (d.head + 1) % d.capacity
+	d.size--
+}
+
+func (d *monoDeque) popBack() {
+	d.size--
+}
@@ -0,0 +1,170 @@
+package pine
+
+import (
+	"errors"
+	"time"
+)
+
+// IntervalUnit is the unit SeriesOpts.Interval is counted in
+type IntervalUnit int
+
+const (
+	// IntervalUnitSecond counts Interval in seconds. Intervals dividing a day
+	// are anchored to the start of each day, others to the Unix epoch so every
+	// interval has the same length
+	IntervalUnitSecond IntervalUnit = iota
+	// IntervalUnitDay counts Interval in days
+	IntervalUnitDay
+	// IntervalUnitWeek counts Interval in weeks starting on SeriesOpts.WeekStart
+	IntervalUnitWeek
+	// IntervalUnitMonth counts Interval in calendar months
+	IntervalUnitMonth
+)
+
+const secondsPerDay = 24 * 60 * 60
+
+// civilEpochWeekday is the weekday of 1970-01-01
+const civilEpochWeekday = time.Thursday
+
+func (o SeriesOpts) validateInterval() error {
+	if o.IntervalUnit < IntervalUnitSecond || o.IntervalUnit > IntervalUnitMonth {
+		return errors.New("unsupported `IntervalUnit`")
+	}
+	if o.DayStart < 0 || o.DayStart >= 24*time.Hour {
+		return errors.New("`DayStart` must be within a day")
+	}
+	if o.WeekStart < time.Sunday || o.WeekStart > time.Saturday {
+		return errors.New("`WeekStart` must be a weekday")
+	}
+	return nil
+}
+
+func (o SeriesOpts) location() *time.Location {
+	if o.Location == nil {
+		return time.UTC
+	}
+	return o.Location
+}
+
+// approxInterval returns the usual length of an interval or zero if it varies
+func (o SeriesOpts) approxInterval() time.Duration {
+	switch o.IntervalUnit {
+	case IntervalUnitSecond:
+		return time.Duration(o.Interval) * time.Second
+	case IntervalUnitDay:
+		return time.Duration(o.Interval) * 24 * time.Hour
+	case IntervalUnitWeek:
+		return time.Duration(o.Interval) * 7 * 24 * time.Hour
+	}
+	return 0
+}
+
+// dayAligned returns true if intervals never span more than one day
+func (o SeriesOpts) dayAligned() bool {
+	switch o.IntervalUnit {
+	case IntervalUnitSecond:
+		return secondsPerDay%o.Interval == 0
+	case IntervalUnitDay:
+		return o.Interval == 1
+	}
+	return false
+}
+
+// dayAt returns the start of day y-m-d which is DayStart after midnight in
+// Location. Day overflows are normalized like time.Date
+func (o SeriesOpts) dayAt(y int, m time.Month, d int) time.Time {
+	return wallClock(y, m, d, o.DayStart, o.location())
+}
+
+// wallClock returns the time on the wall clock off after midnight of y-m-d in
+// loc. off is split into clock fields so it fits an int on every platform
+func wallClock(y int, m time.Month, d int, off time.Duration, loc *time.Location) time.Time {
+	hour := int(off / time.Hour)
+	minute := int(off % time.Hour / time.Minute)
+	second := int(off % time.Minute / time.Second)
+	return time.Date(y, m, d, hour, minute, second, int(off%time.Second), loc)
+}
+
+// dayStart returns the start of the day t belongs to
+func (o SeriesOpts) dayStart(t time.Time) time.Time {
+	y, m, d := t.In(o.location()).Date()
+	st := o.dayAt(y, m, d)
+	if st.After(t) {
+		st = o.dayAt(y, m, d-1)
+	}
+	return st
+}
+
+// civilDay returns the number of days between 1970-01-01 and y-m-d
+func civilDay(y int, m time.Month, d int) int {
+	return int(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / secondsPerDay)
+}
+
+func floorMod(a, b int) int {
+	m := a % b
+	if m < 0 {
+		m += b
+	}
+	return m
+}
+
+// intervalStart returns the start of the interval t belongs to
+func (o SeriesOpts) intervalStart(t time.Time) time.Time {
+	switch o.IntervalUnit {
+	case IntervalUnitDay, IntervalUnitWeek:
+		y, m, d := o.dayStart(t).In(o.location()).Date()
+		day := civilDay(y, m, d)
+		var first int
+		if o.IntervalUnit == IntervalUnitDay {
+			first = day - floorMod(day, o.Interval)
+		} else {
+			// weeks are counted from the first WeekStart after the epoch
+			ref := int(o.WeekStart-civilEpochWeekday+7) % 7
+			week := (day - ref - floorMod(day-ref, 7)) / 7
+			first = ref + (week-floorMod(week, o.Interval))*7
+		}
+		return o.dayAt(y, m, d+first-day)
+	case IntervalUnitMonth:
+		y, m, _ := o.dayStart(t).In(o.location()).Date()
+		month := y*12 + int(m) - 1
+		first := month - floorMod(month, o.Interval)
+		return o.dayAt(first/12, time.Month(first%12+1), 1)
+	}
+	step := time.Duration(o.Interval) * time.Second
+	var st time.Time
+	if o.dayAligned() {
+		st = o.dayStart(t)
+	} else {
+		st = o.dayAt(1970, time.January, 1)
+	}
+	// floor division since t can be before the anchor
+	n := t.Sub(st) / step
+	if t.Sub(st)%step < 0 {
+		n--
+	}
+	return st.Add(n * step)
+}
+
+// nextIntervalStart returns the start of the interval following the one t
+// belongs to
+func (o SeriesOpts) nextIntervalStart(t time.Time) time.Time {
+	st := o.intervalStart(t)
+	switch o.IntervalUnit {
+	case IntervalUnitDay:
+		y, m, d := st.In(o.location()).Date()
+		return o.dayAt(y, m, d+o.Interval)
+	case IntervalUnitWeek:
+		y, m, d := st.In(o.location()).Date()
+		return o.dayAt(y, m, d+7*o.Interval)
+	case IntervalUnitMonth:
+		y, m, _ := st.In(o.location()).Date()
+		return o.dayAt(y, m+time.Month(o.Interval), 1)
+	}
+	step := time.Duration(o.Interval) * time.Second
+	// days longer than usual at daylight saving changes may need more steps
+	for next := st.Add(step); ; next = next.Add(step) {
+		if n := o.intervalStart(next); n.After(st) {
+			return n
+		}
+	}
+}
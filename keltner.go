@@ -0,0 +1,213 @@
+package pine
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// KeltnerChannels holds the outputs of the Keltner Channels indicator.
+// Each output can be registered on Series separately
+type KeltnerChannels struct {
+	// Upper is Basis plus multiplier times EMA of range
+	Upper Indicator
+	// Basis is the exponential moving average of source
+	Basis Indicator
+	// Lower is Basis minus multiplier times EMA of range
+	Lower Indicator
+}
+
+const (
+	kcOutputUpper = iota
+	kcOutputBasis
+	kcOutputLower
+)
+
+type kc struct {
+	lookback     int
+	mult         float64
+	useTrueRange bool
+	// states include the one before the oldest kept interval so any kept
+	// interval can be generated again
+	states    *ring[kcState]
+	genvalues *ring[kcValue]
+	src       Indicator
+}
+
+type kcState struct {
+	Time  time.Time
+	Close float64
+	Basis expAvg
+	Range expAvg
+}
+
+type kcValue struct {
+	Time  time.Time
+	Upper float64
+	Basis float64
+	Lower float64
+}
+
+// NewKeltnerChannels creates a new Keltner Channels indicator like Pine's ta.kc.
+// Range is true range if useTrueRange is set, otherwise high minus low
+func NewKeltnerChannels(i Indicator, lookback int, mult float64, useTrueRange bool) KeltnerChannels {
+	k := &kc{
+		src:          i,
+		lookback:     lookback,
+		mult:         mult,
+		useTrueRange: useTrueRange,
+		states:       newRing[kcState](defaultMax, 0),
+		genvalues:    newRing[kcValue](defaultMax, 0),
+	}
+	return KeltnerChannels{
+		Upper: newOutput(k, kcOutputUpper),
+		Basis: newOutput(k, kcOutputBasis),
+		Lower: newOutput(k, kcOutputLower),
+	}
+}
+
+func (i *kc) getOutput(t time.Time, idx int) (float64, bool) {
+	v, ok := i.genvalues.getTime(t)
+	if !ok {
+		return 0, false
+	}
+	switch idx {
+	case kcOutputUpper:
+		return v.Upper, true
+	case kcOutputBasis:
+		return v.Basis, true
+	case kcOutputLower:
+		return v.Lower, true
+	}
+	return 0, false
+}
+
+func (i *kc) Update(v OHLCV) error {
+	if err := i.src.Update(v); err != nil {
+		return fmt.Errorf("error received from src in KeltnerChannels: %w", err)
+	}
+	return i.updateNode(v)
+}
+
+func (i *kc) inputs() []node {
+	return nodesOf(i.src)
+}
+
+func (i *kc) updateNode(v OHLCV) error {
+	if i.states.len() > 0 && v.S.Before(i.states.lastTime()) {
+		// already generated
+		return nil
+	}
+	val, ok := valueOf(i.src, v.S)
+	if !ok {
+		return nil
+	}
+	prev, ok := i.states.prior(v.S)
+	if !ok {
+		prev = kcState{
+			Basis: newEMAState(i.lookback),
+			Range: newEMAState(i.lookback),
+		}
+	}
+	cur := kcState{
+		Time:  v.S,
+		Close: v.C,
+		Basis: prev.Basis.next(val),
+		Range: prev.Range,
+	}
+	if !i.useTrueRange {
+		cur.Range = prev.Range.next(v.H - v.L)
+	} else if !prev.Time.IsZero() {
+		// true range is na on the first interval as there is no previous close
+		cur.Range = prev.Range.next(trueRange(v, prev.Close))
+	}
+	i.states.set(v.S, cur)
+	if !cur.Basis.ready() || !cur.Range.ready() {
+		return nil
+	}
+	span := cur.Range.value * i.mult
+	i.genvalues.set(v.S, kcValue{
+		Time:  v.S,
+		Upper: cur.Basis.value + span,
+		Basis: cur.Basis.value,
+		Lower: cur.Basis.value - span,
+	})
+	return nil
+}
+
+// trueRange returns max(high - low, abs(high - prevClose), abs(low - prevClose))
+func trueRange(v OHLCV, prevClose float64) float64 {
+	return math.Max(v.H-v.L, math.Max(math.Abs(v.H-prevClose), math.Abs(v.L-prevClose)))
+}
+
+func (i *kc) ApplyOpts(opts SeriesOpts) error {
+	if opts.Max < i.lookback {
+		return errors.New("SeriesOpts max cannot be less than KeltnerChannels lookback value")
+	}
+	if err := i.src.ApplyOpts(opts); err != nil {
+		return fmt.Errorf("error applying opts in source: %w", err)
+	}
+	if i.genvalues.capacity() != opts.Max {
+		i.genvalues = newSeriesRing[kcValue](opts)
+	}
+	i.states = historyRing(i.states, opts)
+	return nil
+}
+
+func (i *kc) reset() {
+	resetIndicator(i.src)
+	i.states.clear()
+	i.genvalues.clear()
+}
+
+func (i *kc) rewind(t time.Time) error {
+	if err := rewindIndicator(i.src, t); err != nil {
+		return err
+	}
+	i.states.rewind(t)
+	i.genvalues.rewind(t)
+	return nil
+}
+
+func (i *kc) encodeState(e *encoder) {
+	encodeRing(e, i.states, func(e *encoder, s kcState) {
+		s.encode(e)
+	})
+	encodeRing(e, i.genvalues, func(e *encoder, v kcValue) {
+		e.time(v.Time)
+		e.float(v.Upper)
+		e.float(v.Basis)
+		e.float(v.Lower)
+	})
+}
+
+func (i *kc) decodeState(d *decoder) {
+	decodeRing(d, i.states, func(d *decoder) kcState {
+		var s kcState
+		s.decode(d)
+		return s
+	})
+	decodeRing(d, i.genvalues, func(d *decoder) kcValue {
+		return kcValue{
+			Time:  d.time(),
+			Upper: d.float(),
+			Basis: d.float(),
+			Lower: d.float(),
+		}
+	})
+}
+
+func (s kcState) encode(e *encoder) {
+	e.time(s.Time)
+	e.float(s.Close)
+	s.Basis.encode(e)
+	s.Range.encode(e)
+}
+
+func (s *kcState) decode(d *decoder) {
+	s.Time = d.time()
+	s.Close = d.float()
+	s.Basis.decode(d)
+	s.Range.decode(d)
+}
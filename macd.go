@@ -0,0 +1,214 @@
+package pine
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MACD holds the outputs of the moving average convergence divergence indicator.
+// Each output can be registered on Series separately
+type MACD struct {
+	// MACD is the fast EMA minus the slow EMA
+	MACD Indicator
+	// Signal is the EMA of MACD
+	Signal Indicator
+	// Histogram is MACD minus Signal
+	Histogram Indicator
+}
+
+const (
+	macdOutputMACD = iota
+	macdOutputSignal
+	macdOutputHistogram
+)
+
+type macd struct {
+	fast   int
+	slow   int
+	signal int
+	// states include the one before the oldest kept interval so any kept
+	// interval can be generated again
+	states    *ring[macdState]
+	genvalues *ring[macdValue]
+	src       Indicator
+}
+
+type macdState struct {
+	Time   time.Time
+	Fast   expAvg
+	Slow   expAvg
+	Signal expAvg
+}
+
+type macdValue struct {
+	Time      time.Time
+	MACD      float64
+	Signal    float64
+	Histogram float64
+	HasSignal bool
+}
+
+// NewMACD creates a new MACD indicator like Pine's ta.macd
+func NewMACD(i Indicator, fast, slow, signal int) MACD {
+	m := &macd{
+		src:       i,
+		fast:      fast,
+		slow:      slow,
+		signal:    signal,
+		states:    newRing[macdState](defaultMax, 0),
+		genvalues: newRing[macdValue](defaultMax, 0),
+	}
+	return MACD{
+		MACD:      newOutput(m, macdOutputMACD),
+		Signal:    newOutput(m, macdOutputSignal),
+		Histogram: newOutput(m, macdOutputHistogram),
+	}
+}
+
+func (i *macd) getOutput(t time.Time, idx int) (float64, bool) {
+	v, ok := i.genvalues.getTime(t)
+	if !ok {
+		return 0, false
+	}
+	switch idx {
+	case macdOutputMACD:
+		return v.MACD, true
+	case macdOutputSignal:
+		return v.Signal, v.HasSignal
+	case macdOutputHistogram:
+		return v.Histogram, v.HasSignal
+	}
+	return 0, false
+}
+
+func (i *macd) Update(v OHLCV) error {
+	if err := i.src.Update(v); err != nil {
+		return fmt.Errorf("error received from src in MACD: %w", err)
+	}
+	return i.updateNode(v)
+}
+
+func (i *macd) inputs() []node {
+	return nodesOf(i.src)
+}
+
+func (i *macd) updateNode(v OHLCV) error {
+	if i.states.len() > 0 && v.S.Before(i.states.lastTime()) {
+		// already generated
+		return nil
+	}
+	val, ok := valueOf(i.src, v.S)
+	if !ok {
+		return nil
+	}
+	prev, ok := i.states.prior(v.S)
+	if !ok {
+		prev = macdState{
+			Fast:   newEMAState(i.fast),
+			Slow:   newEMAState(i.slow),
+			Signal: newEMAState(i.signal),
+		}
+	}
+	// current interval is always derived from previous so revisions are idempotent
+	cur := macdState{
+		Time:   v.S,
+		Fast:   prev.Fast.next(val),
+		Slow:   prev.Slow.next(val),
+		Signal: prev.Signal,
+	}
+	if !cur.Fast.ready() || !cur.Slow.ready() {
+		i.states.set(v.S, cur)
+		return nil
+	}
+	gv := macdValue{
+		Time: v.S,
+		MACD: cur.Fast.value - cur.Slow.value,
+	}
+	cur.Signal = prev.Signal.next(gv.MACD)
+	if cur.Signal.ready() {
+		gv.Signal = cur.Signal.value
+		gv.Histogram = gv.MACD - gv.Signal
+		gv.HasSignal = true
+	}
+	i.states.set(v.S, cur)
+	i.setGenValue(gv)
+	return nil
+}
+
+func (i *macd) setGenValue(gv macdValue) {
+	i.genvalues.set(gv.Time, gv)
+}
+
+func (i *macd) ApplyOpts(opts SeriesOpts) error {
+	if opts.Max < i.slow || opts.Max < i.fast || opts.Max < i.signal {
+		return errors.New("SeriesOpts max cannot be less than MACD lookback value")
+	}
+	if err := i.src.ApplyOpts(opts); err != nil {
+		return fmt.Errorf("error applying opts in source: %w", err)
+	}
+	if i.genvalues.capacity() != opts.Max {
+		i.genvalues = newSeriesRing[macdValue](opts)
+	}
+	i.states = historyRing(i.states, opts)
+	return nil
+}
+
+func (i *macd) reset() {
+	resetIndicator(i.src)
+	i.states.clear()
+	i.genvalues.clear()
+}
+
+func (i *macd) rewind(t time.Time) error {
+	if err := rewindIndicator(i.src, t); err != nil {
+		return err
+	}
+	i.states.rewind(t)
+	i.genvalues.rewind(t)
+	return nil
+}
+
+func (i *macd) encodeState(e *encoder) {
+	encodeRing(e, i.states, func(e *encoder, s macdState) {
+		s.encode(e)
+	})
+	encodeRing(e, i.genvalues, func(e *encoder, v macdValue) {
+		e.time(v.Time)
+		e.float(v.MACD)
+		e.float(v.Signal)
+		e.float(v.Histogram)
+		e.bool(v.HasSignal)
+	})
+}
+
+func (i *macd) decodeState(d *decoder) {
+	decodeRing(d, i.states, func(d *decoder) macdState {
+		var s macdState
+		s.decode(d)
+		return s
+	})
+	decodeRing(d, i.genvalues, func(d *decoder) macdValue {
+		return macdValue{
+			Time:      d.time(),
+			MACD:      d.float(),
+			Signal:    d.float(),
+			Histogram: d.float(),
+			HasSignal: d.bool(),
+		}
+	})
+}
+
+func (s macdState) encode(e *encoder) {
+	e.time(s.Time)
+	s.Fast.encode(e)
+	s.Slow.encode(e)
+	s.Signal.encode(e)
+}
+
+func (s *macdState) decode(d *decoder) {
+	s.Time = d.time()
+	s.Fast.decode(d)
+	s.Slow.decode(d)
+	s.Signal.decode(d)
+}
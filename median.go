@@ -1,144 +1,8 @@
 package pine
 
-import (
-	"errors"
-	"fmt"
-	"time"
-
-	"github.com/shopspring/decimal"
-)
-
-type median struct {
-	lastUpdate OHLCV
-	lookback   int
-	opts       *SeriesOpts
-	genval     map[time.Time]*TimeValue
-	srcval     map[time.Time]*TimeValue
-	genvalues  []*TimeValue
-	srcvalues  []*TimeValue
-	src        Indicator
-}
-
-// NewMedian creates a new Median indicator
+// NewMedian creates a new Median indicator with the middle value of src over
+// lookback intervals like Pine's ta.median. With an even lookback it is the
+// average of the two middle values
 func NewMedian(i Indicator, lookback int) Indicator {
-	return &median{
-		src:       i,
-		lookback:  lookback,
-		genval:    make(map[time.Time]*TimeValue, lookback),
-		srcval:    make(map[time.Time]*TimeValue, lookback*2),
-		srcvalues: make([]*TimeValue, 0, lookback),
-		genvalues: make([]*TimeValue, 0, lookback*2),
-	}
-}
-
-func (i *median) GetValueForInterval(t time.Time) *Interval {
-	v, ok := i.genval[t]
-	if !ok {
-		return nil
-	}
-	return &Interval{
-		StartTime: t,
-		Value:     v.Value,
-	}
-}
-
-func (i *median) shouldUpdate(v OHLCV) bool {
-	downval := i.src.GetValueForInterval(v.S)
-	if downval == nil {
-		// src value does not exist so cannot generate
-		return false
-	}
-	val, ok := i.srcval[v.S]
-	if !ok {
-		return true
-	}
-	if val.Value != downval.Value {
-		// if src value is updated and our src value cache is not updated
-		return true
-	}
-	return false
-}
-
-func (i *median) generateMedian(t time.Time) error {
-	total := len(i.srcvalues)
-	firstidx := total - i.lookback
-	if firstidx < 0 {
-		return nil
-	}
-
-	var avg float64
-	// odd number
-	if i.lookback%2 == 1 {
-		idx := firstidx + (i.lookback-1)/2
-		avg = i.srcvalues[idx].Value
-	} else {
-		// even number
-		i1 := firstidx + i.lookback/2
-		i2 := i1 - 1
-		avg, _ = decimal.NewFromFloat(i.srcvalues[i1].Value).
-			Add(decimal.NewFromFloat(i.srcvalues[i2].Value)).
-			Div(decimal.NewFromFloat(2.0)).
-			Float64()
-	}
-	tv := NewTimeValue(t, avg)
-	_, ok := i.genval[t]
-	if !ok {
-		if len(i.genvalues) == cap(i.genvalues) {
-			var old *TimeValue
-			old, i.genvalues = i.genvalues[0], i.genvalues[1:]
-			delete(i.genval, old.Time)
-		}
-		i.genval[t] = tv
-		i.genvalues = append(i.genvalues, tv)
-	} else {
-		i.genval[t] = tv
-	}
-	return nil
-}
-
-func (i *median) Update(v OHLCV) error {
-	if err := i.src.Update(v); err != nil {
-		return fmt.Errorf("error received from src in Median: %w", err)
-	}
-	if !i.shouldUpdate(v) {
-		return nil
-	}
-	i.lastUpdate = v
-
-	// update src value and generate gen value
-	val := i.src.GetValueForInterval(v.S)
-	if val == nil {
-		return errors.New("expected src to provide data but none was given")
-	}
-	src, ok := i.srcval[v.S]
-	if !ok {
-		tv := NewTimeValue(v.S, val.Value)
-		if len(i.srcvalues) >= i.lookback*2 {
-			// remove first
-			var old *TimeValue
-			old, i.srcvalues = i.srcvalues[0], i.srcvalues[1:]
-			if old == nil {
-				return errors.New("unexpected nil in median array first el")
-			}
-			delete(i.srcval, old.Time)
-		}
-		i.srcval[v.S] = tv
-		i.srcvalues = append(i.srcvalues, tv)
-	} else if src.Value != val.Value {
-		// source value has changed
-		i.srcval[v.S].Value = val.Value
-	}
-	i.generateMedian(v.S)
-
-	return nil
-}
-
-func (i *median) ApplyOpts(opts SeriesOpts) error {
-	if opts.Max < i.lookback {
-		return errors.New("SeriesOpts max cannot be less than Median lookback value")
-	}
-	if err := i.src.ApplyOpts(opts); err != nil {
-		return fmt.Errorf("error applying opts in source: %w", err)
-	}
-	return nil
+	return newPercentile("Median", i, lookback, lookback, 50, percentileMedian)
 }
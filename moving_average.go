@@ -0,0 +1,541 @@
+package pine
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// maStep generates a moving average value for an interval. Setting the same
+// interval again replaces its value so the current interval can be revised
+type maStep interface {
+	set(v OHLCV, x float64) (float64, bool)
+	// applyOpts keeps state of every interval kept by opts
+	applyOpts(opts SeriesOpts)
+	reset()
+	// rewind removes state of intervals from t onwards
+	rewind(t time.Time)
+	encode(e *encoder)
+	decode(d *decoder)
+}
+
+type movingAverage struct {
+	valueStore
+	name     string
+	lookback int
+	last     time.Time
+	step     maStep
+	src      Indicator
+}
+
+// NewWMA creates a new weighted moving average indicator like Pine's ta.wma
+func NewWMA(i Indicator, lookback int) Indicator {
+	return newMovingAverage("WMA", i, lookback, newWMAStep(lookback))
+}
+
+// NewRMA creates a new Wilder's moving average indicator like Pine's ta.rma
+func NewRMA(i Indicator, lookback int) Indicator {
+	return newMovingAverage("RMA", i, lookback, newEMAStep(newRMAState(lookback)))
+}
+
+// NewHMA creates a new Hull moving average indicator like Pine's ta.hma
+func NewHMA(i Indicator, lookback int) Indicator {
+	return newMovingAverage("HMA", i, lookback, &hmaStep{
+		half: newWMAStep(lookback / 2),
+		full: newWMAStep(lookback),
+		out:  newWMAStep(int(math.Floor(math.Sqrt(float64(lookback))))),
+	})
+}
+
+// NewVWMA creates a new volume weighted moving average indicator like Pine's
+// ta.vwma. Volume is read from OHLCV
+func NewVWMA(i Indicator, lookback int) Indicator {
+	return newMovingAverage("VWMA", i, lookback, &vwmaStep{
+		pv:  newNaWindow(lookback),
+		vol: newNaWindow(lookback),
+	})
+}
+
+// NewALMA creates a new Arnaud Legoux moving average indicator like Pine's ta.alma
+func NewALMA(i Indicator, lookback int, offset, sigma float64) Indicator {
+	return newMovingAverage("ALMA", i, lookback, newFIRStep(lookback, func() []float64 {
+		return almaWeights(lookback, offset, sigma)
+	}))
+}
+
+// NewSWMA creates a new symmetrically weighted moving average indicator with
+// fixed length of 4 like Pine's ta.swma
+func NewSWMA(i Indicator) Indicator {
+	return newMovingAverage("SWMA", i, 4, newFIRStep(4, func() []float64 {
+		return []float64{1.0 / 6, 2.0 / 6, 2.0 / 6, 1.0 / 6}
+	}))
+}
+
+// NewDEMA creates a new double exponential moving average indicator
+func NewDEMA(i Indicator, lookback int) Indicator {
+	return newMovingAverage("DEMA", i, lookback, &demaStep{
+		emas: newEMASteps(2, lookback),
+	})
+}
+
+// NewTEMA creates a new triple exponential moving average indicator
+func NewTEMA(i Indicator, lookback int) Indicator {
+	return newMovingAverage("TEMA", i, lookback, &temaStep{
+		emas: newEMASteps(3, lookback),
+	})
+}
+
+// NewZLEMA creates a new zero lag exponential moving average indicator
+func NewZLEMA(i Indicator, lookback int) Indicator {
+	return newMovingAverage("ZLEMA", i, lookback, &zlemaStep{
+		lagged: newNaWindow((lookback-1)/2 + 1),
+		ema:    newEMAStep(newEMAState(lookback)),
+	})
+}
+
+func newMovingAverage(name string, i Indicator, lookback int, step maStep) Indicator {
+	return &movingAverage{
+		name:     name,
+		src:      i,
+		lookback: lookback,
+		step:     step,
+	}
+}
+
+func (i *movingAverage) Update(v OHLCV) error {
+	if err := i.src.Update(v); err != nil {
+		return fmt.Errorf("error received from src in %s: %w", i.name, err)
+	}
+	return i.updateNode(v)
+}
+
+func (i *movingAverage) inputs() []node {
+	return nodesOf(i.src)
+}
+
+func (i *movingAverage) updateNode(v OHLCV) error {
+	if !i.last.IsZero() && v.S.Before(i.last) {
+		// already generated
+		return nil
+	}
+	val, ok := valueOf(i.src, v.S)
+	if !ok {
+		return nil
+	}
+	i.last = v.S
+	avg, ok := i.step.set(v, val)
+	if !ok {
+		return nil
+	}
+	i.setValue(v.S, avg)
+	return nil
+}
+
+func (i *movingAverage) ApplyOpts(opts SeriesOpts) error {
+	if i.lookback <= 0 {
+		return fmt.Errorf("%s lookback must be positive", i.name)
+	}
+	if _, ok := i.step.(*hmaStep); ok && i.lookback < 2 {
+		// the half length WMA needs a lookback of at least 1
+		return fmt.Errorf("%s lookback must be at least 2", i.name)
+	}
+	if opts.Max < i.lookback {
+		return fmt.Errorf("SeriesOpts max cannot be less than %s lookback value", i.name)
+	}
+	if err := i.src.ApplyOpts(opts); err != nil {
+		return fmt.Errorf("error applying opts in source: %w", err)
+	}
+	i.initStore(opts)
+	i.step.applyOpts(opts)
+	return nil
+}
+
+func (i *movingAverage) reset() {
+	resetIndicator(i.src)
+	i.resetStore()
+	i.last = time.Time{}
+	i.step.reset()
+}
+
+func (i *movingAverage) rewind(t time.Time) error {
+	if err := rewindIndicator(i.src, t); err != nil {
+		return err
+	}
+	i.rewindStore(t)
+	i.last = time.Time{}
+	i.step.rewind(t)
+	return nil
+}
+
+// firStep is a moving average with fixed weights where the last weight is
+// applied to the most recent value. Weights are generated once the window is
+// full so large lookbacks only allocate after SeriesOpts accept them
+type firStep struct {
+	weights []float64
+	weigh   func() []float64
+	values  naWindow
+}
+
+func newFIRStep(size int, weigh func() []float64) *firStep {
+	return &firStep{
+		weigh:  weigh,
+		values: newNaWindow(size),
+	}
+}
+
+func newWMAStep(size int) *firStep {
+	return newFIRStep(size, func() []float64 {
+		return wmaWeights(size)
+	})
+}
+
+func (s *firStep) set(v OHLCV, x float64) (float64, bool) {
+	s.values.set(v.S, x, false)
+	if !s.values.full() {
+		return 0, false
+	}
+	if s.weights == nil {
+		s.weights = s.weigh()
+	}
+	var sum, norm float64
+	for j, w := range s.weights {
+		sum += s.values.get(j) * w
+		norm += w
+	}
+	return sum / norm, true
+}
+
+func (s *firStep) applyOpts(opts SeriesOpts) {
+	s.values.applyOpts(opts)
+}
+
+func (s *firStep) reset() {
+	s.values.reset()
+}
+
+func (s *firStep) rewind(t time.Time) {
+	s.values.rewind(t)
+}
+
+// wmaWeights returns linear weights with the largest weight on the most recent value
+func wmaWeights(lookback int) []float64 {
+	weights := make([]float64, lookback)
+	for j := range weights {
+		weights[j] = float64(j + 1)
+	}
+	return weights
+}
+
+// almaWeights returns gaussian weights centered at offset of the window
+func almaWeights(lookback int, offset, sigma float64) []float64 {
+	m := offset * float64(lookback-1)
+	s := float64(lookback) / sigma
+	weights := make([]float64, lookback)
+	for j := range weights {
+		weights[j] = math.Exp(-1 * math.Pow(float64(j)-m, 2) / (2 * math.Pow(s, 2)))
+	}
+	return weights
+}
+
+// emaStep is an exponentially weighted average keeping the state of every
+// interval so intervals can be revised or rewound
+type emaStep struct {
+	init   expAvg
+	states *ring[expAvg]
+}
+
+func newEMAStep(init expAvg) *emaStep {
+	return &emaStep{
+		init:   init,
+		states: newRing[expAvg](defaultMax, 0),
+	}
+}
+
+func newEMASteps(n, lookback int) []*emaStep {
+	steps := make([]*emaStep, n)
+	for j := range steps {
+		steps[j] = newEMAStep(newEMAState(lookback))
+	}
+	return steps
+}
+
+func (s *emaStep) set(v OHLCV, x float64) (float64, bool) {
+	prev, ok := s.states.prior(v.S)
+	if !ok {
+		prev = s.init
+	}
+	cur := prev.next(x)
+	s.states.set(v.S, cur)
+	return cur.value, cur.ready()
+}
+
+func (s *emaStep) applyOpts(opts SeriesOpts) {
+	s.states = historyRing(s.states, opts)
+}
+
+func (s *emaStep) reset() {
+	s.states.clear()
+}
+
+func (s *emaStep) rewind(t time.Time) {
+	s.states.rewind(t)
+}
+
+type hmaStep struct {
+	half *firStep
+	full *firStep
+	out  *firStep
+}
+
+func (s *hmaStep) set(v OHLCV, x float64) (float64, bool) {
+	half, ok := s.half.set(v, x)
+	full, ok2 := s.full.set(v, x)
+	if !ok || !ok2 {
+		return 0, false
+	}
+	return s.out.set(v, 2*half-full)
+}
+
+func (s *hmaStep) applyOpts(opts SeriesOpts) {
+	s.half.applyOpts(opts)
+	s.full.applyOpts(opts)
+	s.out.applyOpts(opts)
+}
+
+func (s *hmaStep) reset() {
+	s.half.reset()
+	s.full.reset()
+	s.out.reset()
+}
+
+func (s *hmaStep) rewind(t time.Time) {
+	s.half.rewind(t)
+	s.full.rewind(t)
+	s.out.rewind(t)
+}
+
+type vwmaStep struct {
+	pv  naWindow
+	vol naWindow
+}
+
+func (s *vwmaStep) set(v OHLCV, x float64) (float64, bool) {
+	s.pv.set(v.S, x*v.V, false)
+	s.vol.set(v.S, v.V, false)
+	pv, ok := s.pv.mean()
+	vol, ok2 := s.vol.mean()
+	if !ok || !ok2 || vol == 0 {
+		return 0, false
+	}
+	return pv / vol, true
+}
+
+func (s *vwmaStep) applyOpts(opts SeriesOpts) {
+	s.pv.applyOpts(opts)
+	s.vol.applyOpts(opts)
+}
+
+func (s *vwmaStep) reset() {
+	s.pv.reset()
+	s.vol.reset()
+}
+
+func (s *vwmaStep) rewind(t time.Time) {
+	s.pv.rewind(t)
+	s.vol.rewind(t)
+}
+
+type demaStep struct {
+	emas []*emaStep
+}
+
+func (s *demaStep) set(v OHLCV, x float64) (float64, bool) {
+	e1, ok := s.emas[0].set(v, x)
+	if !ok {
+		return 0, false
+	}
+	e2, ok := s.emas[1].set(v, e1)
+	if !ok {
+		return 0, false
+	}
+	return 2*e1 - e2, true
+}
+
+func (s *demaStep) applyOpts(opts SeriesOpts) {
+	applyOptsEMASteps(s.emas, opts)
+}
+
+func (s *demaStep) reset() {
+	resetEMASteps(s.emas)
+}
+
+func (s *demaStep) rewind(t time.Time) {
+	rewindEMASteps(s.emas, t)
+}
+
+type temaStep struct {
+	emas []*emaStep
+}
+
+func (s *temaStep) set(v OHLCV, x float64) (float64, bool) {
+	e1, ok := s.emas[0].set(v, x)
+	if !ok {
+		return 0, false
+	}
+	e2, ok := s.emas[1].set(v, e1)
+	if !ok {
+		return 0, false
+	}
+	e3, ok := s.emas[2].set(v, e2)
+	if !ok {
+		return 0, false
+	}
+	return 3*(e1-e2) + e3, true
+}
+
+func (s *temaStep) applyOpts(opts SeriesOpts) {
+	applyOptsEMASteps(s.emas, opts)
+}
+
+func (s *temaStep) reset() {
+	resetEMASteps(s.emas)
+}
+
+func (s *temaStep) rewind(t time.Time) {
+	rewindEMASteps(s.emas, t)
+}
+
+func applyOptsEMASteps(steps []*emaStep, opts SeriesOpts) {
+	for _, s := range steps {
+		s.applyOpts(opts)
+	}
+}
+
+func resetEMASteps(steps []*emaStep) {
+	for _, s := range steps {
+		s.reset()
+	}
+}
+
+func rewindEMASteps(steps []*emaStep, t time.Time) {
+	for _, s := range steps {
+		s.rewind(t)
+	}
+}
+
+type zlemaStep struct {
+	lagged naWindow
+	ema    *emaStep
+}
+
+func (s *zlemaStep) set(v OHLCV, x float64) (float64, bool) {
+	s.lagged.set(v.S, x, false)
+	if !s.lagged.full() {
+		return 0, false
+	}
+	lagged := s.lagged.get(0)
+	return s.ema.set(v, x+(x-lagged))
+}
+
+func (s *zlemaStep) applyOpts(opts SeriesOpts) {
+	s.lagged.applyOpts(opts)
+	s.ema.applyOpts(opts)
+}
+
+func (s *zlemaStep) reset() {
+	s.lagged.reset()
+	s.ema.reset()
+}
+
+func (s *zlemaStep) rewind(t time.Time) {
+	s.lagged.rewind(t)
+	s.ema.rewind(t)
+}
+
+func (i *movingAverage) encodeState(e *encoder) {
+	i.valueStore.encode(e)
+	e.time(i.last)
+	i.step.encode(e)
+}
+
+func (i *movingAverage) decodeState(d *decoder) {
+	i.valueStore.decode(d)
+	i.last = d.time()
+	i.step.decode(d)
+}
+
+func (s *firStep) encode(e *encoder) {
+	s.values.encode(e)
+}
+
+func (s *firStep) decode(d *decoder) {
+	s.values.decode(d)
+}
+
+func (s *emaStep) encode(e *encoder) {
+	encodeRing(e, s.states, putExpAvg)
+}
+
+func (s *emaStep) decode(d *decoder) {
+	decodeRing(d, s.states, getExpAvg)
+}
+
+func (s *hmaStep) encode(e *encoder) {
+	s.half.encode(e)
+	s.full.encode(e)
+	s.out.encode(e)
+}
+
+func (s *hmaStep) decode(d *decoder) {
+	s.half.decode(d)
+	s.full.decode(d)
+	s.out.decode(d)
+}
+
+func (s *vwmaStep) encode(e *encoder) {
+	s.pv.encode(e)
+	s.vol.encode(e)
+}
+
+func (s *vwmaStep) decode(d *decoder) {
+	s.pv.decode(d)
+	s.vol.decode(d)
+}
+
+func (s *demaStep) encode(e *encoder) {
+	encodeEMASteps(e, s.emas)
+}
+
+func (s *demaStep) decode(d *decoder) {
+	decodeEMASteps(d, s.emas)
+}
+
+func (s *temaStep) encode(e *encoder) {
+	encodeEMASteps(e, s.emas)
+}
+
+func (s *temaStep) decode(d *decoder) {
+	decodeEMASteps(d, s.emas)
+}
+
+func encodeEMASteps(e *encoder, steps []*emaStep) {
+	for _, s := range steps {
+		s.encode(e)
+	}
+}
+
+func decodeEMASteps(d *decoder, steps []*emaStep) {
+	for _, s := range steps {
+		s.decode(d)
+	}
+}
+
+func (s *zlemaStep) encode(e *encoder) {
+	s.lagged.encode(e)
+	s.ema.encode(e)
+}
+
+func (s *zlemaStep) decode(d *decoder) {
+	s.lagged.decode(d)
+	s.ema.decode(d)
+}
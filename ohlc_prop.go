@@ -1,8 +1,6 @@
 package pine
 
-import (
-	"time"
-)
+import "time"
 
 // OHLCProp is a property of OHLC
 type OHLCProp int
@@ -25,66 +23,65 @@ const (
 )
 
 type ohlcprop struct {
-	prop    OHLCProp
-	last    OHLCV
-	timemap map[time.Time]float64
+	valueStore
+	prop OHLCProp
 }
 
 // NewOHLCProp returns property of OHLC in OHLCProps
 func NewOHLCProp(p OHLCProp) Indicator {
 	return &ohlcprop{
-		prop:    p,
-		timemap: make(map[time.Time]float64),
+		prop: p,
 	}
 }
 
-func (i *ohlcprop) GetValueForInterval(t time.Time) *Interval {
-	v, ok := i.timemap[t]
-	if !ok {
-		return nil
-	}
-	return &Interval{
-		StartTime: t,
-		Value:     v,
-	}
+func (i *ohlcprop) Update(v OHLCV) error {
+	return i.updateNode(v)
 }
 
-func (i *ohlcprop) Update(v OHLCV) error {
-	timesame := i.last.S.Equal(v.S)
-	var valssame bool
+func (i *ohlcprop) inputs() []node {
+	return nil
+}
+
+func (i *ohlcprop) updateNode(v OHLCV) error {
 	var val float64
 	switch i.prop {
 	case OHLCPropClose:
-		valssame = i.last.C == v.C
 		val = v.C
 	case OHLCPropHigh:
-		valssame = i.last.H == v.H
 		val = v.H
 	case OHLCPropLow:
-		valssame = i.last.L == v.L
 		val = v.L
 	case OHLCPropOpen:
-		valssame = i.last.O == v.O
 		val = v.O
 	case OHLCPropVolume:
-		valssame = i.last.V == v.V
 		val = v.V
 	case OHLCPropHL2:
-		valssame = i.last.H == v.H && i.last.L == v.L
 		val = (v.H + v.L) / 2
 	case OHLCPropHLC3:
-		valssame = i.last.H == v.H && i.last.L == v.L && i.last.C == v.C
 		val = (v.H + v.L + v.C) / 3
 	}
-	if valssame && timesame {
-		return nil
-	}
-	i.timemap[v.S] = val
-	i.last = v
+	i.setValue(v.S, val)
 	return nil
 }
 
 func (i *ohlcprop) ApplyOpts(opts SeriesOpts) error {
-	// validate if needed
+	i.initStore(opts)
 	return nil
 }
+
+func (i *ohlcprop) reset() {
+	i.resetStore()
+}
+
+func (i *ohlcprop) rewind(t time.Time) error {
+	i.rewindStore(t)
+	return nil
+}
+
+func (i *ohlcprop) encodeState(e *encoder) {
+	i.valueStore.encode(e)
+}
+
+func (i *ohlcprop) decodeState(d *decoder) {
+	i.valueStore.decode(d)
+}
@@ -0,0 +1,266 @@
+package pine
+
+import "time"
+
+// skipNode is a node of skiplist where span is the number of nodes each link
+// skips over so nodes can be located by rank
+type skipNode struct {
+	value float64
+	next  []*skipNode
+	span  []int
+}
+
+// skiplist is an indexable skiplist keeping values sorted so inserting,
+// removing and locating the k-th smallest value are O(log n). Removed nodes
+// are reused so steady state updates don't allocate
+type skiplist struct {
+	head     *skipNode
+	level    int
+	maxLevel int
+	size     int
+	rnd      uint64
+	free     []*skipNode
+	update   []*skipNode
+	rank     []int
+}
+
+// newSkiplist creates a skiplist sized for about capacity values
+func newSkiplist(capacity int) *skiplist {
+	maxLevel := 1
+	for c := capacity; c > 1; c >>= 1 {
+		maxLevel++
+	}
+	l := &skiplist{
+		level:    1,
+		maxLevel: maxLevel,
+		rnd:      0x9e3779b97f4a7c15,
+		update:   make([]*skipNode, maxLevel),
+		rank:     make([]int, maxLevel),
+	}
+	l.head = l.newNode(0, maxLevel)
+	return l
+}
+
+func (l *skiplist) newNode(v float64, level int) *skipNode {
+	var n *skipNode
+	if total := len(l.free); total > 0 {
+		n, l.free = l.free[total-1], l.free[:total-1]
+	} else {
+		n = &skipNode{
+			next: make([]*skipNode, l.maxLevel),
+			span: make([]int, l.maxLevel),
+		}
+	}
+	n.value = v
+	n.next = n.next[:level]
+	n.span = n.span[:level]
+	for i := range n.next {
+		n.next[i] = nil
+		n.span[i] = 0
+	}
+	return n
+}
+
+// randomLevel returns level of a new node where each level is half as likely
+func (l *skiplist) randomLevel() int {
+	// xorshift keeps levels deterministic between runs
+	l.rnd ^= l.rnd << 13
+	l.rnd ^= l.rnd >> 7
+	l.rnd ^= l.rnd << 17
+	lvl := 1
+	for r := l.rnd; r&1 == 1 && lvl < l.maxLevel; r >>= 1 {
+		lvl++
+	}
+	return lvl
+}
+
+func (l *skiplist) insert(v float64) {
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		if i == l.level-1 {
+			l.rank[i] = 0
+		} else {
+			l.rank[i] = l.rank[i+1]
+		}
+		for x.next[i] != nil && x.next[i].value < v {
+			l.rank[i] += x.span[i]
+			x = x.next[i]
+		}
+		l.update[i] = x
+	}
+	lvl := l.randomLevel()
+	if lvl > l.level {
+		for i := l.level; i < lvl; i++ {
+			l.rank[i] = 0
+			l.update[i] = l.head
+			l.update[i].span[i] = l.size
+		}
+		l.level = lvl
+	}
+	n := l.newNode(v, lvl)
+	for i := 0; i < lvl; i++ {
+		n.next[i] = l.update[i].next[i]
+		l.update[i].next[i] = n
+		n.span[i] = l.update[i].span[i] - (l.rank[0] - l.rank[i])
+		l.update[i].span[i] = l.rank[0] - l.rank[i] + 1
+	}
+	for i := lvl; i < l.level; i++ {
+		l.update[i].span[i]++
+	}
+	l.size++
+}
+
+// remove removes one occurrence of v and returns false if there is none
+func (l *skiplist) remove(v float64) bool {
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		for x.next[i] != nil && x.next[i].value < v {
+			x = x.next[i]
+		}
+		l.update[i] = x
+	}
+	n := x.next[0]
+	if n == nil || n.value != v {
+		return false
+	}
+	for i := 0; i < l.level; i++ {
+		if l.update[i].next[i] == n {
+			l.update[i].span[i] += n.span[i] - 1
+			l.update[i].next[i] = n.next[i]
+		} else {
+			l.update[i].span[i]--
+		}
+	}
+	for l.level > 1 && l.head.next[l.level-1] == nil {
+		l.level--
+	}
+	l.size--
+	l.free = append(l.free, n)
+	return true
+}
+
+// kth returns the k-th smallest value starting from 0
+func (l *skiplist) kth(k int) float64 {
+	traversed := 0
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		for x.next[i] != nil && traversed+x.span[i] <= k+1 {
+			traversed += x.span[i]
+			x = x.next[i]
+		}
+		if traversed == k+1 {
+			return x.value
+		}
+	}
+	return x.value
+}
+
+// countLessEq returns the number of values less than or equal to v
+func (l *skiplist) countLessEq(v float64) int {
+	count := 0
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		for x.next[i] != nil && x.next[i].value <= v {
+			count += x.span[i]
+			x = x.next[i]
+		}
+	}
+	return count
+}
+
+// orderedWindow keeps the last size values of a source both in interval
+// order and sorted. Setting the most recent interval again replaces its value
+// so the current interval can be revised. Once SeriesOpts are applied values
+// before them are kept too so the window can be rewound
+type orderedWindow struct {
+	size   int
+	values *ring[float64]
+	sorted *skiplist
+}
+
+func newOrderedWindow(size int) *orderedWindow {
+	return &orderedWindow{
+		size:   size,
+		values: newRing[float64](size, 0),
+		sorted: newSkiplist(size),
+	}
+}
+
+// applyOpts keeps values of the window of every interval kept by opts
+func (w *orderedWindow) applyOpts(opts SeriesOpts) {
+	if w.values.capacity() != w.size+opts.Max {
+		w.reset()
+	}
+	w.values = windowRing(w.values, w.size, opts)
+}
+
+// before returns true if t is before the most recent interval in the window
+func (w *orderedWindow) before(t time.Time) bool {
+	return w.values.len() > 0 && t.Before(w.values.lastTime())
+}
+
+func (w *orderedWindow) full() bool {
+	return w.size > 0 && w.values.len() >= w.size
+}
+
+// first returns index of the oldest value in the window
+func (w *orderedWindow) first() int {
+	if n := w.values.len(); n < w.size {
+		return w.values.firstIndex()
+	}
+	return w.values.lastIndex() - w.size + 1
+}
+
+// set replaces the value of the most recent interval if t is the same,
+// otherwise pushes it out the oldest value once the window is full
+func (w *orderedWindow) set(t time.Time, x float64) {
+	if w.size <= 0 {
+		return
+	}
+	if w.values.len() > 0 && w.values.lastTime().Equal(t) {
+		old, _ := w.values.at(0)
+		w.sorted.remove(old)
+	} else if w.full() {
+		old, _ := w.values.get(w.first())
+		w.sorted.remove(old)
+	}
+	w.values.set(t, x)
+	w.sorted.insert(x)
+}
+
+// reset removes all values
+func (w *orderedWindow) reset() {
+	for j := w.first(); j <= w.values.lastIndex(); j++ {
+		v, _ := w.values.get(j)
+		w.sorted.remove(v)
+	}
+	w.values.clear()
+}
+
+// rewind removes values of intervals from t onwards. Each removed value is
+// replaced in the window by the kept value before the window
+func (w *orderedWindow) rewind(t time.Time) {
+	from := w.values.search(t)
+	for j := w.values.lastIndex(); j >= from; j-- {
+		v, _ := w.values.get(j)
+		w.sorted.remove(v)
+		if prev, ok := w.values.get(j - w.size); ok {
+			w.sorted.insert(prev)
+		}
+	}
+	w.values.truncate(from)
+}
+
+func (w *orderedWindow) encode(e *encoder) {
+	encodeRing(e, w.values, putFloat)
+}
+
+// decode restores values and sorts the window again
+func (w *orderedWindow) decode(d *decoder) {
+	w.reset()
+	decodeRing(d, w.values, getFloat)
+	for j := w.first(); j <= w.values.lastIndex(); j++ {
+		v, _ := w.values.get(j)
+		w.sorted.insert(v)
+	}
+}
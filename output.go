@@ -0,0 +1,74 @@
+package pine
+
+import "time"
+
+// multiSource is implemented by indicators generating more than one value
+// per interval. Each value is exposed to Series as a separate Indicator
+type multiSource interface {
+	ApplyOpts(opts SeriesOpts) error
+	Update(v OHLCV) error
+	node
+	rewinder
+	getOutput(t time.Time, idx int) (float64, bool)
+	reset()
+}
+
+type output struct {
+	src multiSource
+	idx int
+}
+
+func newOutput(src multiSource, idx int) Indicator {
+	return &output{
+		src: src,
+		idx: idx,
+	}
+}
+
+func (i *output) GetValueForInterval(t time.Time) *Interval {
+	v, ok := i.value(t)
+	if !ok {
+		return nil
+	}
+	return &Interval{
+		StartTime: t,
+		Value:     v,
+	}
+}
+
+func (i *output) value(t time.Time) (float64, bool) {
+	return i.src.getOutput(t, i.idx)
+}
+
+// Update updates the shared source once for every registered output. Sources
+// compute the interval again each time and rely on the result being the same
+// so registering several outputs is safe
+func (i *output) Update(v OHLCV) error {
+	return i.src.Update(v)
+}
+
+func (i *output) inputs() []node {
+	return []node{i.src}
+}
+
+// updateNode does nothing since values are read from the shared source
+func (i *output) updateNode(v OHLCV) error {
+	return nil
+}
+
+func (i *output) ApplyOpts(opts SeriesOpts) error {
+	return i.src.ApplyOpts(opts)
+}
+
+func (i *output) reset() {
+	i.src.reset()
+}
+
+// rewind rewinds the shared source once for every registered output
+func (i *output) rewind(t time.Time) error {
+	return i.src.rewind(t)
+}
+
+func (i *output) encodeState(e *encoder) {}
+
+func (i *output) decodeState(d *decoder) {}
@@ -0,0 +1,158 @@
+package pine
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// percentile generates an order statistic over a window of src values
+type percentile struct {
+	valueStore
+	name     string
+	lookback int
+	p        float64
+	window   *orderedWindow
+	compute  func(w *orderedWindow, p, x float64) float64
+	src      Indicator
+}
+
+// NewPercentile creates a new indicator with the p-th percentile of src over
+// lookback intervals, interpolating linearly between the two nearest ranks
+// like Pine's ta.percentile_linear_interpolation. p ranges from 0 to 100
+func NewPercentile(i Indicator, lookback int, p float64) Indicator {
+	return newPercentile("Percentile", i, lookback, lookback, p, percentileLinear)
+}
+
+// NewPercentileNearestRank creates a new indicator with the p-th percentile of
+// src over lookback intervals using the nearest rank method like Pine's
+// ta.percentile_nearest_rank, so the value is always one of src values.
+// p ranges from 0 to 100
+func NewPercentileNearestRank(i Indicator, lookback int, p float64) Indicator {
+	return newPercentile("PercentileNearestRank", i, lookback, lookback, p, percentileNearestRank)
+}
+
+// NewPercentRank creates a new indicator with the percentage of the previous
+// lookback values of src that are less than or equal to the current value
+// like Pine's ta.percentrank
+func NewPercentRank(i Indicator, lookback int) Indicator {
+	// window holds the current value next to the previous lookback values
+	return newPercentile("PercentRank", i, lookback, lookback+1, 0, percentRank)
+}
+
+func newPercentile(name string, i Indicator, lookback, size int, p float64, compute func(*orderedWindow, float64, float64) float64) Indicator {
+	return &percentile{
+		name:     name,
+		lookback: lookback,
+		p:        p,
+		window:   newOrderedWindow(size),
+		compute:  compute,
+		src:      i,
+	}
+}
+
+func (i *percentile) Update(v OHLCV) error {
+	if err := i.src.Update(v); err != nil {
+		return fmt.Errorf("error received from src in %s: %w", i.name, err)
+	}
+	return i.updateNode(v)
+}
+
+func (i *percentile) inputs() []node {
+	return nodesOf(i.src)
+}
+
+func (i *percentile) updateNode(v OHLCV) error {
+	if i.window.before(v.S) {
+		// already generated
+		return nil
+	}
+	val, ok := valueOf(i.src, v.S)
+	if !ok {
+		// src value does not exist so cannot generate
+		return nil
+	}
+	i.window.set(v.S, val)
+	if !i.window.full() {
+		return nil
+	}
+	i.setValue(v.S, i.compute(i.window, i.p, val))
+	return nil
+}
+
+func (i *percentile) ApplyOpts(opts SeriesOpts) error {
+	if i.lookback <= 0 {
+		return fmt.Errorf("%s lookback must be positive", i.name)
+	}
+	if i.p < 0 || i.p > 100 {
+		return fmt.Errorf("%s percentage must be between 0 and 100", i.name)
+	}
+	if opts.Max < i.lookback {
+		return fmt.Errorf("SeriesOpts max cannot be less than %s lookback value", i.name)
+	}
+	if err := i.src.ApplyOpts(opts); err != nil {
+		return fmt.Errorf("error applying opts in source: %w", err)
+	}
+	i.initStore(opts)
+	i.window.applyOpts(opts)
+	return nil
+}
+
+func (i *percentile) reset() {
+	resetIndicator(i.src)
+	i.window.reset()
+	i.resetStore()
+}
+
+func (i *percentile) rewind(t time.Time) error {
+	if err := rewindIndicator(i.src, t); err != nil {
+		return err
+	}
+	i.window.rewind(t)
+	i.rewindStore(t)
+	return nil
+}
+
+func (i *percentile) encodeState(e *encoder) {
+	i.valueStore.encode(e)
+	i.window.encode(e)
+}
+
+func (i *percentile) decodeState(d *decoder) {
+	i.valueStore.decode(d)
+	i.window.decode(d)
+}
+
+// percentileMedian returns the middle value or the average of the two middle
+// values if the window has an even number of values
+func percentileMedian(w *orderedWindow, _, _ float64) float64 {
+	n := w.sorted.size
+	if n%2 == 1 {
+		return w.sorted.kth(n / 2)
+	}
+	return (w.sorted.kth(n/2-1) + w.sorted.kth(n/2)) / 2
+}
+
+func percentileLinear(w *orderedWindow, p, _ float64) float64 {
+	rank := p / 100 * float64(w.sorted.size-1)
+	lo := int(math.Floor(rank))
+	lov := w.sorted.kth(lo)
+	if frac := rank - float64(lo); frac > 0 {
+		return lov + frac*(w.sorted.kth(lo+1)-lov)
+	}
+	return lov
+}
+
+func percentileNearestRank(w *orderedWindow, p, _ float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(w.sorted.size)))
+	if rank < 1 {
+		rank = 1
+	}
+	return w.sorted.kth(rank - 1)
+}
+
+func percentRank(w *orderedWindow, _, x float64) float64 {
+	// the current value is in the window and always counts itself
+	count := w.sorted.countLessEq(x) - 1
+	return 100 * float64(count) / float64(w.size-1)
+}
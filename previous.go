@@ -7,22 +7,26 @@ import (
 )
 
 type prev struct {
-	lastUpdate OHLCV
-	lookback   int
-	opts       *SeriesOpts
-	src        Indicator
+	lookback int
+	bars     *ring[struct{}]
+	src      Indicator
 }
 
 // NewPrevious looks back at previous intervals values
 func NewPrevious(i Indicator, lookback int) Indicator {
 	return &prev{
 		lookback: lookback,
+		bars:     newRing[struct{}](defaultMax, 0),
 		src:      i,
 	}
 }
 
 func (i *prev) GetValueForInterval(t time.Time) *Interval {
-	v2 := i.src.GetValueForInterval(t.Add(-1 * time.Duration(i.lookback*i.opts.Interval) * time.Second))
+	bt, ok := barsBack(i.bars, t, i.lookback)
+	if !ok {
+		return nil
+	}
+	v2 := i.src.GetValueForInterval(bt)
 	if v2 == nil {
 		return nil
 	}
@@ -36,9 +40,29 @@ func (i *prev) Update(v OHLCV) error {
 	if err := i.src.Update(v); err != nil {
 		return fmt.Errorf("error received from src in Change: %w", err)
 	}
+	return i.updateNode(v)
+}
+
+func (i *prev) inputs() []node {
+	return nodesOf(i.src)
+}
+
+// updateNode keeps the interval so lookback counts bars like Pine's history
+// references rather than calendar intervals which skip session breaks
+func (i *prev) updateNode(v OHLCV) error {
+	i.bars.set(v.S, struct{}{})
 	return nil
 }
 
+// barsBack returns the interval n bars before interval t
+func barsBack(bars *ring[struct{}], t time.Time, n int) (time.Time, bool) {
+	idx, ok := bars.indexOf(t)
+	if !ok || !bars.has(idx-n) {
+		return time.Time{}, false
+	}
+	return bars.timeAt(idx - n), true
+}
+
 func (i *prev) ApplyOpts(opts SeriesOpts) error {
 	if opts.Max < i.lookback {
 		return errors.New("SeriesOpts max cannot be less than Change lookback value")
@@ -46,6 +70,35 @@ func (i *prev) ApplyOpts(opts SeriesOpts) error {
 	if err := i.src.ApplyOpts(opts); err != nil {
 		return fmt.Errorf("error applying opts in source: %w", err)
 	}
-	i.opts = &opts
+	if i.bars.capacity() != opts.Max {
+		i.bars = newSeriesRing[struct{}](opts)
+	}
 	return nil
 }
+
+func (i *prev) reset() {
+	resetIndicator(i.src)
+	i.bars.clear()
+}
+
+func (i *prev) rewind(t time.Time) error {
+	if err := rewindIndicator(i.src, t); err != nil {
+		return err
+	}
+	i.bars.rewind(t)
+	return nil
+}
+
+func (i *prev) encodeState(e *encoder) {
+	encodeRing(e, i.bars, putBar)
+}
+
+func (i *prev) decodeState(d *decoder) {
+	decodeRing(d, i.bars, getBar)
+}
+
+func putBar(e *encoder, v struct{}) {}
+
+func getBar(d *decoder) struct{} {
+	return struct{}{}
+}
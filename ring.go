@@ -0,0 +1,219 @@
+package pine
+
+import (
+	"sort"
+	"time"
+)
+
+// ring is a fixed capacity buffer of interval values addressed by a
+// monotonically increasing index. Once full, pushing overwrites the oldest
+// value so steady state updates don't allocate
+type ring[T any] struct {
+	times    []time.Time
+	items    []T
+	size     int
+	start    int
+	count    int
+	interval time.Duration
+}
+
+// newRing creates a ring keeping up to capacity values. Interval is used to
+// locate values by time without searching when there are no gaps. Storage is
+// allocated when the first value is set so indicators can be created with
+// lookbacks SeriesOpts rejects later
+func newRing[T any](capacity int, interval time.Duration) *ring[T] {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &ring[T]{
+		size:     capacity,
+		interval: interval,
+	}
+}
+
+// newSeriesRing creates a ring keeping up to SeriesOpts.Max intervals
+func newSeriesRing[T any](opts SeriesOpts) *ring[T] {
+	return newRing[T](opts.Max, opts.approxInterval())
+}
+
+// historyRing returns r if it keeps the state of SeriesOpts.Max intervals and
+// of the interval before them, otherwise a new ring that does. Late execs
+// amending the oldest kept interval continue from the state before it
+func historyRing[T any](r *ring[T], opts SeriesOpts) *ring[T] {
+	if r != nil && r.capacity() == opts.Max+1 {
+		return r
+	}
+	return newRing[T](opts.Max+1, opts.approxInterval())
+}
+
+// windowRing returns r if it keeps a window of size values before each of
+// SeriesOpts.Max intervals, otherwise a new ring that does. The most recent
+// size values are the window
+func windowRing[T any](r *ring[T], size int, opts SeriesOpts) *ring[T] {
+	if r != nil && r.capacity() == size+opts.Max {
+		return r
+	}
+	return newRing[T](size+opts.Max, opts.approxInterval())
+}
+
+func (r *ring[T]) slot(idx int) int {
+	return idx % r.size
+}
+
+// len returns the number of values kept
+func (r *ring[T]) len() int {
+	return r.count
+}
+
+// capacity returns the max number of values kept
+func (r *ring[T]) capacity() int {
+	return r.size
+}
+
+// firstIndex returns the index of the oldest value
+func (r *ring[T]) firstIndex() int {
+	return r.start
+}
+
+// lastIndex returns the index of the most recent value or -1 if empty
+func (r *ring[T]) lastIndex() int {
+	return r.start + r.count - 1
+}
+
+func (r *ring[T]) has(idx int) bool {
+	return r.count > 0 && idx >= r.start && idx <= r.lastIndex()
+}
+
+// get returns value at index
+func (r *ring[T]) get(idx int) (T, bool) {
+	if !r.has(idx) {
+		var zero T
+		return zero, false
+	}
+	return r.items[r.slot(idx)], true
+}
+
+// ptr returns pointer to value at index which stays valid until overwritten
+func (r *ring[T]) ptr(idx int) *T {
+	if !r.has(idx) {
+		return nil
+	}
+	return &r.items[r.slot(idx)]
+}
+
+// at returns value offset intervals back from the most recent one
+func (r *ring[T]) at(offset int) (T, bool) {
+	return r.get(r.lastIndex() - offset)
+}
+
+// timeAt returns interval time of index
+func (r *ring[T]) timeAt(idx int) time.Time {
+	if !r.has(idx) {
+		return time.Time{}
+	}
+	return r.times[r.slot(idx)]
+}
+
+// lastTime returns interval time of the most recent value
+func (r *ring[T]) lastTime() time.Time {
+	return r.timeAt(r.lastIndex())
+}
+
+// indexOf returns index of interval t
+func (r *ring[T]) indexOf(t time.Time) (int, bool) {
+	if r.count == 0 {
+		return -1, false
+	}
+	last := r.lastIndex()
+	lt := r.times[r.slot(last)]
+	if t.Equal(lt) {
+		return last, true
+	}
+	if t.After(lt) {
+		return -1, false
+	}
+	if r.interval > 0 {
+		// without gaps the offset can be derived from the time difference
+		guess := last - int(lt.Sub(t)/r.interval)
+		if r.has(guess) && r.times[r.slot(guess)].Equal(t) {
+			return guess, true
+		}
+	}
+	if idx := r.search(t); r.has(idx) && r.times[r.slot(idx)].Equal(t) {
+		return idx, true
+	}
+	return -1, false
+}
+
+// search returns index of the oldest value of interval t or later, which is
+// one past the most recent value if there is none
+func (r *ring[T]) search(t time.Time) int {
+	return r.start + sort.Search(r.count, func(k int) bool {
+		return !r.times[r.slot(r.start+k)].Before(t)
+	})
+}
+
+// prior returns value of the most recent interval before t
+func (r *ring[T]) prior(t time.Time) (T, bool) {
+	idx := r.lastIndex()
+	if r.count > 0 && !r.times[r.slot(idx)].Before(t) {
+		idx = r.search(t) - 1
+	}
+	return r.get(idx)
+}
+
+// getTime returns value of interval t
+func (r *ring[T]) getTime(t time.Time) (T, bool) {
+	idx, ok := r.indexOf(t)
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	return r.items[r.slot(idx)], true
+}
+
+// set replaces value of interval t if kept, otherwise appends it if t is after
+// the most recent interval. It returns index of the value or false if t is
+// older than kept values
+func (r *ring[T]) set(t time.Time, v T) (int, bool) {
+	if idx, ok := r.indexOf(t); ok {
+		r.items[r.slot(idx)] = v
+		return idx, true
+	}
+	if r.count > 0 && !t.After(r.lastTime()) {
+		return -1, false
+	}
+	if r.items == nil {
+		r.times = make([]time.Time, r.size)
+		r.items = make([]T, r.size)
+	}
+	if r.count == r.size {
+		r.start++
+	} else {
+		r.count++
+	}
+	idx := r.lastIndex()
+	r.times[r.slot(idx)] = t
+	r.items[r.slot(idx)] = v
+	return idx, true
+}
+
+// clear removes all values
+func (r *ring[T]) clear() {
+	r.truncate(r.start)
+}
+
+// rewind removes values of intervals from t onwards
+func (r *ring[T]) rewind(t time.Time) {
+	r.truncate(r.search(t))
+}
+
+// truncate removes values from index onwards
+func (r *ring[T]) truncate(idx int) {
+	if idx < r.start {
+		idx = r.start
+	}
+	if idx <= r.lastIndex() {
+		r.count = idx - r.start
+	}
+}
@@ -0,0 +1,209 @@
+package pine
+
+import (
+	"math"
+	"time"
+)
+
+// rollingWindow keeps the last size values of a source together with running
+// sums so mean, variance and linear regression are O(1) per update. Sums are
+// kept with every value so setting the most recent interval again continues
+// from the sums before it, which revises the current interval, and the window
+// can be rewound to any kept interval once SeriesOpts are applied. Sums are
+// recomputed once every size pushes to bound float drift, which keeps the
+// amortized cost constant
+type rollingWindow struct {
+	size   int
+	values *ring[rollingValue]
+	rollingSums
+}
+
+// rollingSums are the running sums of the window after a value is pushed
+type rollingSums struct {
+	sum float64
+	// mean and m2 are Welford's running mean and sum of squared differences
+	mean float64
+	m2   float64
+	// sumjy is Σ j*y where j is the position in the window, oldest being 0
+	sumjy  float64
+	pushes int
+}
+
+type rollingValue struct {
+	x    float64
+	sums rollingSums
+}
+
+func newRollingWindow(size int) *rollingWindow {
+	return &rollingWindow{
+		size: size,
+		// one more value keeps the sums before the most recent one
+		values: newRing[rollingValue](size+1, 0),
+	}
+}
+
+// applyOpts keeps values of the window of every interval kept by opts
+func (w *rollingWindow) applyOpts(opts SeriesOpts) {
+	if w.values.capacity() != w.size+opts.Max {
+		w.reset()
+	}
+	w.values = windowRing(w.values, w.size, opts)
+}
+
+// before returns true if t is before the most recent interval in the window
+func (w *rollingWindow) before(t time.Time) bool {
+	return w.values.len() > 0 && t.Before(w.values.lastTime())
+}
+
+func (w *rollingWindow) full() bool {
+	return w.size > 0 && w.values.len() >= w.size
+}
+
+// count returns the number of values in the window
+func (w *rollingWindow) count() int {
+	if n := w.values.len(); n < w.size {
+		return n
+	}
+	return w.size
+}
+
+// first returns index of the oldest value in the window
+func (w *rollingWindow) first() int {
+	return w.values.lastIndex() - w.count() + 1
+}
+
+// set replaces the value of the most recent interval if t is the same,
+// otherwise pushes it out the oldest value once the window is full
+func (w *rollingWindow) set(t time.Time, x float64) {
+	if w.size <= 0 {
+		return
+	}
+	if w.values.len() > 0 && w.values.lastTime().Equal(t) {
+		w.values.truncate(w.values.lastIndex())
+		w.restore()
+	}
+	n := w.count()
+	if n == w.size {
+		old, _ := w.values.get(w.first())
+		w.sum -= old.x
+		w.remove(old.x, n)
+		// every remaining value moves one position towards the oldest
+		w.sumjy -= w.sum
+		n--
+	}
+	idx, _ := w.values.set(t, rollingValue{x: x})
+	w.sum += x
+	w.sumjy += float64(n) * x
+	d := x - w.mean
+	w.mean += d / float64(n+1)
+	w.m2 += d * (x - w.mean)
+	w.clamp()
+	w.pushes++
+	if w.pushes >= w.size {
+		w.resync()
+	}
+	w.values.ptr(idx).sums = w.rollingSums
+}
+
+// restore continues from the sums of the most recent value
+func (w *rollingWindow) restore() {
+	v, _ := w.values.at(0)
+	w.rollingSums = v.sums
+}
+
+// reset removes all values
+func (w *rollingWindow) reset() {
+	w.values.clear()
+	w.rollingSums = rollingSums{}
+}
+
+// rewind removes values of intervals from t onwards
+func (w *rollingWindow) rewind(t time.Time) {
+	w.values.rewind(t)
+	w.restore()
+}
+
+// remove takes x out of Welford's running values of n values
+func (w *rollingWindow) remove(x float64, n int) {
+	if n <= 1 {
+		w.mean, w.m2 = 0, 0
+		return
+	}
+	d := x - w.mean
+	w.mean -= d / float64(n-1)
+	w.m2 -= d * (x - w.mean)
+}
+
+func (w *rollingWindow) clamp() {
+	if w.m2 < 0 {
+		w.m2 = 0
+	}
+}
+
+// resync recomputes running sums from the values in the window
+func (w *rollingWindow) resync() {
+	w.rollingSums = rollingSums{}
+	first := w.first()
+	for j := 0; j < w.count(); j++ {
+		v, _ := w.values.get(first + j)
+		x := v.x
+		w.sum += x
+		w.sumjy += float64(j) * x
+		d := x - w.mean
+		w.mean += d / float64(j+1)
+		w.m2 += d * (x - w.mean)
+	}
+}
+
+// average returns the simple average of the window
+func (w *rollingWindow) average() float64 {
+	return w.sum / float64(w.count())
+}
+
+// stdDev returns the population standard deviation of the window
+func (w *rollingWindow) stdDev() float64 {
+	return math.Sqrt(w.m2 / float64(w.count()))
+}
+
+// linReg returns the least squares line through the window evaluated at the
+// most recent value
+func (w *rollingWindow) linReg() float64 {
+	n := float64(w.count())
+	if n < 2 {
+		return w.average()
+	}
+	// positions are 0 to n-1 so their sums have closed forms
+	sumj := n * (n - 1) / 2
+	sumjj := (n - 1) * n * (2*n - 1) / 6
+	slope := (n*w.sumjy - sumj*w.sum) / (n*sumjj - sumj*sumj)
+	intercept := (w.sum - slope*sumj) / n
+	return intercept + slope*(n-1)
+}
+
+func (w *rollingWindow) encode(e *encoder) {
+	encodeRing(e, w.values, func(e *encoder, v rollingValue) {
+		e.float(v.x)
+		e.float(v.sums.sum)
+		e.float(v.sums.mean)
+		e.float(v.sums.m2)
+		e.float(v.sums.sumjy)
+		e.int(v.sums.pushes)
+	})
+}
+
+// decode restores values and continues from the sums of the most recent one
+func (w *rollingWindow) decode(d *decoder) {
+	decodeRing(d, w.values, func(d *decoder) rollingValue {
+		return rollingValue{
+			x: d.float(),
+			sums: rollingSums{
+				sum:    d.float(),
+				mean:   d.float(),
+				m2:     d.float(),
+				sumjy:  d.float(),
+				pushes: d.int(),
+			},
+		}
+	})
+	w.restore()
+}
@@ -0,0 +1,166 @@
+package pine
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+type rsi struct {
+	valueStore
+	lookback int
+	opts     *SeriesOpts
+	// states are generated values including the one before the oldest kept
+	// interval so any kept interval can be generated again
+	states    *ring[rsiValue]
+	srcvalues *ring[float64]
+	src       Indicator
+}
+
+// rsiValue keeps Wilder's running averages of an interval so the next
+// interval (or a revision of this one) can be derived from it
+type rsiValue struct {
+	AvgGain float64
+	AvgLoss float64
+}
+
+// NewRSI creates a new relative strength index indicator. Gains and losses
+// are smoothed with Wilder's moving average (RMA) like Pine's ta.rsi
+func NewRSI(i Indicator, lookback int) Indicator {
+	return &rsi{
+		src:      i,
+		lookback: lookback,
+		// lookback number of changes requires one more src value
+		srcvalues: newRing[float64](lookback+1, 0),
+		states:    newRing[rsiValue](defaultMax, 0),
+	}
+}
+
+func (i *rsi) generateRSI(t time.Time) {
+	if i.srcvalues.len() <= i.lookback {
+		return
+	}
+	var gain, loss float64
+	prev, ok := i.states.prior(t)
+	if !ok {
+		// seed with simple average of gains and losses
+		for j := i.lookback; j > 0; j-- {
+			p, _ := i.srcvalues.at(j)
+			c, _ := i.srcvalues.at(j - 1)
+			g, l := gainLoss(p, c)
+			gain += g
+			loss += l
+		}
+		gain /= float64(i.lookback)
+		loss /= float64(i.lookback)
+	} else {
+		p, _ := i.srcvalues.at(1)
+		c, _ := i.srcvalues.at(0)
+		g, l := gainLoss(p, c)
+		n := float64(i.lookback)
+		gain = (prev.AvgGain*(n-1) + g) / n
+		loss = (prev.AvgLoss*(n-1) + l) / n
+	}
+	i.states.set(t, rsiValue{
+		AvgGain: gain,
+		AvgLoss: loss,
+	})
+	i.setValue(t, rsiFromAvg(gain, loss))
+}
+
+func gainLoss(prev, cur float64) (float64, float64) {
+	diff := cur - prev
+	return math.Max(diff, 0), math.Max(-diff, 0)
+}
+
+func rsiFromAvg(gain, loss float64) float64 {
+	if loss == 0 {
+		return 100
+	}
+	if gain == 0 {
+		return 0
+	}
+	return 100 - 100/(1+gain/loss)
+}
+
+func (i *rsi) Update(v OHLCV) error {
+	if err := i.src.Update(v); err != nil {
+		return fmt.Errorf("error received from src in RSI: %w", err)
+	}
+	return i.updateNode(v)
+}
+
+func (i *rsi) inputs() []node {
+	return nodesOf(i.src)
+}
+
+func (i *rsi) updateNode(v OHLCV) error {
+	if i.srcvalues.len() > 0 && v.S.Before(i.srcvalues.lastTime()) {
+		// already generated
+		return nil
+	}
+	val, ok := valueOf(i.src, v.S)
+	if !ok {
+		// src value does not exist so cannot generate
+		return nil
+	}
+	i.srcvalues.set(v.S, val)
+	i.generateRSI(v.S)
+	return nil
+}
+
+func (i *rsi) ApplyOpts(opts SeriesOpts) error {
+	if opts.Max < i.lookback {
+		return errors.New("SeriesOpts max cannot be less than RSI lookback value")
+	}
+	if err := i.src.ApplyOpts(opts); err != nil {
+		return fmt.Errorf("error applying opts in source: %w", err)
+	}
+	i.initStore(opts)
+	i.srcvalues = windowRing(i.srcvalues, i.lookback+1, opts)
+	i.states = historyRing(i.states, opts)
+	i.opts = &opts
+	return nil
+}
+
+func (i *rsi) reset() {
+	resetIndicator(i.src)
+	i.srcvalues.clear()
+	i.states.clear()
+	i.resetStore()
+}
+
+func (i *rsi) rewind(t time.Time) error {
+	if err := rewindIndicator(i.src, t); err != nil {
+		return err
+	}
+	i.srcvalues.rewind(t)
+	i.states.rewind(t)
+	i.rewindStore(t)
+	return nil
+}
+
+func (i *rsi) encodeState(e *encoder) {
+	i.valueStore.encode(e)
+	encodeRing(e, i.srcvalues, putFloat)
+	encodeRing(e, i.states, putRSIValue)
+}
+
+func (i *rsi) decodeState(d *decoder) {
+	i.valueStore.decode(d)
+	decodeRing(d, i.srcvalues, getFloat)
+	decodeRing(d, i.states, getRSIValue)
+}
+
+func putRSIValue(e *encoder, v rsiValue) {
+	e.float(v.AvgGain)
+	e.float(v.AvgLoss)
+}
+
+func getRSIValue(d *decoder) rsiValue {
+	return rsiValue{
+		AvgGain: d.float(),
+		AvgLoss: d.float(),
+	}
+}
@@ -0,0 +1,505 @@
+package script
+
+import (
+	"math"
+	"strings"
+)
+
+type valueKind int
+
+const (
+	valueNumber valueKind = iota
+	valueBool
+	valueString
+	valueSeries
+	valueTuple
+)
+
+// maxLength is the largest length or history offset so lookbacks fit in an
+// int on every platform
+const maxLength = math.MaxInt32
+
+// value is the result of an expression
+type value struct {
+	kind  valueKind
+	num   float64
+	isInt bool
+	b     bool
+	s     string
+	call  *Call
+	tuple []*Call
+	// ref is the name of the variable a number or bool was read from
+	ref string
+}
+
+func (v value) describe() string {
+	switch v.kind {
+	case valueNumber:
+		if v.isInt {
+			return "int"
+		}
+		return "float"
+	case valueBool:
+		return "bool"
+	case valueString:
+		return "string"
+	case valueTuple:
+		return "tuple"
+	}
+	return "series"
+}
+
+// builtinProps are the built-in series of OHLCV properties
+var builtinProps = map[string]string{
+	"open":   "OHLCPropOpen",
+	"high":   "OHLCPropHigh",
+	"low":    "OHLCPropLow",
+	"close":  "OHLCPropClose",
+	"volume": "OHLCPropVolume",
+	"hl2":    "OHLCPropHL2",
+	"hlc3":   "OHLCPropHLC3",
+}
+
+type compiler struct {
+	script *Script
+	env    map[string]value
+	// builtins keeps calls of built-in series so they are shared
+	builtins map[string]*Call
+}
+
+func newCompiler() *compiler {
+	return &compiler{
+		script:   &Script{},
+		env:      make(map[string]value),
+		builtins: make(map[string]*Call),
+	}
+}
+
+func (c *compiler) stmt(s stmt) error {
+	switch s := s.(type) {
+	case *assignStmt:
+		return c.assign(s)
+	case *exprStmt:
+		if call, ok := s.x.(*callExpr); ok {
+			switch call.fn {
+			case "indicator", "study":
+				return c.declaration(call)
+			case "plot":
+				return c.plot(call)
+			}
+		}
+		_, err := c.expr(s.x)
+		return err
+	}
+	return errorf(s.position(), "unsupported statement")
+}
+
+// declaration reads the title of the script and ignores other arguments
+func (c *compiler) declaration(call *callExpr) error {
+	for j, a := range call.args {
+		if (a.name == "" && j == 0) || a.name == "title" {
+			v, err := c.expr(a.x)
+			if err != nil {
+				return err
+			}
+			if v.kind != valueString {
+				return errorf(a.pos, "title must be a string")
+			}
+			c.script.Title = v.s
+		}
+	}
+	return nil
+}
+
+// plot checks the plotted series and ignores styling arguments
+func (c *compiler) plot(call *callExpr) error {
+	for j, a := range call.args {
+		if (a.name == "" && j == 0) || a.name == "series" {
+			_, err := c.expr(a.x)
+			return err
+		}
+	}
+	return errorf(call.pos, "plot requires a series")
+}
+
+func (c *compiler) assign(s *assignStmt) error {
+	for _, name := range s.names {
+		if _, ok := c.env[name]; ok {
+			return errorf(s.pos, "%s is already declared", name)
+		}
+		if _, ok := builtinProps[name]; ok {
+			return errorf(s.pos, "%s cannot be redeclared", name)
+		}
+	}
+	v, err := c.expr(s.x)
+	if err != nil {
+		return err
+	}
+	if !s.tuple {
+		if v.kind == valueTuple {
+			return errorf(s.x.position(), "tuple of %d values must be assigned to [a, b, ...]", len(v.tuple))
+		}
+		c.declare(s.names[0], v)
+		return nil
+	}
+	if v.kind != valueTuple {
+		return errorf(s.x.position(), "%s cannot be assigned to a tuple", v.describe())
+	}
+	if len(s.names) != len(v.tuple) {
+		return errorf(s.pos, "tuple of %d values is assigned to %d variables", len(v.tuple), len(s.names))
+	}
+	for j, name := range s.names {
+		if name != "_" {
+			c.declare(name, value{kind: valueSeries, call: v.tuple[j]})
+		}
+	}
+	return nil
+}
+
+func (c *compiler) declare(name string, v value) {
+	switch v.kind {
+	case valueSeries:
+		c.script.Vars = append(c.script.Vars, Var{Name: name, Call: v.call})
+	case valueNumber, valueBool:
+		c.script.Consts = append(c.script.Consts, Const{Name: name, Value: v.arg()})
+		v.ref = name
+	}
+	c.env[name] = v
+}
+
+// arg returns v as an argument of a call
+func (v value) arg() Arg {
+	switch v.kind {
+	case valueSeries:
+		return Arg{Kind: ArgIndicator, Call: v.call}
+	case valueBool:
+		return Arg{Kind: ArgBool, Bool: v.b, Ref: v.ref}
+	case valueNumber:
+		if v.isInt {
+			return Arg{Kind: ArgInt, Num: v.num, Ref: v.ref}
+		}
+	}
+	return Arg{Kind: ArgFloat, Num: v.num, Ref: v.ref}
+}
+
+func (c *compiler) expr(x expr) (value, error) {
+	switch x := x.(type) {
+	case *numberLit:
+		return value{kind: valueNumber, num: x.value, isInt: x.isInt}, nil
+	case *stringLit:
+		return value{kind: valueString, s: x.value}, nil
+	case *boolLit:
+		return value{kind: valueBool, b: x.value}, nil
+	case *identExpr:
+		return c.ident(x)
+	case *unaryExpr:
+		return c.unary(x)
+	case *binaryExpr:
+		return c.binary(x)
+	case *indexExpr:
+		return c.index(x)
+	case *callExpr:
+		return c.call(x)
+	case *ternaryExpr:
+		return value{}, errorf(x.pos, "conditional expressions are not supported")
+	}
+	return value{}, errorf(x.position(), "unsupported expression")
+}
+
+func (c *compiler) ident(x *identExpr) (value, error) {
+	if v, ok := c.env[x.name]; ok {
+		return v, nil
+	}
+	if call := c.builtin(x.name); call != nil {
+		return value{kind: valueSeries, call: call}, nil
+	}
+	if x.name == "na" {
+		return value{}, errorf(x.pos, "na is not supported")
+	}
+	return value{}, errorf(x.pos, "undeclared identifier %s", x.name)
+}
+
+// builtin returns the call of built-in series name or nil
+func (c *compiler) builtin(name string) *Call {
+	if call, ok := c.builtins[name]; ok {
+		return call
+	}
+	var call *Call
+	switch name {
+	case "ta.tr":
+		call = &Call{Func: "NewTrueRange"}
+	case "ta.vwap":
+		call = &Call{
+			Of: &Call{
+				Func: "NewVWAP",
+				Args: []Arg{{Kind: ArgConst, Const: "VWAPOpts{}"}},
+			},
+			Field: "VWAP",
+		}
+	default:
+		prop, ok := builtinProps[name]
+		if !ok {
+			return nil
+		}
+		call = &Call{
+			Func: "NewOHLCProp",
+			Args: []Arg{{Kind: ArgConst, Const: prop}},
+		}
+	}
+	c.builtins[name] = call
+	return call
+}
+
+func (c *compiler) unary(x *unaryExpr) (value, error) {
+	if x.op == "not" {
+		return value{}, errorf(x.pos, "operator not is not supported")
+	}
+	v, err := c.expr(x.x)
+	if err != nil {
+		return value{}, err
+	}
+	switch {
+	case x.op == "+" && (v.kind == valueNumber || v.kind == valueSeries):
+		return v, nil
+	case v.kind == valueNumber:
+		return value{kind: valueNumber, num: -v.num, isInt: v.isInt}, nil
+	case v.kind == valueSeries:
+		return arithmetic("ArithmeticSubtraction", value{kind: valueNumber, isInt: true}, v), nil
+	}
+	return value{}, errorf(x.pos, "operator %s is not supported on %s", x.op, v.describe())
+}
+
+// arithmeticTypes are the pine arithmetic types of operators
+var arithmeticTypes = map[string]string{
+	"+": "ArithmeticAddition",
+	"-": "ArithmeticSubtraction",
+	"*": "ArithmeticMultiplication",
+	"/": "ArithmeticDivision",
+}
+
+func (c *compiler) binary(x *binaryExpr) (value, error) {
+	a, err := c.expr(x.x)
+	if err != nil {
+		return value{}, err
+	}
+	b, err := c.expr(x.y)
+	if err != nil {
+		return value{}, err
+	}
+	if a.kind == valueNumber && b.kind == valueNumber {
+		return fold(x, a, b)
+	}
+	typ, ok := arithmeticTypes[x.op]
+	if !ok {
+		return value{}, errorf(x.pos, "operator %s is not supported", x.op)
+	}
+	for _, v := range []value{a, b} {
+		if v.kind != valueNumber && v.kind != valueSeries {
+			return value{}, errorf(x.pos, "operator %s is not supported on %s", x.op, v.describe())
+		}
+	}
+	return arithmetic(typ, a, b), nil
+}
+
+// fold evaluates operators on numbers
+func fold(x *binaryExpr, a, b value) (value, error) {
+	v := value{kind: valueNumber, isInt: a.isInt && b.isInt}
+	switch x.op {
+	case "+":
+		v.num = a.num + b.num
+	case "-":
+		v.num = a.num - b.num
+	case "*":
+		v.num = a.num * b.num
+	case "/":
+		if b.num == 0 {
+			return value{}, errorf(x.pos, "division by zero")
+		}
+		v.num = a.num / b.num
+		v.isInt = v.isInt && v.num == math.Trunc(v.num)
+	case "%":
+		if !v.isInt || b.num == 0 {
+			return value{}, errorf(x.pos, "operator %% requires a non-zero int")
+		}
+		v.num = math.Mod(a.num, b.num)
+	default:
+		return value{}, errorf(x.pos, "operator %s is not supported", x.op)
+	}
+	return v, nil
+}
+
+// arithmetic returns the series of operation typ on a and b where numbers are
+// constant series
+func arithmetic(typ string, a, b value) value {
+	return value{
+		kind: valueSeries,
+		call: &Call{
+			Func: "NewArithmetic",
+			Args: []Arg{
+				{Kind: ArgConst, Const: typ},
+				seriesArg(a),
+				seriesArg(b),
+				{Kind: ArgConst, Const: "ArithmeticOpts{}"},
+			},
+		},
+	}
+}
+
+// seriesArg returns series v or the constant series of number v
+func seriesArg(v value) Arg {
+	if v.kind == valueSeries {
+		return v.arg()
+	}
+	return Arg{
+		Kind: ArgIndicator,
+		Call: &Call{
+			Func: "NewConstant",
+			Args: []Arg{{Kind: ArgFloat, Num: v.num, Ref: v.ref}},
+		},
+	}
+}
+
+func (c *compiler) index(x *indexExpr) (value, error) {
+	v, err := c.expr(x.x)
+	if err != nil {
+		return value{}, err
+	}
+	n, err := c.expr(x.n)
+	if err != nil {
+		return value{}, err
+	}
+	if n.kind != valueNumber || !n.isInt || n.num < 0 {
+		return value{}, errorf(x.n.position(), "history offset must be a non-negative int")
+	}
+	if n.num > maxLength {
+		return value{}, errorf(x.n.position(), "history offset must be at most %d", maxLength)
+	}
+	switch {
+	case v.kind == valueNumber || v.kind == valueBool || n.num == 0:
+		return v, nil
+	case v.kind == valueSeries:
+		return value{
+			kind: valueSeries,
+			call: &Call{
+				Func: "NewPrevious",
+				Args: []Arg{v.arg(), n.arg()},
+			},
+		}, nil
+	}
+	return value{}, errorf(x.pos, "history reference is not supported on %s", v.describe())
+}
+
+func (c *compiler) call(x *callExpr) (value, error) {
+	fn, ok := functions[x.fn]
+	if !ok {
+		if strings.HasPrefix(x.fn, "ta.") || strings.HasPrefix(x.fn, "math.") || strings.HasPrefix(x.fn, "input") {
+			return value{}, errorf(x.pos, "function %s is not supported", x.fn)
+		}
+		return value{}, errorf(x.pos, "unknown function %s", x.fn)
+	}
+	args := make([]argValue, len(fn.params))
+	named := false
+	for j, a := range x.args {
+		idx := j
+		if a.name != "" {
+			named = true
+			idx = -1
+			for k, p := range fn.params {
+				if p == a.name {
+					idx = k
+				}
+			}
+			if idx < 0 {
+				return value{}, errorf(a.pos, "%s has no argument %s", x.fn, a.name)
+			}
+		} else if named {
+			return value{}, errorf(a.pos, "positional argument after named arguments")
+		}
+		if idx >= len(fn.params) {
+			return value{}, errorf(a.pos, "too many arguments to %s", x.fn)
+		}
+		if args[idx].set {
+			return value{}, errorf(a.pos, "argument %s is set twice", fn.params[idx])
+		}
+		v, err := c.expr(a.x)
+		if err != nil {
+			return value{}, err
+		}
+		args[idx] = argValue{value: v, pos: a.pos, name: fn.params[idx], set: true}
+	}
+	for j := range args {
+		if !args[j].set {
+			args[j] = argValue{pos: x.pos, name: fn.params[j]}
+		}
+	}
+	return fn.compile(c, x, args)
+}
+
+// argValue is an argument of a function call
+type argValue struct {
+	value
+	pos  Pos
+	name string
+	set  bool
+}
+
+func (a argValue) required() error {
+	if !a.set {
+		return errorf(a.pos, "missing argument %s", a.name)
+	}
+	return nil
+}
+
+// series returns argument a as a series where numbers are constant series
+func (a argValue) series() (Arg, error) {
+	if err := a.required(); err != nil {
+		return Arg{}, err
+	}
+	if a.kind != valueSeries && a.kind != valueNumber {
+		return Arg{}, errorf(a.pos, "%s must be a series but is %s", a.name, a.describe())
+	}
+	return seriesArg(a.value), nil
+}
+
+// length returns argument a as a positive int
+func (a argValue) length() (Arg, error) {
+	if err := a.required(); err != nil {
+		return Arg{}, err
+	}
+	if a.kind != valueNumber || !a.isInt || a.num < 1 {
+		return Arg{}, errorf(a.pos, "%s must be a positive int but is %s", a.name, a.describe())
+	}
+	if a.num > maxLength {
+		return Arg{}, errorf(a.pos, "%s must be at most %d", a.name, maxLength)
+	}
+	return a.arg(), nil
+}
+
+// float returns argument a as a float64 or def if it is not set
+func (a argValue) float(def float64) (Arg, error) {
+	if !a.set {
+		return Arg{Kind: ArgFloat, Num: def}, nil
+	}
+	if a.kind != valueNumber {
+		return Arg{}, errorf(a.pos, "%s must be a number but is %s", a.name, a.describe())
+	}
+	arg := a.arg()
+	arg.Kind = ArgFloat
+	return arg, nil
+}
+
+// bool returns argument a as a bool or def if it is not set
+func (a argValue) bool(def bool) (Arg, error) {
+	if !a.set {
+		return Arg{Kind: ArgBool, Bool: def}, nil
+	}
+	if a.kind != valueBool {
+		return Arg{}, errorf(a.pos, "%s must be a bool but is %s", a.name, a.describe())
+	}
+	return a.arg(), nil
+}
+
+// isBuiltin reports whether argument a is built-in series name
+func (c *compiler) isBuiltin(a argValue, name string) bool {
+	return a.kind == valueSeries && a.call == c.builtin(name)
+}
@@ -0,0 +1,351 @@
+package script
+
+import "math"
+
+// function is a built-in function of the subset with Pine's parameter names
+// so arguments can be named
+type function struct {
+	params  []string
+	compile func(c *compiler, x *callExpr, args []argValue) (value, error)
+}
+
+var functions map[string]function
+
+func init() {
+	functions = map[string]function{
+		"input":        {[]string{"defval", "title"}, compileInput(-1)},
+		"input.int":    {[]string{"defval", "title", "minval", "maxval", "step", "tooltip", "inline", "group", "confirm"}, compileInput(valueNumber)},
+		"input.float":  {[]string{"defval", "title", "minval", "maxval", "step", "tooltip", "inline", "group", "confirm"}, compileInput(valueNumber)},
+		"input.bool":   {[]string{"defval", "title", "tooltip", "inline", "group", "confirm"}, compileInput(valueBool)},
+		"input.string": {[]string{"defval", "title", "options", "tooltip", "inline", "group", "confirm"}, compileInput(valueString)},
+		"input.source": {[]string{"defval", "title", "tooltip", "inline", "group"}, compileInput(valueSeries)},
+
+		"ta.sma":         {[]string{"source", "length"}, compileSourceLength("NewSMA")},
+		"ta.ema":         {[]string{"source", "length"}, compileSourceLength("NewEMA")},
+		"ta.rma":         {[]string{"source", "length"}, compileSourceLength("NewRMA")},
+		"ta.wma":         {[]string{"source", "length"}, compileSourceLength("NewWMA")},
+		"ta.hma":         {[]string{"source", "length"}, compileSourceLength("NewHMA")},
+		"ta.vwma":        {[]string{"source", "length"}, compileSourceLength("NewVWMA")},
+		"ta.rsi":         {[]string{"source", "length"}, compileSourceLength("NewRSI")},
+		"ta.median":      {[]string{"source", "length"}, compileSourceLength("NewMedian")},
+		"ta.percentrank": {[]string{"source", "length"}, compileSourceLength("NewPercentRank")},
+		"ta.highest":     {[]string{"source", "length"}, compileExtremum("NewHighest", "high")},
+		"ta.lowest":      {[]string{"source", "length"}, compileExtremum("NewLowest", "low")},
+		"ta.highestbars": {[]string{"source", "length"}, compileExtremum("NewHighestBars", "high")},
+		"ta.lowestbars":  {[]string{"source", "length"}, compileExtremum("NewLowestBars", "low")},
+		"ta.stdev":       {[]string{"source", "length", "biased"}, compileStdev},
+		"ta.linreg":      {[]string{"source", "length", "offset"}, compileLinReg},
+		"ta.change":      {[]string{"source", "length"}, compileChange},
+		"ta.mom":         {[]string{"source", "length"}, compileChange},
+		"ta.alma":        {[]string{"series", "length", "offset", "sigma"}, compileALMA},
+		"ta.swma":        {[]string{"source"}, compileSWMA},
+		"ta.atr":         {[]string{"length"}, compileLength("NewATR")},
+		"ta.wpr":         {[]string{"length"}, compileLength("NewWilliamsR")},
+		"ta.crossover":   {[]string{"source1", "source2"}, compileCross("NewCrossover")},
+		"ta.crossunder":  {[]string{"source1", "source2"}, compileCross("NewCrossunder")},
+		"ta.cross":       {[]string{"source1", "source2"}, compileCross("NewCross")},
+		"ta.stoch":       {[]string{"source", "high", "low", "length"}, compileStoch},
+		"ta.vwap":        {[]string{"source"}, compileVWAP},
+
+		"ta.percentile_linear_interpolation": {[]string{"source", "length", "percentage"}, compilePercentile("NewPercentile")},
+		"ta.percentile_nearest_rank":         {[]string{"source", "length", "percentage"}, compilePercentile("NewPercentileNearestRank")},
+
+		"ta.macd": {[]string{"source", "fastlen", "slowlen", "siglen"}, compileMACD},
+		"ta.bb":   {[]string{"series", "length", "mult"}, compileBands("NewBollingerBands")},
+		"ta.kc":   {[]string{"series", "length", "mult", "useTrueRange"}, compileBands("NewKeltnerChannels")},
+
+		"math.max": {[]string{"number0", "number1"}, compileMinMax("ArithmeticMax", math.Max)},
+		"math.min": {[]string{"number0", "number1"}, compileMinMax("ArithmeticMin", math.Min)},
+		"math.abs": {[]string{"number"}, compileAbs},
+	}
+}
+
+// series returns the series of call fn with args
+func series(fn string, args ...Arg) value {
+	return value{
+		kind: valueSeries,
+		call: &Call{Func: fn, Args: args},
+	}
+}
+
+// compileInput returns the default value of an input which must be of kind
+// if kind is not negative
+func compileInput(kind valueKind) func(c *compiler, x *callExpr, args []argValue) (value, error) {
+	return func(c *compiler, x *callExpr, args []argValue) (value, error) {
+		def := args[0]
+		if err := def.required(); err != nil {
+			return value{}, err
+		}
+		if kind >= 0 && def.kind != kind {
+			return value{}, errorf(def.pos, "defval of %s cannot be %s", x.fn, def.describe())
+		}
+		if x.fn == "input.float" {
+			def.isInt = false
+		}
+		if x.fn == "input.int" && !def.isInt {
+			return value{}, errorf(def.pos, "defval of %s must be an int", x.fn)
+		}
+		return def.value, nil
+	}
+}
+
+func compileSourceLength(fn string) func(c *compiler, x *callExpr, args []argValue) (value, error) {
+	return func(c *compiler, x *callExpr, args []argValue) (value, error) {
+		src, err := args[0].series()
+		if err != nil {
+			return value{}, err
+		}
+		length, err := args[1].length()
+		if err != nil {
+			return value{}, err
+		}
+		return series(fn, src, length), nil
+	}
+}
+
+func compileLength(fn string) func(c *compiler, x *callExpr, args []argValue) (value, error) {
+	return func(c *compiler, x *callExpr, args []argValue) (value, error) {
+		length, err := args[0].length()
+		if err != nil {
+			return value{}, err
+		}
+		return series(fn, length), nil
+	}
+}
+
+// compileExtremum also accepts a length only like ta.highest(10) in which case
+// source is prop
+func compileExtremum(fn, prop string) func(c *compiler, x *callExpr, args []argValue) (value, error) {
+	return func(c *compiler, x *callExpr, args []argValue) (value, error) {
+		if args[0].set && !args[1].set && args[0].kind == valueNumber {
+			args[1] = args[0]
+			args[1].name = "length"
+			args[0] = argValue{value: value{kind: valueSeries, call: c.builtin(prop)}, name: "source", set: true}
+		}
+		return compileSourceLength(fn)(c, x, args)
+	}
+}
+
+func compileStdev(c *compiler, x *callExpr, args []argValue) (value, error) {
+	biased, err := args[2].bool(true)
+	if err != nil {
+		return value{}, err
+	}
+	if !biased.Bool {
+		return value{}, errorf(args[2].pos, "only biased standard deviation is supported")
+	}
+	return compileSourceLength("NewStdDev")(c, x, args[:2])
+}
+
+func compileLinReg(c *compiler, x *callExpr, args []argValue) (value, error) {
+	offset := args[2]
+	if err := offset.required(); err != nil {
+		return value{}, err
+	}
+	if offset.kind != valueNumber || offset.num != 0 {
+		return value{}, errorf(offset.pos, "only offset 0 is supported")
+	}
+	return compileSourceLength("NewLinReg")(c, x, args[:2])
+}
+
+func compileChange(c *compiler, x *callExpr, args []argValue) (value, error) {
+	src, err := args[0].series()
+	if err != nil {
+		return value{}, err
+	}
+	length := Arg{Kind: ArgInt, Num: 1}
+	if args[1].set {
+		if length, err = args[1].length(); err != nil {
+			return value{}, err
+		}
+	}
+	return series("NewChange", src, length, Arg{Kind: ArgConst, Const: "nil"}), nil
+}
+
+func compileALMA(c *compiler, x *callExpr, args []argValue) (value, error) {
+	src, err := args[0].series()
+	if err != nil {
+		return value{}, err
+	}
+	length, err := args[1].length()
+	if err != nil {
+		return value{}, err
+	}
+	for _, a := range args[2:] {
+		if err := a.required(); err != nil {
+			return value{}, err
+		}
+	}
+	offset, err := args[2].float(0)
+	if err != nil {
+		return value{}, err
+	}
+	sigma, err := args[3].float(0)
+	if err != nil {
+		return value{}, err
+	}
+	return series("NewALMA", src, length, offset, sigma), nil
+}
+
+func compileSWMA(c *compiler, x *callExpr, args []argValue) (value, error) {
+	src, err := args[0].series()
+	if err != nil {
+		return value{}, err
+	}
+	return series("NewSWMA", src), nil
+}
+
+func compileCross(fn string) func(c *compiler, x *callExpr, args []argValue) (value, error) {
+	return func(c *compiler, x *callExpr, args []argValue) (value, error) {
+		a, err := args[0].series()
+		if err != nil {
+			return value{}, err
+		}
+		b, err := args[1].series()
+		if err != nil {
+			return value{}, err
+		}
+		return series(fn, a, b), nil
+	}
+}
+
+// compileStoch supports the raw stochastic of close, high and low
+func compileStoch(c *compiler, x *callExpr, args []argValue) (value, error) {
+	for j, name := range []string{"close", "high", "low"} {
+		if err := args[j].required(); err != nil {
+			return value{}, err
+		}
+		if !c.isBuiltin(args[j], name) {
+			return value{}, errorf(args[j].pos, "%s must be %s", args[j].name, name)
+		}
+	}
+	length, err := args[3].length()
+	if err != nil {
+		return value{}, err
+	}
+	one := Arg{Kind: ArgInt, Num: 1}
+	return value{
+		kind: valueSeries,
+		call: &Call{
+			Of:    &Call{Func: "NewStoch", Args: []Arg{length, one, one}},
+			Field: "K",
+		},
+	}, nil
+}
+
+// compileVWAP supports the typical price source of pine VWAP
+func compileVWAP(c *compiler, x *callExpr, args []argValue) (value, error) {
+	if err := args[0].required(); err != nil {
+		return value{}, err
+	}
+	if !c.isBuiltin(args[0], "hlc3") {
+		return value{}, errorf(args[0].pos, "source must be hlc3")
+	}
+	return value{kind: valueSeries, call: c.builtin("ta.vwap")}, nil
+}
+
+func compilePercentile(fn string) func(c *compiler, x *callExpr, args []argValue) (value, error) {
+	return func(c *compiler, x *callExpr, args []argValue) (value, error) {
+		src, err := args[0].series()
+		if err != nil {
+			return value{}, err
+		}
+		length, err := args[1].length()
+		if err != nil {
+			return value{}, err
+		}
+		if err := args[2].required(); err != nil {
+			return value{}, err
+		}
+		p, err := args[2].float(0)
+		if err != nil {
+			return value{}, err
+		}
+		return series(fn, src, length, p), nil
+	}
+}
+
+// tuple returns fields of the call of constructor fn with args
+func tuple(fn string, args []Arg, fields ...string) value {
+	of := &Call{Func: fn, Args: args}
+	v := value{kind: valueTuple}
+	for _, f := range fields {
+		v.tuple = append(v.tuple, &Call{Of: of, Field: f})
+	}
+	return v
+}
+
+func compileMACD(c *compiler, x *callExpr, args []argValue) (value, error) {
+	src, err := args[0].series()
+	if err != nil {
+		return value{}, err
+	}
+	lengths := []Arg{src}
+	for _, a := range args[1:] {
+		l, err := a.length()
+		if err != nil {
+			return value{}, err
+		}
+		lengths = append(lengths, l)
+	}
+	return tuple("NewMACD", lengths, "MACD", "Signal", "Histogram"), nil
+}
+
+// compileBands compiles ta.bb and ta.kc returning middle, upper and lower
+func compileBands(fn string) func(c *compiler, x *callExpr, args []argValue) (value, error) {
+	return func(c *compiler, x *callExpr, args []argValue) (value, error) {
+		src, err := args[0].series()
+		if err != nil {
+			return value{}, err
+		}
+		length, err := args[1].length()
+		if err != nil {
+			return value{}, err
+		}
+		if err := args[2].required(); err != nil {
+			return value{}, err
+		}
+		mult, err := args[2].float(0)
+		if err != nil {
+			return value{}, err
+		}
+		fnArgs := []Arg{src, length, mult}
+		if fn == "NewKeltnerChannels" {
+			useTR, err := args[3].bool(true)
+			if err != nil {
+				return value{}, err
+			}
+			fnArgs = append(fnArgs, useTR)
+		}
+		return tuple(fn, fnArgs, "Basis", "Upper", "Lower"), nil
+	}
+}
+
+func compileMinMax(typ string, fold func(a, b float64) float64) func(c *compiler, x *callExpr, args []argValue) (value, error) {
+	return func(c *compiler, x *callExpr, args []argValue) (value, error) {
+		for _, a := range args {
+			if _, err := a.series(); err != nil {
+				return value{}, err
+			}
+		}
+		a, b := args[0], args[1]
+		if a.kind == valueNumber && b.kind == valueNumber {
+			return value{kind: valueNumber, num: fold(a.num, b.num), isInt: a.isInt && b.isInt}, nil
+		}
+		return arithmetic(typ, a.value, b.value), nil
+	}
+}
+
+func compileAbs(c *compiler, x *callExpr, args []argValue) (value, error) {
+	a := args[0]
+	if _, err := a.series(); err != nil {
+		return value{}, err
+	}
+	if a.kind == valueNumber {
+		if a.num < 0 {
+			a.num = -a.num
+		}
+		return value{kind: valueNumber, num: a.num, isInt: a.isInt}, nil
+	}
+	return arithmetic("ArithmeticAbsDiff", a.value, value{kind: valueNumber, isInt: true}), nil
+}
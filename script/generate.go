@@ -0,0 +1,280 @@
+package script
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	gotoken "go/token"
+	"strconv"
+	"strings"
+)
+
+// GenerateOpts defines the Go source generated of a script
+type GenerateOpts struct {
+	// Package is the package of the generated file
+	Package string
+	// Func is the name of the generated function adding the indicators of the
+	// script to a series
+	Func string
+	// Source is the name of the script file mentioned in the header
+	Source string
+}
+
+// Generate returns formatted Go source of a function adding the series
+// variables of s to a series like Register, written with the pine API the
+// way indicators are set up by hand
+func (s *Script) Generate(opts GenerateOpts) ([]byte, error) {
+	if !gotoken.IsIdentifier(opts.Package) {
+		return nil, fmt.Errorf("invalid package name %q", opts.Package)
+	}
+	if !gotoken.IsIdentifier(opts.Func) {
+		return nil, fmt.Errorf("invalid function name %q", opts.Func)
+	}
+	g := newGenerator(s)
+	var b bytes.Buffer
+	if opts.Source != "" {
+		fmt.Fprintf(&b, "// Code generated by pine2go from %s. DO NOT EDIT.\n\n", opts.Source)
+	} else {
+		b.WriteString("// Code generated by pine2go. DO NOT EDIT.\n\n")
+	}
+	fmt.Fprintf(&b, "package %s\n\n", opts.Package)
+	b.WriteString("import \"github.com/xpt-nl/pine\"\n\n")
+	title := s.Title
+	if title == "" {
+		title = "the script"
+	}
+	fmt.Fprintf(&b, "// %s adds the indicators of %s to s\n", opts.Func, title)
+	fmt.Fprintf(&b, "func %s(s pine.Series) error {\n", opts.Func)
+	for _, line := range g.body() {
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	b.WriteString("}\n")
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("error formatting generated source: %w", err)
+	}
+	return src, nil
+}
+
+// generator writes declarations of calls in dependency order. Calls read more
+// than once are declared as variables, others are written inline
+type generator struct {
+	script *Script
+	// names are the Go variables of declared constants and calls
+	names map[string]string
+	calls map[*Call]string
+	taken map[string]bool
+	// reserved are the script variables so generated names avoid them
+	reserved map[string]bool
+	refs     map[*Call]int
+	consts   map[string]Arg
+	lines    []string
+}
+
+func newGenerator(s *Script) *generator {
+	g := &generator{
+		script: s,
+		names:  make(map[string]string),
+		calls:  make(map[*Call]string),
+		// identifiers of the generated function
+		taken:    map[string]bool{"pine": true, "s": true, "err": true},
+		reserved: make(map[string]bool),
+		refs:     make(map[*Call]int),
+		consts:   make(map[string]Arg),
+	}
+	for _, c := range s.Consts {
+		g.consts[c.Name] = c.Value
+		g.reserved[c.Name] = true
+	}
+	for _, v := range s.Vars {
+		g.reserved[v.Name] = true
+		g.count(v.Call)
+	}
+	return g
+}
+
+// count counts reads of c and its arguments the first time c is read
+func (g *generator) count(c *Call) {
+	g.refs[c]++
+	if g.refs[c] > 1 {
+		return
+	}
+	if c.Of != nil {
+		g.count(c.Of)
+	}
+	for _, a := range c.Args {
+		if a.Kind == ArgIndicator {
+			g.count(a.Call)
+		}
+	}
+}
+
+func (g *generator) body() []string {
+	// constants read by indicators are declared first like parameters
+	used := make(map[string]bool)
+	seen := make(map[*Call]bool)
+	for _, v := range g.script.Vars {
+		g.usedConsts(v.Call, used, seen)
+	}
+	for _, c := range g.script.Consts {
+		if used[c.Name] {
+			g.constRef(Arg{Kind: c.Value.Kind, Ref: c.Name})
+		}
+	}
+	if len(g.lines) > 0 {
+		g.lines = append(g.lines, "")
+	}
+	for _, v := range g.script.Vars {
+		name := g.declare(v.Name, false)
+		if prev, ok := g.calls[v.Call]; ok {
+			g.lines = append(g.lines, fmt.Sprintf("%s := %s", name, prev))
+		} else {
+			g.lines = append(g.lines, fmt.Sprintf("%s := %s", name, g.expr(v.Call)))
+			g.calls[v.Call] = name
+		}
+		g.names[v.Name] = name
+	}
+	g.lines = append(g.lines, "")
+	for _, v := range g.script.Vars {
+		g.lines = append(g.lines,
+			fmt.Sprintf("if err := s.AddIndicator(%s, %s); err != nil {", strconv.Quote(v.Name), g.names[v.Name]),
+			"return err",
+			"}",
+		)
+	}
+	g.lines = append(g.lines, "return nil")
+	return g.lines
+}
+
+// usedConsts adds the constants read by c to used
+func (g *generator) usedConsts(c *Call, used map[string]bool, seen map[*Call]bool) {
+	if seen[c] {
+		return
+	}
+	seen[c] = true
+	if c.Of != nil {
+		g.usedConsts(c.Of, used, seen)
+	}
+	for _, a := range c.Args {
+		if a.Kind == ArgIndicator {
+			g.usedConsts(a.Call, used, seen)
+		}
+		for ref := a.Ref; ref != "" && !used[ref]; ref = g.consts[ref].Ref {
+			used[ref] = true
+		}
+	}
+}
+
+// declare returns a Go identifier for name not taken yet which also avoids
+// script variables if name is generated
+func (g *generator) declare(name string, generated bool) string {
+	id := name
+	if gotoken.IsKeyword(id) {
+		id += "_"
+	}
+	base := id
+	for j := 2; g.taken[id] || (generated && g.reserved[id]); j++ {
+		id = fmt.Sprintf("%s%d", base, j)
+	}
+	g.taken[id] = true
+	return id
+}
+
+// ref returns the expression of c declaring it first if read more than once
+func (g *generator) ref(c *Call) string {
+	if name, ok := g.calls[c]; ok {
+		return name
+	}
+	if g.refs[c] < 2 {
+		return g.expr(c)
+	}
+	expr := g.expr(c)
+	name := g.declare(g.callName(c), true)
+	g.lines = append(g.lines, fmt.Sprintf("%s := %s", name, expr))
+	g.calls[c] = name
+	return name
+}
+
+// callName returns a variable name for c like close for NewOHLCProp of close
+// or macd for NewMACD
+func (g *generator) callName(c *Call) string {
+	if c.Of != nil {
+		return lowerFirst(c.Field)
+	}
+	if c.Func == "NewOHLCProp" && len(c.Args) == 1 {
+		return strings.ToLower(strings.TrimPrefix(c.Args[0].Const, "OHLCProp"))
+	}
+	return lowerFirst(strings.TrimPrefix(c.Func, "New"))
+}
+
+func lowerFirst(s string) string {
+	// initialisms like MACD are lowered as a whole
+	if strings.ToUpper(s) == s {
+		return strings.ToLower(s)
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+func (g *generator) expr(c *Call) string {
+	if c.Of != nil {
+		return fmt.Sprintf("%s.%s", g.ref(c.Of), c.Field)
+	}
+	args := make([]string, len(c.Args))
+	for j, a := range c.Args {
+		args[j] = g.arg(a)
+	}
+	return fmt.Sprintf("pine.%s(%s)", c.Func, strings.Join(args, ", "))
+}
+
+func (g *generator) arg(a Arg) string {
+	switch a.Kind {
+	case ArgIndicator:
+		return g.ref(a.Call)
+	case ArgInt, ArgFloat, ArgBool:
+		if a.Ref != "" {
+			return g.constRef(a)
+		}
+		return literal(a)
+	}
+	if a.Const == "nil" {
+		return "nil"
+	}
+	return "pine." + a.Const
+}
+
+// constRef returns the variable of the constant a is read from declaring it
+// first, converted to float64 if it is an int read as float
+func (g *generator) constRef(a Arg) string {
+	def := g.consts[a.Ref]
+	name, ok := g.names[a.Ref]
+	if !ok {
+		value := literal(def)
+		if def.Ref != "" {
+			value = g.constRef(def)
+		}
+		name = g.declare(a.Ref, false)
+		g.lines = append(g.lines, fmt.Sprintf("%s := %s", name, value))
+		g.names[a.Ref] = name
+	}
+	if a.Kind == ArgFloat && def.Kind == ArgInt {
+		return fmt.Sprintf("float64(%s)", name)
+	}
+	return name
+}
+
+// literal returns a Go literal of a where floats are written with a decimal
+// point so variables declared with them are float64
+func literal(a Arg) string {
+	switch a.Kind {
+	case ArgInt:
+		return strconv.FormatInt(int64(a.Num), 10)
+	case ArgBool:
+		return strconv.FormatBool(a.Bool)
+	}
+	s := strconv.FormatFloat(a.Num, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
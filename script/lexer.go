@@ -0,0 +1,222 @@
+package script
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	tokenNewline
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOp
+)
+
+type token struct {
+	typ  tokenType
+	text string
+	pos  Pos
+}
+
+func (t token) String() string {
+	switch t.typ {
+	case tokenEOF:
+		return "end of script"
+	case tokenNewline:
+		return "end of line"
+	case tokenString:
+		return strconv.Quote(t.text)
+	}
+	return t.text
+}
+
+// operators are sorted so longer operators are matched first
+var operators = []string{":=", "==", "!=", "<=", ">=", "=>", "+", "-", "*", "/", "%", "=", "<", ">", "?", ":", "(", ")", "[", "]", ","}
+
+// lexer splits a script into tokens. Newlines end statements unless they are
+// inside brackets or the next line is indented which continues the statement
+type lexer struct {
+	src   string
+	off   int
+	line  int
+	col   int
+	depth int
+}
+
+func lex(src string) ([]token, error) {
+	l := &lexer{
+		src:  src,
+		line: 1,
+		col:  1,
+	}
+	var toks []token
+	for {
+		t, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		if t.typ == tokenNewline && (len(toks) == 0 || toks[len(toks)-1].typ == tokenNewline) {
+			// blank lines
+			continue
+		}
+		toks = append(toks, t)
+		if t.typ == tokenEOF {
+			return toks, nil
+		}
+	}
+}
+
+func (l *lexer) pos() Pos {
+	return Pos{Line: l.line, Col: l.col}
+}
+
+func (l *lexer) peek(n int) byte {
+	if l.off+n >= len(l.src) {
+		return 0
+	}
+	return l.src[l.off+n]
+}
+
+func (l *lexer) advance(n int) {
+	for j := 0; j < n; j++ {
+		if l.src[l.off] == '\n' {
+			l.line++
+			l.col = 1
+		} else {
+			l.col++
+		}
+		l.off++
+	}
+}
+
+func (l *lexer) next() (token, error) {
+	for {
+		c := l.peek(0)
+		switch {
+		case c == ' ' || c == '\t' || c == '\r':
+			l.advance(1)
+		case c == '/' && l.peek(1) == '/':
+			for l.off < len(l.src) && l.src[l.off] != '\n' {
+				l.advance(1)
+			}
+		case c == '\n':
+			pos := l.pos()
+			l.advance(1)
+			if l.depth == 0 && !l.continued() {
+				return token{typ: tokenNewline, pos: pos}, nil
+			}
+		default:
+			return l.token()
+		}
+	}
+}
+
+// continued reports whether the next line with code is indented
+func (l *lexer) continued() bool {
+	off := l.off
+	for off < len(l.src) {
+		end := strings.IndexByte(l.src[off:], '\n')
+		if end < 0 {
+			end = len(l.src) - off
+		}
+		line := l.src[off : off+end]
+		code := strings.TrimLeft(line, " \t\r")
+		if code != "" && !strings.HasPrefix(code, "//") {
+			return len(code) < len(line)
+		}
+		off += end + 1
+	}
+	return false
+}
+
+func (l *lexer) token() (token, error) {
+	pos := l.pos()
+	c := l.peek(0)
+	switch {
+	case c == 0:
+		return token{typ: tokenEOF, pos: pos}, nil
+	case isLetter(c):
+		start := l.off
+		for isLetter(l.peek(0)) || isDigit(l.peek(0)) || (l.peek(0) == '.' && isLetter(l.peek(1))) {
+			l.advance(1)
+		}
+		return token{typ: tokenIdent, text: l.src[start:l.off], pos: pos}, nil
+	case isDigit(c) || (c == '.' && isDigit(l.peek(1))):
+		start := l.off
+		for isDigit(l.peek(0)) || l.peek(0) == '.' {
+			l.advance(1)
+		}
+		if e := l.peek(0); e == 'e' || e == 'E' {
+			l.advance(1)
+			if s := l.peek(0); s == '+' || s == '-' {
+				l.advance(1)
+			}
+			for isDigit(l.peek(0)) {
+				l.advance(1)
+			}
+		}
+		return token{typ: tokenNumber, text: l.src[start:l.off], pos: pos}, nil
+	case c == '"' || c == '\'':
+		return l.string(c)
+	}
+	for _, op := range operators {
+		if strings.HasPrefix(l.src[l.off:], op) {
+			switch op {
+			case "(", "[":
+				l.depth++
+			case ")", "]":
+				if l.depth > 0 {
+					l.depth--
+				}
+			}
+			l.advance(len(op))
+			return token{typ: tokenOp, text: op, pos: pos}, nil
+		}
+	}
+	return token{}, &Error{Pos: pos, Msg: fmt.Sprintf("unexpected character %q", c)}
+}
+
+func (l *lexer) string(quote byte) (token, error) {
+	pos := l.pos()
+	l.advance(1)
+	var b strings.Builder
+	for {
+		c := l.peek(0)
+		switch c {
+		case 0, '\n':
+			return token{}, &Error{Pos: pos, Msg: "string is not terminated"}
+		case quote:
+			l.advance(1)
+			return token{typ: tokenString, text: b.String(), pos: pos}, nil
+		case '\\':
+			l.advance(1)
+			switch e := l.peek(0); e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 0, '\n':
+				return token{}, &Error{Pos: pos, Msg: "string is not terminated"}
+			default:
+				b.WriteByte(e)
+			}
+			l.advance(1)
+		default:
+			b.WriteByte(c)
+			l.advance(1)
+		}
+	}
+}
+
+func isLetter(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
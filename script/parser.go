@@ -0,0 +1,426 @@
+package script
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// stmt is a statement of a script
+type stmt interface {
+	position() Pos
+}
+
+// assignStmt declares one variable or a tuple of variables
+type assignStmt struct {
+	pos   Pos
+	names []string
+	tuple bool
+	x     expr
+}
+
+// exprStmt is an expression evaluated for its side effects like plot
+type exprStmt struct {
+	x expr
+}
+
+func (s *assignStmt) position() Pos { return s.pos }
+func (s *exprStmt) position() Pos   { return s.x.position() }
+
+// expr is an expression of a script
+type expr interface {
+	position() Pos
+}
+
+type numberLit struct {
+	pos   Pos
+	value float64
+	isInt bool
+}
+
+type stringLit struct {
+	pos   Pos
+	value string
+}
+
+type boolLit struct {
+	pos   Pos
+	value bool
+}
+
+type identExpr struct {
+	pos  Pos
+	name string
+}
+
+type unaryExpr struct {
+	pos Pos
+	op  string
+	x   expr
+}
+
+type binaryExpr struct {
+	pos Pos
+	op  string
+	x   expr
+	y   expr
+}
+
+type ternaryExpr struct {
+	pos  Pos
+	cond expr
+	x    expr
+	y    expr
+}
+
+type callExpr struct {
+	pos  Pos
+	fn   string
+	args []callArg
+}
+
+// callArg is a positional argument or a named one if name is set
+type callArg struct {
+	pos  Pos
+	name string
+	x    expr
+}
+
+// indexExpr reads x n intervals back
+type indexExpr struct {
+	pos Pos
+	x   expr
+	n   expr
+}
+
+func (e *numberLit) position() Pos   { return e.pos }
+func (e *stringLit) position() Pos   { return e.pos }
+func (e *boolLit) position() Pos     { return e.pos }
+func (e *identExpr) position() Pos   { return e.pos }
+func (e *unaryExpr) position() Pos   { return e.pos }
+func (e *binaryExpr) position() Pos  { return e.pos }
+func (e *ternaryExpr) position() Pos { return e.pos }
+func (e *callExpr) position() Pos    { return e.pos }
+func (e *indexExpr) position() Pos   { return e.pos }
+
+// binaryPrecedence of Pine operators from lowest to highest
+var binaryPrecedence = [][]string{
+	{"or"},
+	{"and"},
+	{"==", "!="},
+	{"<", ">", "<=", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+// keywords starting statements the subset doesn't support
+var unsupportedKeywords = map[string]string{
+	"if":     "if statements",
+	"for":    "for loops",
+	"while":  "while loops",
+	"switch": "switch statements",
+	"import": "imports",
+	"export": "exports",
+	"type":   "user-defined types",
+	"method": "methods",
+	"var":    "var declarations",
+	"varip":  "varip declarations",
+}
+
+// typeKeywords may precede variable declarations and are ignored
+var typeKeywords = map[string]bool{
+	"int":    true,
+	"float":  true,
+	"bool":   true,
+	"string": true,
+	"series": true,
+	"simple": true,
+	"const":  true,
+}
+
+type parser struct {
+	toks []token
+	off  int
+}
+
+func parse(src string) ([]stmt, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	var stmts []stmt
+	for p.peek().typ != tokenEOF {
+		s, err := p.stmt()
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, s)
+		if t := p.next(); t.typ != tokenNewline && t.typ != tokenEOF {
+			return nil, errorf(t.pos, "unexpected %s after statement", t)
+		}
+	}
+	return stmts, nil
+}
+
+func (p *parser) peek() token {
+	return p.peekAt(0)
+}
+
+func (p *parser) peekAt(n int) token {
+	if p.off+n >= len(p.toks) {
+		return p.toks[len(p.toks)-1]
+	}
+	return p.toks[p.off+n]
+}
+
+func (p *parser) next() token {
+	t := p.peek()
+	if p.off < len(p.toks) {
+		p.off++
+	}
+	return t
+}
+
+func (p *parser) isOp(op string) bool {
+	t := p.peek()
+	return t.typ == tokenOp && t.text == op
+}
+
+func (p *parser) expect(op string) (token, error) {
+	t := p.next()
+	if t.typ != tokenOp || t.text != op {
+		return t, errorf(t.pos, "expected %s but got %s", op, t)
+	}
+	return t, nil
+}
+
+func (p *parser) stmt() (stmt, error) {
+	t := p.peek()
+	if t.typ == tokenIdent {
+		if what, ok := unsupportedKeywords[t.text]; ok {
+			return nil, errorf(t.pos, "%s are not supported", what)
+		}
+		if typeKeywords[t.text] && p.peekAt(1).typ == tokenIdent {
+			// declared type is inferred from the value instead
+			p.next()
+			return p.stmt()
+		}
+		if p.peekAt(1).typ == tokenOp {
+			switch p.peekAt(1).text {
+			case "=":
+				p.off += 2
+				x, err := p.expr()
+				if err != nil {
+					return nil, err
+				}
+				return &assignStmt{pos: t.pos, names: []string{t.text}, x: x}, nil
+			case ":=":
+				return nil, errorf(p.peekAt(1).pos, "reassignment with := is not supported")
+			}
+		}
+	}
+	if p.isOp("[") && p.tupleAhead() {
+		return p.tuple()
+	}
+	x, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if p.isOp("=>") {
+		return nil, errorf(p.peek().pos, "function definitions are not supported")
+	}
+	return &exprStmt{x: x}, nil
+}
+
+// tupleAhead reports whether the statement is a tuple declaration
+// like [a, b] = f()
+func (p *parser) tupleAhead() bool {
+	for j := 1; ; j++ {
+		t := p.peekAt(j)
+		if t.typ == tokenOp && t.text == "]" {
+			next := p.peekAt(j + 1)
+			return next.typ == tokenOp && (next.text == "=" || next.text == ":=")
+		}
+		if t.typ != tokenIdent && !(t.typ == tokenOp && t.text == ",") {
+			return false
+		}
+	}
+}
+
+func (p *parser) tuple() (stmt, error) {
+	start := p.next()
+	s := &assignStmt{pos: start.pos, tuple: true}
+	for !p.isOp("]") {
+		if len(s.names) > 0 {
+			if _, err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		s.names = append(s.names, p.next().text)
+	}
+	p.next()
+	if p.isOp(":=") {
+		return nil, errorf(p.peek().pos, "reassignment with := is not supported")
+	}
+	p.next()
+	x, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	s.x = x
+	return s, nil
+}
+
+func (p *parser) expr() (expr, error) {
+	cond, err := p.binary(0)
+	if err != nil {
+		return nil, err
+	}
+	if !p.isOp("?") {
+		return cond, nil
+	}
+	q := p.next()
+	x, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	y, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	return &ternaryExpr{pos: q.pos, cond: cond, x: x, y: y}, nil
+}
+
+func (p *parser) binary(level int) (expr, error) {
+	if level == len(binaryPrecedence) {
+		return p.unary()
+	}
+	x, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if !p.isBinaryOp(t, level) {
+			return x, nil
+		}
+		p.next()
+		y, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		x = &binaryExpr{pos: t.pos, op: t.text, x: x, y: y}
+	}
+}
+
+func (p *parser) isBinaryOp(t token, level int) bool {
+	if t.typ != tokenOp && t.typ != tokenIdent {
+		return false
+	}
+	for _, op := range binaryPrecedence[level] {
+		if t.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) unary() (expr, error) {
+	t := p.peek()
+	if (t.typ == tokenOp && (t.text == "-" || t.text == "+")) || (t.typ == tokenIdent && t.text == "not") {
+		p.next()
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryExpr{pos: t.pos, op: t.text, x: x}, nil
+	}
+	return p.postfix()
+}
+
+func (p *parser) postfix() (expr, error) {
+	x, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("[") {
+		t := p.next()
+		n, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		x = &indexExpr{pos: t.pos, x: x, n: n}
+	}
+	return x, nil
+}
+
+func (p *parser) primary() (expr, error) {
+	t := p.next()
+	switch t.typ {
+	case tokenNumber:
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, errorf(t.pos, "invalid number %s", t.text)
+		}
+		return &numberLit{pos: t.pos, value: v, isInt: !strings.ContainsAny(t.text, ".eE")}, nil
+	case tokenString:
+		return &stringLit{pos: t.pos, value: t.text}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true", "false":
+			return &boolLit{pos: t.pos, value: t.text == "true"}, nil
+		}
+		if p.isOp("(") {
+			return p.call(t)
+		}
+		return &identExpr{pos: t.pos, name: t.text}, nil
+	case tokenOp:
+		if t.text == "(" {
+			x, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return x, nil
+		}
+	}
+	return nil, errorf(t.pos, "unexpected %s", t)
+}
+
+func (p *parser) call(fn token) (expr, error) {
+	p.next()
+	c := &callExpr{pos: fn.pos, fn: fn.text}
+	for !p.isOp(")") {
+		if len(c.args) > 0 {
+			if _, err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		a := callArg{pos: p.peek().pos}
+		if p.peek().typ == tokenIdent && p.peekAt(1).typ == tokenOp && p.peekAt(1).text == "=" {
+			a.name = p.next().text
+			p.next()
+		}
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		a.x = x
+		c.args = append(c.args, a)
+	}
+	p.next()
+	return c, nil
+}
+
+func errorf(pos Pos, format string, args ...any) *Error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,239 @@
+// Package script compiles a subset of Pine Script v5 into pine indicators.
+//
+// Scripts may declare variables with arithmetic on numbers and series,
+// history references like close[1], input.* calls which are replaced by their
+// default values and ta.* and math.* calls mapped to pine indicators. Each
+// series variable is registered on a Series by its name. Other constructs
+// like if statements, loops and function definitions are reported as errors
+// at their line and column
+package script
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/xpt-nl/pine"
+)
+
+// Pos is a line and column of a script starting at 1
+type Pos struct {
+	Line int
+	Col  int
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Col)
+}
+
+// Error is a syntax error or an unsupported construct at Pos of a script
+type Error struct {
+	Pos Pos
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// Script is a compiled script
+type Script struct {
+	// Title is the title of the indicator declaration
+	Title string
+	// Consts are the numeric and bool variables like inputs in order of
+	// declaration
+	Consts []Const
+	// Vars are the series variables in order of declaration
+	Vars []Var
+}
+
+// Const is a numeric or bool variable of a script
+type Const struct {
+	Name  string
+	Value Arg
+}
+
+// Var is a series variable of a script
+type Var struct {
+	Name string
+	Call *Call
+}
+
+// Call is a constructor of package pine a series compiles to. Series read by
+// several expressions share their Call so they are built once
+type Call struct {
+	// Func is the name of the constructor like NewSMA
+	Func string
+	Args []Arg
+	// Of is the call returning several indicators Field is read of, like the
+	// MACD of NewMACD, in which case Func is empty
+	Of    *Call
+	Field string
+}
+
+// ArgKind is the type of an argument of a Call
+type ArgKind int
+
+const (
+	// ArgIndicator is an indicator built of Call
+	ArgIndicator ArgKind = iota
+	// ArgInt is an int in Num
+	ArgInt
+	// ArgFloat is a float64 in Num
+	ArgFloat
+	// ArgBool is a bool in Bool
+	ArgBool
+	// ArgConst is an exported constant or value of package pine in Const,
+	// like OHLCPropClose or ArithmeticOpts{}, or nil
+	ArgConst
+)
+
+// Arg is an argument of a Call
+type Arg struct {
+	Kind  ArgKind
+	Call  *Call
+	Num   float64
+	Bool  bool
+	Const string
+	// Ref is the name of the script constant the value was read from if any
+	Ref string
+}
+
+// Compile parses and compiles src
+func Compile(src string) (*Script, error) {
+	stmts, err := parse(src)
+	if err != nil {
+		return nil, err
+	}
+	c := newCompiler()
+	for _, s := range stmts {
+		if err := c.stmt(s); err != nil {
+			return nil, err
+		}
+	}
+	return c.script, nil
+}
+
+// Indicators builds new indicators of the series variables by name. Every
+// call returns indicators not shared with earlier calls
+func (s *Script) Indicators() map[string]pine.Indicator {
+	b := &builder{
+		built: make(map[*Call]reflect.Value),
+	}
+	inds := make(map[string]pine.Indicator, len(s.Vars))
+	for _, v := range s.Vars {
+		inds[v.Name] = b.build(v.Call).Interface().(pine.Indicator)
+	}
+	return inds
+}
+
+// Register builds new indicators of the series variables and adds them to
+// series by name in order of declaration
+func (s *Script) Register(series pine.Series) error {
+	inds := s.Indicators()
+	for _, v := range s.Vars {
+		if err := series.AddIndicator(v.Name, inds[v.Name]); err != nil {
+			return fmt.Errorf("error adding %s: %w", v.Name, err)
+		}
+	}
+	return nil
+}
+
+// constructors are the functions of package pine calls compile to
+var constructors = map[string]any{
+	"NewALMA":                  pine.NewALMA,
+	"NewATR":                   pine.NewATR,
+	"NewArithmetic":            pine.NewArithmetic,
+	"NewBollingerBands":        pine.NewBollingerBands,
+	"NewChange":                pine.NewChange,
+	"NewConstant":              pine.NewConstant,
+	"NewCross":                 pine.NewCross,
+	"NewCrossover":             pine.NewCrossover,
+	"NewCrossunder":            pine.NewCrossunder,
+	"NewEMA":                   pine.NewEMA,
+	"NewHMA":                   pine.NewHMA,
+	"NewHighest":               pine.NewHighest,
+	"NewHighestBars":           pine.NewHighestBars,
+	"NewKeltnerChannels":       pine.NewKeltnerChannels,
+	"NewLinReg":                pine.NewLinReg,
+	"NewLowest":                pine.NewLowest,
+	"NewLowestBars":            pine.NewLowestBars,
+	"NewMACD":                  pine.NewMACD,
+	"NewMedian":                pine.NewMedian,
+	"NewOHLCProp":              pine.NewOHLCProp,
+	"NewPercentRank":           pine.NewPercentRank,
+	"NewPercentile":            pine.NewPercentile,
+	"NewPercentileNearestRank": pine.NewPercentileNearestRank,
+	"NewPrevious":              pine.NewPrevious,
+	"NewRMA":                   pine.NewRMA,
+	"NewRSI":                   pine.NewRSI,
+	"NewSMA":                   pine.NewSMA,
+	"NewSWMA":                  pine.NewSWMA,
+	"NewStdDev":                pine.NewStdDev,
+	"NewStoch":                 pine.NewStoch,
+	"NewTrueRange":             pine.NewTrueRange,
+	"NewVWAP":                  pine.NewVWAP,
+	"NewVWMA":                  pine.NewVWMA,
+	"NewWMA":                   pine.NewWMA,
+	"NewWilliamsR":             pine.NewWilliamsR,
+}
+
+// constants are the values of package pine ArgConst names
+var constants = map[string]any{
+	"ArithmeticAddition":       pine.ArithmeticAddition,
+	"ArithmeticSubtraction":    pine.ArithmeticSubtraction,
+	"ArithmeticMultiplication": pine.ArithmeticMultiplication,
+	"ArithmeticDivision":       pine.ArithmeticDivision,
+	"ArithmeticAbsDiff":        pine.ArithmeticAbsDiff,
+	"ArithmeticMax":            pine.ArithmeticMax,
+	"ArithmeticMin":            pine.ArithmeticMin,
+	"ArithmeticOpts{}":         pine.ArithmeticOpts{},
+	"OHLCPropClose":            pine.OHLCPropClose,
+	"OHLCPropOpen":             pine.OHLCPropOpen,
+	"OHLCPropHigh":             pine.OHLCPropHigh,
+	"OHLCPropLow":              pine.OHLCPropLow,
+	"OHLCPropVolume":           pine.OHLCPropVolume,
+	"OHLCPropHL2":              pine.OHLCPropHL2,
+	"OHLCPropHLC3":             pine.OHLCPropHLC3,
+	"VWAPOpts{}":               pine.VWAPOpts{},
+}
+
+// builder builds indicators of calls once
+type builder struct {
+	built map[*Call]reflect.Value
+}
+
+func (b *builder) build(c *Call) reflect.Value {
+	if v, ok := b.built[c]; ok {
+		return v
+	}
+	var v reflect.Value
+	if c.Of != nil {
+		v = b.build(c.Of).FieldByName(c.Field)
+	} else {
+		fn := reflect.ValueOf(constructors[c.Func])
+		args := make([]reflect.Value, len(c.Args))
+		for j, a := range c.Args {
+			args[j] = b.arg(a, fn.Type().In(j))
+		}
+		v = fn.Call(args)[0]
+	}
+	b.built[c] = v
+	return v
+}
+
+func (b *builder) arg(a Arg, t reflect.Type) reflect.Value {
+	switch a.Kind {
+	case ArgIndicator:
+		return b.build(a.Call)
+	case ArgInt:
+		return reflect.ValueOf(int(a.Num)).Convert(t)
+	case ArgFloat:
+		return reflect.ValueOf(a.Num).Convert(t)
+	case ArgBool:
+		return reflect.ValueOf(a.Bool)
+	}
+	if a.Const == "nil" {
+		return reflect.Zero(t)
+	}
+	return reflect.ValueOf(constants[a.Const])
+}
@@ -0,0 +1,280 @@
+package pine
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Lookahead defines which higher timeframe interval a lower timeframe
+// interval reads like Pine's barmerge.lookahead_on and barmerge.lookahead_off
+type Lookahead int
+
+const (
+	// LookaheadOff reads the most recent higher timeframe interval closed by
+	// the end of the lower timeframe interval so backtests don't see values
+	// of unclosed intervals
+	LookaheadOff Lookahead = iota
+	// LookaheadOn reads the higher timeframe interval containing the lower
+	// timeframe interval. Historical values include data from later lower
+	// timeframe intervals
+	LookaheadOn
+)
+
+// Timeframe aggregates intervals of the series its securities are added to
+// into intervals of a higher timeframe
+type Timeframe struct {
+	series     *series
+	base       *SeriesOpts
+	indicators int
+	// states are the aggregates after every lower timeframe interval
+	states *ring[tfState]
+}
+
+// tfState is the aggregate of the lower timeframe intervals of a higher
+// timeframe interval before Cur and Cur, the last lower timeframe interval
+type tfState struct {
+	HasAgg bool
+	Agg    OHLCV
+	Cur    OHLCV
+}
+
+// NewTimeframe creates a higher timeframe with opts. Interval must be a
+// multiple of the interval of the series its securities are added to. Day,
+// week and month intervals require series intervals that don't span days
+func NewTimeframe(opts SeriesOpts) (*Timeframe, error) {
+	s, err := NewSeries(nil, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error creating timeframe series: %w", err)
+	}
+	return &Timeframe{
+		series: s.(*series),
+		states: newRing[tfState](defaultMax, 0),
+	}, nil
+}
+
+// Series returns the aggregated higher timeframe series
+func (tf *Timeframe) Series() Series {
+	return tf.series
+}
+
+func (tf *Timeframe) applyOpts(opts SeriesOpts) error {
+	htf := tf.series.opts
+	if htf.IntervalUnit == IntervalUnitSecond && opts.IntervalUnit == IntervalUnitSecond {
+		if htf.Interval < opts.Interval || htf.Interval%opts.Interval != 0 {
+			return errors.New("Timeframe interval must be a multiple of series interval")
+		}
+	} else if !opts.dayAligned() || htf.IntervalUnit == IntervalUnitSecond {
+		return errors.New("Timeframe interval must be a multiple of series interval")
+	}
+	if tf.base != nil && (tf.base.Interval != opts.Interval || tf.base.IntervalUnit != opts.IntervalUnit) {
+		return errors.New("Timeframe cannot be shared by series of different intervals")
+	}
+	tf.base = &opts
+	if n := opts.Max + 1; tf.states.len() == 0 && tf.states.capacity() != n || tf.states.capacity() < n {
+		// series sharing tf keep states for the largest Max
+		states := historyRing[tfState](nil, opts)
+		if st, ok := tf.states.at(0); ok {
+			states.set(st.Cur.S, st)
+		}
+		tf.states = states
+	}
+	return nil
+}
+
+// bucket returns start of the higher timeframe interval t belongs to
+func (tf *Timeframe) bucket(t time.Time) time.Time {
+	return tf.series.getLastIntervalFromTime(t)
+}
+
+// update merges lower timeframe interval v into its higher timeframe interval.
+// Updating the same interval again replaces its contribution
+func (tf *Timeframe) update(v OHLCV) error {
+	if last, ok := tf.states.at(0); ok {
+		if v.S.Before(last.Cur.S) {
+			// already aggregated
+			return nil
+		}
+		if last.Cur == v {
+			// shared by several securities
+			return nil
+		}
+	}
+	st, _ := tf.states.prior(v.S)
+	start := tf.bucket(v.S)
+	if !st.Cur.S.IsZero() {
+		// previous interval is final so it joins the aggregate if in the same bucket
+		if tf.bucket(st.Cur.S).Equal(start) {
+			st.Agg = st.merge(st.Cur)
+			st.HasAgg = true
+		} else {
+			st.HasAgg = false
+		}
+	}
+	st.Cur = v
+	tf.states.set(v.S, st)
+	htf := st.merge(v)
+	htf.S = start
+	if err := tf.series.AddOHLCV(htf); err != nil {
+		return fmt.Errorf("error updating timeframe series: %w", err)
+	}
+	return nil
+}
+
+// merge returns the aggregate of the current bucket with v
+func (st tfState) merge(v OHLCV) OHLCV {
+	if !st.HasAgg {
+		return v
+	}
+	m := st.Agg
+	if v.H > m.H {
+		m.H = v.H
+	}
+	if v.L < m.L {
+		m.L = v.L
+	}
+	m.C = v.C
+	m.V += v.V
+	return m
+}
+
+// reset removes aggregated intervals so they can be aggregated again
+func (tf *Timeframe) reset() {
+	tf.series.reset()
+	tf.states.clear()
+}
+
+// rewind removes aggregates of lower timeframe intervals from t onwards and
+// the higher timeframe intervals they are part of. Securities sharing tf
+// rewind it once
+func (tf *Timeframe) rewind(t time.Time) error {
+	if last, ok := tf.states.at(0); !ok || last.Cur.S.Before(t) {
+		return nil
+	}
+	tf.states.rewind(t)
+	if err := tf.series.truncate(tf.bucket(t)); err != nil {
+		return fmt.Errorf("error rewinding timeframe series: %w", err)
+	}
+	return nil
+}
+
+// encode writes the aggregates and the higher timeframe series
+func (tf *Timeframe) encode(e *encoder) {
+	encodeRing(e, tf.states, putTFState)
+	tf.series.encodeSnapshot(e)
+}
+
+func (tf *Timeframe) decode(d *decoder) {
+	decodeRing(d, tf.states, getTFState)
+	// times of the higher timeframe series are in its location
+	loc := d.loc
+	d.loc = tf.series.opts.location()
+	tf.series.decodeSnapshot(d)
+	d.loc = loc
+}
+
+func putTFState(e *encoder, st tfState) {
+	e.bool(st.HasAgg)
+	e.ohlcv(st.Agg)
+	e.ohlcv(st.Cur)
+}
+
+func getTFState(d *decoder) tfState {
+	return tfState{
+		HasAgg: d.bool(),
+		Agg:    d.ohlcv(),
+		Cur:    d.ohlcv(),
+	}
+}
+
+type security struct {
+	tf        *Timeframe
+	name      string
+	lookahead Lookahead
+	src       Indicator
+}
+
+// NewSecurity creates an indicator reading src on higher timeframe tf like
+// Pine's request.security. src is updated with the aggregated intervals of tf
+func NewSecurity(tf *Timeframe, i Indicator, lookahead Lookahead) Indicator {
+	tf.indicators++
+	return &security{
+		tf:        tf,
+		name:      fmt.Sprintf("security%d", tf.indicators),
+		lookahead: lookahead,
+		src:       i,
+	}
+}
+
+func (i *security) GetValueForInterval(t time.Time) *Interval {
+	v, ok := i.value(t)
+	if !ok {
+		return nil
+	}
+	return &Interval{
+		StartTime: t,
+		Value:     v,
+	}
+}
+
+func (i *security) value(t time.Time) (float64, bool) {
+	start := i.tf.bucket(t)
+	if i.lookahead == LookaheadOn {
+		return valueOf(i.src, start)
+	}
+	if i.tf.base != nil && i.tf.bucket(i.tf.base.nextIntervalStart(t)).Equal(start) {
+		// t doesn't close its higher timeframe interval so read the previous one
+		idx, ok := i.tf.series.values.indexOf(start)
+		if !ok || !i.tf.series.values.has(idx-1) {
+			return 0, false
+		}
+		start = i.tf.series.values.timeAt(idx - 1)
+	}
+	return valueOf(i.src, start)
+}
+
+func (i *security) Update(v OHLCV) error {
+	if err := i.tf.update(v); err != nil {
+		return fmt.Errorf("error updating timeframe in security: %w", err)
+	}
+	return nil
+}
+
+func (i *security) inputs() []node {
+	return nil
+}
+
+func (i *security) updateNode(v OHLCV) error {
+	return i.Update(v)
+}
+
+func (i *security) ApplyOpts(opts SeriesOpts) error {
+	if err := i.tf.applyOpts(opts); err != nil {
+		return err
+	}
+	if _, ok := i.tf.series.items[i.name]; ok {
+		return nil
+	}
+	if err := i.tf.series.AddIndicator(i.name, i.src); err != nil {
+		return fmt.Errorf("error adding source to timeframe: %w", err)
+	}
+	return nil
+}
+
+func (i *security) reset() {
+	i.tf.reset()
+}
+
+func (i *security) rewind(t time.Time) error {
+	return i.tf.rewind(t)
+}
+
+func (i *security) encodeState(e *encoder) {
+	i.tf.encode(e)
+}
+
+// decodeState restores the timeframe again for every security sharing it
+// which leaves it in the same state
+func (i *security) decodeState(d *decoder) {
+	i.tf.decode(d)
+}
@@ -12,6 +12,16 @@ type Series interface {
 	AddExec(v TPQ) error
 	AddOHLCV(v OHLCV) error
 	GetValueForInterval(t time.Time) *Interval
+	// DroppedExecs returns the number of late execs dropped
+	DroppedExecs() int
+	// Subscribe calls fn for every event of the series until the returned
+	// function is called
+	Subscribe(fn func(e Event)) func()
+	// MarshalBinary returns a snapshot of intervals and indicator state
+	MarshalBinary() ([]byte, error)
+	// UnmarshalBinary restores a snapshot into a series with the same
+	// interval and indicators
+	UnmarshalBinary(data []byte) error
 }
 
 type Indicator interface {
@@ -25,6 +35,23 @@ type Interval struct {
 	OHLCV      *OHLCV
 	Value      float64
 	Indicators map[string]*float64
+	// Session is the trading session of the interval
+	Session SessionState
+	// BarState is the state of the interval like Pine's barstate
+	BarState BarState
+}
+
+// BarState is the state of an interval like Pine's barstate variables
+type BarState struct {
+	// IsConfirmed is set once a later interval is added so the interval is
+	// closed and its values are final like Pine's barstate.isconfirmed
+	IsConfirmed bool
+	// IsNew is set if the interval is the last one and has not been updated
+	// since it was created like Pine's barstate.isnew
+	IsNew bool
+	// IsRealtime is set if the interval was added after the series was
+	// created rather than passed to NewSeries like Pine's barstate.isrealtime
+	IsRealtime bool
 }
 
 type TPQ struct {
@@ -40,12 +67,32 @@ type OHLCV struct {
 
 // SeriesOpts is options required for creating Series
 type SeriesOpts struct {
-	// interval in seconds
+	// interval in IntervalUnit, seconds by default
 	Interval int
+	// unit of Interval
+	IntervalUnit IntervalUnit
+	// Location is the time zone intervals are anchored in. Defaults to UTC
+	Location *time.Location
+	// DayStart is the offset from midnight days start at, e.g. 17 hours for
+	// daily intervals closing at 17:00 like futures sessions
+	DayStart time.Duration
+	// WeekStart is the first day of weekly intervals
+	WeekStart time.Weekday
 	// max number of OHLC bars to keep
 	Max int
 	// instruction when there are no execs during interval
 	EmptyInst EmptyInst
+	// Session is the trading calendar. Intervals are always kept if nil
+	Session *Session
+	// instruction when execs are before the last interval
+	LateInst LateInst
+	// UpdateOnClose updates indicators once per interval when it closes
+	// rather than on every exec or OHLCV of the interval
+	UpdateOnClose bool
+	// OnClose is called once when an interval closes with its final OHLCV and
+	// indicator values. Intervals passed to NewSeries are closed already
+	// except the last one
+	OnClose func(itvl *Interval)
 }
 
 // EmptyInst is instruction when no values are set for the interval
@@ -60,6 +107,31 @@ const (
 	EmptyInstUseZeros
 )
 
+// LateInst is instruction when an exec belongs to an interval before the last one
+type LateInst int
+
+const (
+	// LateInstDrop drops late execs and counts them in DroppedExecs
+	LateInstDrop LateInst = iota
+	// LateInstReject returns LateExecError for late execs
+	LateInstReject
+	// LateInstAmend merges late execs into their interval and generates
+	// indicator values again from that interval on. Execs of intervals no
+	// longer kept or never created are dropped
+	LateInstAmend
+)
+
+// LateExecError is returned by AddExec for late execs with LateInstReject
+type LateExecError struct {
+	Exec TPQ
+	// Last is the start of the last interval
+	Last time.Time
+}
+
+func (e *LateExecError) Error() string {
+	return fmt.Sprintf("exec at %v is before the last interval at %v", e.Exec.Timestamp, e.Last)
+}
+
 // NewSeries generates new OHLCV serie
 func NewSeries(ohlcv []OHLCV, opts SeriesOpts) (Series, error) {
 	// Validate validates series opts and returns error if not good
@@ -68,28 +140,56 @@ func NewSeries(ohlcv []OHLCV, opts SeriesOpts) (Series, error) {
 		err = errors.New("`Interval` must be positive")
 	} else if opts.Max <= 0 {
 		err = errors.New("`Max` must be positive")
+	} else if opts.LateInst < LateInstDrop || opts.LateInst > LateInstAmend {
+		err = errors.New("unsupported `LateInst`")
+	} else if err = opts.validateInterval(); err == nil && opts.Session != nil {
+		err = opts.Session.validate()
 	}
 	if err != nil {
 		return nil, fmt.Errorf("error validating seriesopts: %w", err)
 	}
-	tm := make(map[time.Time]*OHLCV)
 	s := &series{
 		items:   make(map[string]Indicator),
+		graph:   newGraph(),
+		emitted: make(map[string]emittedValue),
 		opts:    opts,
-		timemap: tm,
-		values:  make([]OHLCV, 0, opts.Max),
+		values:  newSeriesRing[OHLCV](opts),
+		spans:   newSeriesRing[execSpan](opts),
 	}
 	s.initValues(ohlcv)
+	// intervals passed in are history where all but the last one are closed
+	s.history = s.values.lastIndex()
+	s.closed = s.history - 1
 	return s, nil
 }
 
 type series struct {
-	items    map[string]Indicator
+	items map[string]Indicator
+	// names are the names of items in the order added
+	names    []string
+	graph    *graph
+	subs     []subscriber
+	lastSub  int
+	emitted  map[string]emittedValue
 	lastExec TPQ
 	lastOHLC *OHLCV
 	opts     SeriesOpts
-	values   []OHLCV
-	timemap  map[time.Time]*OHLCV
+	values   *ring[OHLCV]
+	spans    *ring[execSpan]
+	dropped  int
+	// closed is the index of the last closed interval
+	closed int
+	// history is the index of the last interval passed to NewSeries
+	history int
+	// lastNew is set until the last interval is updated
+	lastNew bool
+}
+
+// execSpan is the time of the first and last exec of an interval. Both are
+// zero if the interval was filled or added as OHLCV
+type execSpan struct {
+	first time.Time
+	last  time.Time
 }
 
 func (s *series) initValues(values []OHLCV) {
@@ -101,43 +201,158 @@ func (s *series) initValues(values []OHLCV) {
 func (s *series) insertInterval(v OHLCV) {
 	t := s.getLastIntervalFromTime(v.S)
 	v.S = t
-	_, ok := s.timemap[t]
-	if !ok {
-		s.values = append(s.values, v)
-		s.timemap[t] = &v
-		s.lastOHLC = &v
+	if _, ok := s.values.indexOf(t); ok {
+		return
+	}
+	// the oldest interval is overwritten once Max intervals are kept
+	if idx, ok := s.values.set(t, v); ok {
+		s.lastOHLC = s.values.ptr(idx)
+		s.spans.set(t, execSpan{})
 	}
 }
 
+// updateIndicators updates every node of the indicators once with v
 func (s *series) updateIndicators(v OHLCV) error {
+	if err := s.graph.update(v); err != nil {
+		return fmt.Errorf("error updating indicator: %w", err)
+	}
+	return nil
+}
+
+// addInterval closes the last interval and adds v after it with execs
+// during span
+func (s *series) addInterval(v OHLCV, span execSpan, typ EventType) error {
+	if err := s.closeLast(); err != nil {
+		return err
+	}
+	s.insertInterval(v)
+	s.spans.set(v.S, span)
+	s.lastNew = true
+	if !s.opts.UpdateOnClose {
+		if err := s.updateIndicators(v); err != nil {
+			return err
+		}
+	}
+	s.notify(typ, v.S, !s.opts.UpdateOnClose)
+	return nil
+}
+
+// reviseLast updates indicators with the updated last interval
+func (s *series) reviseLast() error {
+	s.lastNew = false
+	if !s.opts.UpdateOnClose {
+		if err := s.updateIndicators(*s.lastOHLC); err != nil {
+			return err
+		}
+	}
+	s.notify(EventBarUpdated, s.lastOHLC.S, !s.opts.UpdateOnClose)
+	return nil
+}
+
+// closeLast confirms the last interval before a later one is added
+func (s *series) closeLast() error {
+	if s.lastOHLC == nil {
+		return nil
+	}
+	idx := s.values.lastIndex()
+	if idx <= s.closed {
+		return nil
+	}
+	s.closed = idx
+	if s.opts.UpdateOnClose {
+		if err := s.updateIndicators(*s.lastOHLC); err != nil {
+			return err
+		}
+	}
+	if s.opts.OnClose != nil {
+		s.opts.OnClose(s.GetValueForInterval(s.lastOHLC.S))
+	}
+	s.notify(EventBarClosed, s.lastOHLC.S, s.opts.UpdateOnClose)
+	return nil
+}
+
+// updatedIndex returns the index of the last interval indicators are updated with
+func (s *series) updatedIndex() int {
+	if s.opts.UpdateOnClose && s.closed < s.values.lastIndex() {
+		return s.closed
+	}
+	return s.values.lastIndex()
+}
+
+// reset removes all intervals and indicator values
+func (s *series) reset() {
+	s.values.clear()
+	s.spans.clear()
+	s.lastOHLC = nil
 	for _, ind := range s.items {
-		if err := ind.Update(v); err != nil {
-			return fmt.Errorf("error updating indicator: %w", err)
+		resetIndicator(ind)
+	}
+}
+
+// replay generates indicator values again from interval t. Indicators are
+// rewound to t if all of them keep the state of every kept interval, otherwise
+// they are reset and updated again from the oldest kept interval
+func (s *series) replay(t time.Time) error {
+	first := s.values.search(t)
+	for _, ind := range s.items {
+		err := rewindIndicator(ind, t)
+		if errors.Is(err, errNoRewind) {
+			for _, ind := range s.items {
+				resetIndicator(ind)
+			}
+			first = s.values.firstIndex()
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("error rewinding indicator: %w", err)
+		}
+	}
+	for idx := first; idx <= s.updatedIndex(); idx++ {
+		v, _ := s.values.get(idx)
+		if err := s.updateIndicators(v); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-func (s *series) getLastIntervalFromTime(t time.Time) time.Time {
-	year, month, day := t.UTC().Date()
-	st := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
-	m := s.getMultiplierDiff(t, st)
-	return st.Add(time.Duration(m*s.opts.Interval) * time.Second)
+// truncate removes intervals from t onwards and the indicator values of them
+func (s *series) truncate(t time.Time) error {
+	s.values.rewind(t)
+	s.spans.rewind(t)
+	s.lastOHLC = nil
+	s.lastNew = false
+	last := s.values.lastIndex()
+	if s.values.has(last) {
+		s.lastOHLC = s.values.ptr(last)
+	}
+	if s.closed > last {
+		// intervals before a removed one were closed by it
+		s.closed = last
+	}
+	return s.replay(t)
 }
 
-func (s *series) getMultiplierDiff(t time.Time, st time.Time) int {
-	diff := t.Sub(st).Seconds()
-	return int(diff / float64(s.opts.Interval))
+func (s *series) getLastIntervalFromTime(t time.Time) time.Time {
+	return s.opts.intervalStart(t)
 }
 
 func (s *series) getOHLCV(t time.Time) *OHLCV {
-	return s.timemap[t]
+	idx, ok := s.values.indexOf(t)
+	if !ok {
+		return nil
+	}
+	return s.values.ptr(idx)
 }
 
 // series_add_exec.go
 
 func (s *series) AddExec(v TPQ) error {
 	start := s.getLastIntervalFromTime(v.Timestamp)
+	if !s.opts.isTradingInterval(start) {
+		// no intervals outside trading hours
+		return nil
+	}
 	if s.lastOHLC == nil {
 		if err := s.createNewOHLCV(v, start); err != nil {
 			return fmt.Errorf("error creating new ohlcv: %w", err)
@@ -151,11 +366,76 @@ func (s *series) AddExec(v TPQ) error {
 		if err := s.updateAndFillGaps(v, start); err != nil {
 			return fmt.Errorf("error updating and filling gaps: %w", err)
 		}
+	} else {
+		return s.addLateExec(v, start)
 	}
 	s.lastExec = v
 	return nil
 }
 
+func (s *series) DroppedExecs() int {
+	return s.dropped
+}
+
+// addLateExec handles exec v of interval start before the last interval
+// following LateInst
+func (s *series) addLateExec(v TPQ, start time.Time) error {
+	switch s.opts.LateInst {
+	case LateInstReject:
+		return &LateExecError{
+			Exec: v,
+			Last: s.lastOHLC.S,
+		}
+	case LateInstAmend:
+		idx, ok := s.values.indexOf(start)
+		if !ok {
+			break
+		}
+		s.amendOHLCV(s.values.ptr(idx), s.spans.ptr(idx), v)
+		if err := s.replay(start); err != nil {
+			return fmt.Errorf("error replaying indicators: %w", err)
+		}
+		s.notify(EventBarUpdated, start, true)
+		return nil
+	}
+	s.dropped++
+	return nil
+}
+
+// amendOHLCV merges exec v into interval itvl. Open and close are replaced
+// only if v is before or after all execs of the interval
+func (s *series) amendOHLCV(itvl *OHLCV, span *execSpan, v TPQ) {
+	if span.first.IsZero() {
+		if itvl.V == 0 {
+			// filled interval has no execs to merge with
+			*itvl = NewOHLCVWithSamePx(v.Px, v.Qty, itvl.S)
+			*span = execSpan{v.Timestamp, v.Timestamp}
+		} else {
+			// order of execs within OHLCV is unknown
+			itvl.H = math.Max(itvl.H, v.Px)
+			itvl.L = math.Min(itvl.L, v.Px)
+			itvl.V += v.Qty
+		}
+		return
+	}
+	s.mergeExec(itvl, span, v)
+}
+
+// mergeExec merges exec v into interval itvl with execs during span
+func (s *series) mergeExec(itvl *OHLCV, span *execSpan, v TPQ) {
+	if v.Timestamp.Before(span.first) {
+		itvl.O = v.Px
+		span.first = v.Timestamp
+	}
+	if !v.Timestamp.Before(span.last) {
+		itvl.C = v.Px
+		span.last = v.Timestamp
+	}
+	itvl.H = math.Max(itvl.H, v.Px)
+	itvl.L = math.Min(itvl.L, v.Px)
+	itvl.V += v.Qty
+}
+
 func NewOHLCVWithSamePx(px, qty float64, t time.Time) OHLCV {
 	return OHLCV{px, px, px, px, qty, t}
 }
@@ -163,8 +443,7 @@ func NewOHLCVWithSamePx(px, qty float64, t time.Time) OHLCV {
 func (s *series) createNewOHLCV(v TPQ, start time.Time) error {
 	// create first one
 	ohlcv := NewOHLCVWithSamePx(v.Px, v.Qty, start)
-	s.insertInterval(ohlcv)
-	if err := s.updateIndicators(ohlcv); err != nil {
+	if err := s.addInterval(ohlcv, execSpan{v.Timestamp, v.Timestamp}, EventBarOpened); err != nil {
 		return fmt.Errorf("error updating indicator: %w", err)
 	}
 	return nil
@@ -172,64 +451,53 @@ func (s *series) createNewOHLCV(v TPQ, start time.Time) error {
 
 func (s *series) updateLastOHLCV(v TPQ) error {
 	itvl := s.lastOHLC
-	itvl.C = v.Px
-	itvl.V += v.Qty
-	if v.Px > itvl.H {
-		itvl.H = v.Px
-	} else if v.Px < itvl.L {
-		itvl.L = v.Px
+	span := s.spans.ptr(s.spans.lastIndex())
+	if span.first.IsZero() {
+		// execs after OHLCV are assumed to be in order
+		span.first, span.last = itvl.S, v.Timestamp
 	}
-	if err := s.updateIndicators(*itvl); err != nil {
+	// execs can be out of order within the interval
+	s.mergeExec(itvl, span, v)
+	if err := s.reviseLast(); err != nil {
 		return fmt.Errorf("error updating indicator: %w", err)
 	}
 	return nil
 }
 
 func (s *series) updateAndFillGaps(v TPQ, start time.Time) error {
+	if err := s.fillGaps(start); err != nil {
+		return err
+	}
+	ohlcv := NewOHLCVWithSamePx(v.Px, v.Qty, start)
+	if err := s.addInterval(ohlcv, execSpan{v.Timestamp, v.Timestamp}, EventBarOpened); err != nil {
+		return fmt.Errorf("error updating indicator: %w", err)
+	}
+	return nil
+}
+
+// fillGaps creates intervals from the last interval up to start following
+// EmptyInst. Intervals outside trading hours are skipped
+func (s *series) fillGaps(start time.Time) error {
+	var px float64
 	switch s.opts.EmptyInst {
 	case EmptyInstUseLastClose:
-		// figure out how many
-		m := s.getMultiplierDiff(v.Timestamp, s.lastOHLC.S)
-		usenew := int(math.Max(float64(m-1), float64(0)))
-		for i := 0; i < m; i++ {
-			var px, qty float64
-			if i == usenew {
-				px = v.Px
-				qty = v.Qty
-			} else {
-				px = s.lastOHLC.C
-				qty = 0
-			}
-			newt := s.lastOHLC.S.Add(time.Duration(s.opts.Interval) * time.Second)
-			ohlcv := NewOHLCVWithSamePx(px, qty, newt)
-			s.insertInterval(ohlcv)
-			s.updateIndicators(ohlcv)
-		}
+		px = s.lastOHLC.C
 	case EmptyInstUseZeros:
-		// figure out how many
-		m := s.getMultiplierDiff(v.Timestamp, s.lastOHLC.S)
-		usenew := int(math.Max(float64(m-1), float64(0)))
-		for i := 0; i < m; i++ {
-			var px, qty float64
-			if i == usenew {
-				px = v.Px
-				qty = v.Qty
-			} else {
-				px = 0
-				qty = 0
-			}
-			newt := s.lastOHLC.S.Add(time.Duration(s.opts.Interval) * time.Second)
-			ohlcv := NewOHLCVWithSamePx(px, qty, newt)
-			s.insertInterval(ohlcv)
-			s.updateIndicators(ohlcv)
-		}
+		px = 0
 	case EmptyInstIgnore:
-		v := NewOHLCVWithSamePx(v.Px, v.Qty, start)
-		s.insertInterval(v)
-		s.updateIndicators(v)
+		return nil
 	default:
 		return fmt.Errorf("unsupported interval: %+v", s.opts.EmptyInst)
 	}
+	for t := s.opts.nextIntervalStart(s.lastOHLC.S); t.Before(start); t = s.opts.nextIntervalStart(t) {
+		if !s.opts.isTradingInterval(t) {
+			continue
+		}
+		ohlcv := NewOHLCVWithSamePx(px, 0, t)
+		if err := s.addInterval(ohlcv, execSpan{}, EventGapFilled); err != nil {
+			return fmt.Errorf("error updating indicator: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -240,13 +508,31 @@ func (s *series) AddIndicator(name string, i Indicator) error {
 	if err := i.ApplyOpts(s.opts); err != nil {
 		return fmt.Errorf("error applying opts")
 	}
-	// update with current values downstream
-	for _, v := range s.values {
-		if err := i.Update(v); err != nil {
-			return fmt.Errorf("error updating indicator")
+	var nodes []node
+	if _, ok := s.items[name]; ok {
+		// nodes only the replaced indicator reads are no longer updated
+		old := s.graph
+		s.items[name] = i
+		s.graph = newGraph()
+		for _, n := range s.names {
+			s.graph.add(s.items[n])
+		}
+		nodes = s.graph.without(old)
+	} else {
+		nodes = s.graph.add(i)
+		s.names = append(s.names, name)
+		s.items[name] = i
+	}
+	// update with current values downstream. Nodes shared with indicators
+	// added before are up to date already
+	for idx := s.values.firstIndex(); idx <= s.updatedIndex(); idx++ {
+		v, _ := s.values.get(idx)
+		for _, n := range nodes {
+			if err := n.updateNode(v); err != nil {
+				return fmt.Errorf("error updating indicator")
+			}
 		}
 	}
-	s.items[name] = i
 	return nil
 }
 
@@ -255,9 +541,15 @@ func (s *series) AddIndicator(name string, i Indicator) error {
 func (s *series) AddOHLCV(v OHLCV) error {
 	start := s.getLastIntervalFromTime(v.S)
 	v.S = start
+	if !s.opts.isTradingInterval(start) {
+		// no intervals outside trading hours
+		return nil
+	}
 	if s.lastOHLC == nil {
 		// create first one
-		s.insertInterval(v)
+		if err := s.addInterval(v, execSpan{}, EventBarOpened); err != nil {
+			return fmt.Errorf("error updating indicator: %w", err)
+		}
 	} else if s.lastOHLC.S.Equal(start) {
 		// update this interval
 		itvl := s.lastOHLC
@@ -266,50 +558,17 @@ func (s *series) AddOHLCV(v OHLCV) error {
 		itvl.L = v.L
 		itvl.C = v.C
 		itvl.V = v.V
+		if err := s.reviseLast(); err != nil {
+			return fmt.Errorf("error updating indicator: %w", err)
+		}
 	} else if start.Sub(s.lastOHLC.S).Seconds() > 0 {
-		// calculate how many intervals are missing
-		switch s.opts.EmptyInst {
-		case EmptyInstUseLastClose:
-			// figure out how many
-			m := s.getMultiplierDiff(v.S, s.lastOHLC.S)
-			usenew := int(math.Max(float64(m-1), float64(0)))
-			for i := 0; i < m; i++ {
-				var px, qty float64
-				var ohlcv OHLCV
-				if i == usenew {
-					ohlcv = v
-				} else {
-					px = s.lastOHLC.C
-					qty = 0
-					newt := s.lastOHLC.S.Add(time.Duration(s.opts.Interval) * time.Second)
-					ohlcv = NewOHLCVWithSamePx(px, qty, newt)
-				}
-				s.insertInterval(ohlcv)
-			}
-		case EmptyInstUseZeros:
-			// figure out how many
-			m := s.getMultiplierDiff(v.S, s.lastOHLC.S)
-			usenew := int(math.Max(float64(m-1), float64(0)))
-			for i := 0; i < m; i++ {
-				var px, qty float64
-				var ohlcv OHLCV
-				if i == usenew {
-					ohlcv = v
-				} else {
-					px = 0
-					qty = 0
-					newt := s.lastOHLC.S.Add(time.Duration(s.opts.Interval) * time.Second)
-					ohlcv = NewOHLCVWithSamePx(px, qty, newt)
-				}
-				s.insertInterval(ohlcv)
-			}
-		case EmptyInstIgnore:
-			s.insertInterval(v)
-		default:
-			return fmt.Errorf("unsupported interval: %+v", s.opts.EmptyInst)
+		if err := s.fillGaps(start); err != nil {
+			return fmt.Errorf("error filling gaps: %w", err)
+		}
+		if err := s.addInterval(v, execSpan{}, EventBarOpened); err != nil {
+			return fmt.Errorf("error updating indicator: %w", err)
 		}
 	}
-	s.lastOHLC = &v
 	return nil
 }
 
@@ -331,13 +590,26 @@ func (s *series) GetValueForInterval(t time.Time) *Interval {
 			inds[k] = &val.Value
 		}
 	}
-	v, ok := s.timemap[t]
+	idx, ok := s.values.indexOf(t)
 	if !ok {
 		return nil
 	}
+	v, _ := s.values.get(idx)
 	return &Interval{
 		StartTime:  v.S,
-		OHLCV:      v,
+		OHLCV:      &v,
 		Indicators: inds,
+		Session:    s.opts.sessionState(v.S),
+		BarState:   s.barState(idx),
+	}
+}
+
+// barState returns the state of the interval at index
+func (s *series) barState(idx int) BarState {
+	confirmed := idx <= s.closed
+	return BarState{
+		IsConfirmed: confirmed,
+		IsNew:       !confirmed && s.lastNew && idx == s.values.lastIndex(),
+		IsRealtime:  idx > s.history,
 	}
 }
@@ -0,0 +1,165 @@
+package pine
+
+import (
+	"errors"
+	"time"
+)
+
+// SessionHours is a period of trading hours as offsets from midnight in
+// SeriesOpts.Location, e.g. 9h30m to 16h. Hours ending at or before they
+// start continue on the next day, which is the trading day they belong to
+type SessionHours struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// Session is the trading calendar of a market. Intervals outside trading hours
+// are neither created by gap filling nor accepted from execs and OHLCVs
+type Session struct {
+	// Regular are the regular trading hours
+	Regular []SessionHours
+	// PreMarket are the extended trading hours before regular hours
+	PreMarket []SessionHours
+	// PostMarket are the extended trading hours after regular hours
+	PostMarket []SessionHours
+	// Extended keeps intervals during pre and post market hours
+	Extended bool
+	// Days are the trading days. Defaults to Monday to Friday
+	Days []time.Weekday
+	// Holidays are the dates the market is closed
+	Holidays []time.Time
+}
+
+// SessionState is the trading session an interval belongs to
+type SessionState int
+
+const (
+	// SessionRegular is regular trading hours. Intervals of series without
+	// Session are always in regular hours
+	SessionRegular SessionState = iota
+	// SessionPreMarket is extended trading hours before regular hours
+	SessionPreMarket
+	// SessionPostMarket is extended trading hours after regular hours
+	SessionPostMarket
+	// SessionClosed is outside trading hours
+	SessionClosed
+)
+
+// IsMarket returns true in regular trading hours like Pine's session.ismarket
+func (s SessionState) IsMarket() bool {
+	return s == SessionRegular
+}
+
+// IsPreMarket returns true before regular trading hours like Pine's session.ispremarket
+func (s SessionState) IsPreMarket() bool {
+	return s == SessionPreMarket
+}
+
+// IsPostMarket returns true after regular trading hours like Pine's session.ispostmarket
+func (s SessionState) IsPostMarket() bool {
+	return s == SessionPostMarket
+}
+
+func (s *Session) validate() error {
+	for _, hours := range [][]SessionHours{s.Regular, s.PreMarket, s.PostMarket} {
+		for _, h := range hours {
+			if h.Start < 0 || h.Start >= 24*time.Hour || h.End < 0 || h.End > 24*time.Hour {
+				return errors.New("session hours must be within a day")
+			}
+		}
+	}
+	if len(s.Regular) == 0 {
+		return errors.New("session must have regular hours")
+	}
+	return nil
+}
+
+// isTradingDay returns true if the market trades on y-m-d
+func (s *Session) isTradingDay(y int, m time.Month, d int) bool {
+	date := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	wd := date.Weekday()
+	if len(s.Days) == 0 {
+		if wd == time.Saturday || wd == time.Sunday {
+			return false
+		}
+	} else {
+		trades := false
+		for _, day := range s.Days {
+			if day == wd {
+				trades = true
+				break
+			}
+		}
+		if !trades {
+			return false
+		}
+	}
+	for _, h := range s.Holidays {
+		hy, hm, hd := h.Date()
+		if hy == date.Year() && hm == date.Month() && hd == date.Day() {
+			return false
+		}
+	}
+	return true
+}
+
+// sessionState returns the session of the interval starting at st. It is the
+// session at st or, if closed at st, the first session starting during the
+// interval
+func (o SeriesOpts) sessionState(st time.Time) SessionState {
+	if o.Session == nil {
+		return SessionRegular
+	}
+	end := o.nextIntervalStart(st)
+	loc := o.location()
+	y, m, d := st.In(loc).Date()
+	ey, em, ed := end.In(loc).Date()
+	days := civilDay(ey, em, ed) - civilDay(y, m, d)
+	state := SessionClosed
+	var first time.Time
+	// hours continuing past midnight may have started the day before
+	for j := -1; j <= days; j++ {
+		for _, sess := range []struct {
+			state SessionState
+			hours []SessionHours
+		}{
+			{SessionRegular, o.Session.Regular},
+			{SessionPreMarket, o.Session.PreMarket},
+			{SessionPostMarket, o.Session.PostMarket},
+		} {
+			for _, h := range sess.hours {
+				open := wallClock(y, m, d+j, h.Start, loc)
+				closeDay := d + j
+				if h.End <= h.Start {
+					closeDay++
+				}
+				close := wallClock(y, m, closeDay, h.End, loc)
+				if !open.Before(end) || !close.After(st) {
+					continue
+				}
+				if !o.Session.isTradingDay(y, m, closeDay) {
+					continue
+				}
+				if !open.After(st) {
+					return sess.state
+				}
+				if first.IsZero() || open.Before(first) {
+					first = open
+					state = sess.state
+				}
+			}
+		}
+	}
+	return state
+}
+
+// isTradingInterval returns true if the interval starting at st is kept
+func (o SeriesOpts) isTradingInterval(st time.Time) bool {
+	switch o.sessionState(st) {
+	case SessionRegular:
+		return true
+	case SessionPreMarket, SessionPostMarket:
+		return o.Session.Extended
+	}
+	return false
+}
@@ -0,0 +1,438 @@
+package pine
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// snapshotMagic prefixes snapshots so other data is rejected
+const snapshotMagic = "pine"
+
+// snapshotVersion is the version of snapshots written. Bump it whenever the
+// state of the series or an indicator changes and decode older versions in
+// decodeSnapshot if possible
+const snapshotVersion = 1
+
+var (
+	// ErrSnapshotVersion is returned when restoring snapshots of an
+	// unsupported version
+	ErrSnapshotVersion = errors.New("unsupported snapshot version")
+	// ErrSnapshotMismatch is returned when restoring snapshots of a series
+	// with different interval or indicators
+	ErrSnapshotMismatch = errors.New("snapshot does not match series")
+)
+
+// snapshotter is implemented by nodes of this package to save and restore
+// their state
+type snapshotter interface {
+	encodeState(e *encoder)
+	decodeState(d *decoder)
+}
+
+// encoder writes state in a compact binary format
+type encoder struct {
+	buf []byte
+	err error
+}
+
+// fail keeps the first error of state that cannot be written
+func (e *encoder) fail(err error) {
+	if e.err == nil {
+		e.err = err
+	}
+}
+
+func (e *encoder) int(v int) {
+	e.varint(int64(v))
+}
+
+func (e *encoder) varint(v int64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(b[:], v)
+	e.buf = append(e.buf, b[:n]...)
+}
+
+func (e *encoder) float(v float64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], math.Float64bits(v))
+	e.buf = append(e.buf, b[:]...)
+}
+
+func (e *encoder) bool(v bool) {
+	if v {
+		e.buf = append(e.buf, 1)
+	} else {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+// time writes t without location as it is restored in the series location
+func (e *encoder) time(t time.Time) {
+	e.bool(t.IsZero())
+	if !t.IsZero() {
+		e.varint(t.UnixNano())
+	}
+}
+
+func (e *encoder) bytes(b []byte) {
+	e.int(len(b))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) string(s string) {
+	e.bytes([]byte(s))
+}
+
+func (e *encoder) ohlcv(v OHLCV) {
+	e.float(v.O)
+	e.float(v.H)
+	e.float(v.L)
+	e.float(v.C)
+	e.float(v.V)
+	e.time(v.S)
+}
+
+// decoder reads state written by encoder. The first error is kept and
+// following reads return zero values
+type decoder struct {
+	buf []byte
+	loc *time.Location
+	err error
+}
+
+func (d *decoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+func (d *decoder) int() int {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.buf)
+	if n <= 0 {
+		d.fail(errors.New("snapshot is truncated"))
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return int(v)
+}
+
+func (d *decoder) float() float64 {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.buf) < 8 {
+		d.fail(errors.New("snapshot is truncated"))
+		return 0
+	}
+	v := math.Float64frombits(binary.LittleEndian.Uint64(d.buf))
+	d.buf = d.buf[8:]
+	return v
+}
+
+func (d *decoder) bool() bool {
+	if d.err != nil {
+		return false
+	}
+	if len(d.buf) < 1 {
+		d.fail(errors.New("snapshot is truncated"))
+		return false
+	}
+	v := d.buf[0] == 1
+	d.buf = d.buf[1:]
+	return v
+}
+
+func (d *decoder) time() time.Time {
+	if d.bool() || d.err != nil {
+		return time.Time{}
+	}
+	v, n := binary.Varint(d.buf)
+	if n <= 0 {
+		d.fail(errors.New("snapshot is truncated"))
+		return time.Time{}
+	}
+	d.buf = d.buf[n:]
+	return time.Unix(0, v).In(d.loc)
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.int()
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(d.buf) {
+		d.fail(errors.New("snapshot is truncated"))
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes())
+}
+
+func (d *decoder) ohlcv() OHLCV {
+	return OHLCV{
+		O: d.float(),
+		H: d.float(),
+		L: d.float(),
+		C: d.float(),
+		V: d.float(),
+		S: d.time(),
+	}
+}
+
+// encodeRing writes values of r from the oldest
+func encodeRing[T any](e *encoder, r *ring[T], put func(e *encoder, v T)) {
+	if r == nil {
+		e.int(0)
+		return
+	}
+	e.int(r.len())
+	for idx := r.firstIndex(); idx <= r.lastIndex(); idx++ {
+		v, _ := r.get(idx)
+		e.time(r.timeAt(idx))
+		put(e, v)
+	}
+}
+
+// decodeRing replaces values of r with values written by encodeRing
+func decodeRing[T any](d *decoder, r *ring[T], get func(d *decoder) T) {
+	n := d.int()
+	if d.err != nil {
+		return
+	}
+	if n < 0 || (n > 0 && (r == nil || n > r.capacity())) {
+		d.fail(fmt.Errorf("%w: too many values", ErrSnapshotMismatch))
+		return
+	}
+	if r == nil {
+		return
+	}
+	r.clear()
+	for j := 0; j < n && d.err == nil; j++ {
+		t := d.time()
+		r.set(t, get(d))
+	}
+}
+
+func putFloat(e *encoder, v float64) {
+	e.float(v)
+}
+
+func getFloat(d *decoder) float64 {
+	return d.float()
+}
+
+func (s *valueStore) encode(e *encoder) {
+	encodeRing(e, s.vals, putFloat)
+}
+
+func (s *valueStore) decode(d *decoder) {
+	decodeRing(d, s.vals, getFloat)
+}
+
+func (a expAvg) encode(e *encoder) {
+	e.float(a.alpha)
+	e.int(a.length)
+	e.int(a.count)
+	e.float(a.sum)
+	e.float(a.value)
+}
+
+func (a *expAvg) decode(d *decoder) {
+	a.alpha = d.float()
+	a.length = d.int()
+	a.count = d.int()
+	a.sum = d.float()
+	a.value = d.float()
+}
+
+func putExpAvg(e *encoder, a expAvg) {
+	a.encode(e)
+}
+
+func getExpAvg(d *decoder) expAvg {
+	var a expAvg
+	a.decode(d)
+	return a
+}
+
+// MarshalBinary returns a snapshot of intervals and the state of every
+// indicator so the series can be restored without replaying intervals
+func (s *series) MarshalBinary() ([]byte, error) {
+	e := &encoder{}
+	e.buf = append(e.buf, snapshotMagic...)
+	e.int(snapshotVersion)
+	s.encodeSnapshot(e)
+	if e.err != nil {
+		return nil, e.err
+	}
+	return e.buf, nil
+}
+
+// UnmarshalBinary restores a snapshot taken by MarshalBinary. The series must
+// have the same interval and indicators added in the same order as the
+// series the snapshot was taken of. The series is unchanged on error
+func (s *series) UnmarshalBinary(data []byte) error {
+	// current state is kept to roll back to
+	prev, err := s.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if err := s.restore(data); err != nil {
+		if rerr := s.restore(prev); rerr != nil {
+			return fmt.Errorf("error rolling back snapshot: %w", rerr)
+		}
+		return err
+	}
+	return nil
+}
+
+// restore decodes a snapshot written by MarshalBinary
+func (s *series) restore(data []byte) error {
+	if len(data) < len(snapshotMagic) || string(data[:len(snapshotMagic)]) != snapshotMagic {
+		return errors.New("data is not a snapshot")
+	}
+	d := &decoder{
+		buf: data[len(snapshotMagic):],
+		loc: s.opts.location(),
+	}
+	if v := d.int(); d.err == nil && v != snapshotVersion {
+		return fmt.Errorf("%w: %d", ErrSnapshotVersion, v)
+	}
+	s.decodeSnapshot(d)
+	if d.err == nil && len(d.buf) > 0 {
+		d.fail(fmt.Errorf("%w: unexpected data after snapshot", ErrSnapshotMismatch))
+	}
+	if d.err != nil {
+		return fmt.Errorf("error restoring snapshot: %w", d.err)
+	}
+	return nil
+}
+
+// encodeSnapshot writes the state of s without header so it can be nested in
+// snapshots of other series
+func (s *series) encodeSnapshot(e *encoder) {
+	e.int(s.opts.Interval)
+	e.int(int(s.opts.IntervalUnit))
+	encodeRing(e, s.values, func(e *encoder, v OHLCV) {
+		e.ohlcv(v)
+	})
+	encodeRing(e, s.spans, func(e *encoder, v execSpan) {
+		e.time(v.first)
+		e.time(v.last)
+	})
+	e.time(s.lastExec.Timestamp)
+	e.float(s.lastExec.Px)
+	e.float(s.lastExec.Qty)
+	// indexes are relative to the oldest interval as rings restart indexing
+	e.int(s.closed - s.values.firstIndex())
+	e.int(s.history - s.values.firstIndex())
+	e.bool(s.lastNew)
+	e.int(s.dropped)
+	e.int(len(s.names))
+	for _, name := range s.names {
+		e.string(name)
+	}
+	e.int(len(s.graph.nodes))
+	for _, n := range s.graph.nodes {
+		e.string(nodeType(n))
+		switch n := n.(type) {
+		case snapshotter:
+			n.encodeState(e)
+		case *indicatorNode:
+			m, ok := n.Indicator.(encoding.BinaryMarshaler)
+			if !ok {
+				e.fail(fmt.Errorf("indicator %T does not implement encoding.BinaryMarshaler", n.Indicator))
+				return
+			}
+			b, err := m.MarshalBinary()
+			if err != nil {
+				e.fail(fmt.Errorf("error marshaling indicator %T: %w", n.Indicator, err))
+				return
+			}
+			e.bytes(b)
+		default:
+			e.fail(fmt.Errorf("node %T cannot be saved", n))
+			return
+		}
+	}
+}
+
+// decodeSnapshot reads state written by encodeSnapshot
+func (s *series) decodeSnapshot(d *decoder) {
+	if d.int() != s.opts.Interval || IntervalUnit(d.int()) != s.opts.IntervalUnit {
+		d.fail(fmt.Errorf("%w: interval differs", ErrSnapshotMismatch))
+	}
+	decodeRing(d, s.values, func(d *decoder) OHLCV {
+		return d.ohlcv()
+	})
+	decodeRing(d, s.spans, func(d *decoder) execSpan {
+		return execSpan{d.time(), d.time()}
+	})
+	s.lastExec = TPQ{
+		Timestamp: d.time(),
+		Px:        d.float(),
+		Qty:       d.float(),
+	}
+	s.closed = s.values.firstIndex() + d.int()
+	s.history = s.values.firstIndex() + d.int()
+	s.lastNew = d.bool()
+	s.dropped = d.int()
+	s.lastOHLC = s.values.ptr(s.values.lastIndex())
+	if n := d.int(); d.err == nil && n != len(s.names) {
+		d.fail(fmt.Errorf("%w: %d indicators instead of %d", ErrSnapshotMismatch, n, len(s.names)))
+	}
+	for j := 0; j < len(s.names) && d.err == nil; j++ {
+		if name := d.string(); d.err == nil && name != s.names[j] {
+			d.fail(fmt.Errorf("%w: indicator %s instead of %s", ErrSnapshotMismatch, name, s.names[j]))
+		}
+	}
+	if n := d.int(); d.err == nil && n != len(s.graph.nodes) {
+		d.fail(fmt.Errorf("%w: %d nodes instead of %d", ErrSnapshotMismatch, n, len(s.graph.nodes)))
+	}
+	for j := 0; j < len(s.graph.nodes) && d.err == nil; j++ {
+		n := s.graph.nodes[j]
+		if typ := d.string(); d.err == nil && typ != nodeType(n) {
+			d.fail(fmt.Errorf("%w: node %s instead of %s", ErrSnapshotMismatch, typ, nodeType(n)))
+			break
+		}
+		switch n := n.(type) {
+		case snapshotter:
+			n.decodeState(d)
+		case *indicatorNode:
+			b := d.bytes()
+			if d.err != nil {
+				break
+			}
+			u, ok := n.Indicator.(encoding.BinaryUnmarshaler)
+			if !ok {
+				d.fail(fmt.Errorf("indicator %T does not implement encoding.BinaryUnmarshaler", n.Indicator))
+				break
+			}
+			if err := u.UnmarshalBinary(b); err != nil {
+				d.fail(fmt.Errorf("error unmarshaling indicator %T: %w", n.Indicator, err))
+			}
+		default:
+			d.fail(fmt.Errorf("node %T cannot be restored", n))
+		}
+	}
+}
+
+// nodeType returns the type of n used to match nodes of snapshots
+func nodeType(n node) string {
+	if w, ok := n.(*indicatorNode); ok {
+		return fmt.Sprintf("%T", w.Indicator)
+	}
+	return fmt.Sprintf("%T", n)
+}
@@ -3,7 +3,6 @@ package pine
 import (
 	"errors"
 	"fmt"
-	"math"
 	"time"
 
 	"github.com/shopspring/decimal"
@@ -12,88 +11,43 @@ import (
 // NewStdDev creates a new standard deviation indicator
 func NewStdDev(i Indicator, lookback int) Indicator {
 	return &stddev{
-		src:       i,
-		lookback:  lookback,
-		genval:    make(map[time.Time]*TimeValue, lookback),
-		srcval:    make(map[time.Time]*TimeValue, lookback*2),
-		srcvalues: make([]*TimeValue, 0, lookback),
-		genvalues: make([]*TimeValue, 0, lookback*2),
+		src:      i,
+		lookback: lookback,
+		window:   newRollingWindow(lookback),
 	}
 }
 
 type stddev struct {
-	lastUpdate OHLCV
-	lookback   int
-	opts       *SeriesOpts
-	genval     map[time.Time]*TimeValue
-	srcval     map[time.Time]*TimeValue
-	genvalues  []*TimeValue
-	srcvalues  []*TimeValue
-	src        Indicator
+	valueStore
+	lookback int
+	opts     *SeriesOpts
+	window   *rollingWindow
+	src      Indicator
 }
 
-func (i *stddev) GetValueForInterval(t time.Time) *Interval {
-	v, ok := i.genval[t]
-	if !ok {
-		return nil
-	}
-	return &Interval{
-		StartTime: t,
-		Value:     v.Value,
+func (i *stddev) Update(v OHLCV) error {
+	if err := i.src.Update(v); err != nil {
+		return fmt.Errorf("error received from src in StdDev: %w", err)
 	}
+	return i.updateNode(v)
 }
 
-func (i *stddev) shouldUpdate(v OHLCV) bool {
-	downval := i.src.GetValueForInterval(v.S)
-	if downval == nil {
-		// src value does not exist so cannot generate
-		return false
-	}
-	val, ok := i.srcval[v.S]
-	if !ok {
-		return true
-	}
-	if val.Value != downval.Value {
-		// if src value is updated and our src value cache is not updated
-		return true
-	}
-	return false
+func (i *stddev) inputs() []node {
+	return nodesOf(i.src)
 }
 
-func (i *stddev) Update(v OHLCV) error {
-	if err := i.src.Update(v); err != nil {
-		return fmt.Errorf("error received from src in StdDev: %w", err)
-	}
-	if !i.shouldUpdate(v) {
+func (i *stddev) updateNode(v OHLCV) error {
+	if i.window.before(v.S) {
+		// already generated
 		return nil
 	}
-	i.lastUpdate = v
-
-	// update src value and generate gen value
-	val := i.src.GetValueForInterval(v.S)
-	if val == nil {
-		return errors.New("expected src to provide data but none was given")
-	}
-	src, ok := i.srcval[v.S]
+	val, ok := valueOf(i.src, v.S)
 	if !ok {
-		tv := NewTimeValue(v.S, val.Value)
-		if len(i.srcvalues) >= i.lookback*2 {
-			// remove first
-			var old *TimeValue
-			old, i.srcvalues = i.srcvalues[0], i.srcvalues[1:]
-			if old == nil {
-				return errors.New("unexpected nil in StdDev array first el")
-			}
-			delete(i.srcval, old.Time)
-		}
-		i.srcval[v.S] = tv
-		i.srcvalues = append(i.srcvalues, tv)
-	} else if src.Value != val.Value {
-		// source value has changed
-		i.srcval[v.S].Value = val.Value
+		// src value does not exist so cannot generate
+		return nil
 	}
+	i.window.set(v.S, val)
 	i.generateStdDev(v.S)
-
 	return nil
 }
 
@@ -104,53 +58,34 @@ func (i *stddev) ApplyOpts(opts SeriesOpts) error {
 	if err := i.src.ApplyOpts(opts); err != nil {
 		return fmt.Errorf("error applying opts in source: %w", err)
 	}
-	if i.opts == nil || i.opts.Max != opts.Max {
-		i.genval = make(map[time.Time]*TimeValue, opts.Max)
-		i.srcval = make(map[time.Time]*TimeValue, i.lookback+opts.Max)
-		i.srcvalues = make([]*TimeValue, 0, i.lookback+opts.Max)
-		i.genvalues = make([]*TimeValue, 0, opts.Max)
-	}
+	i.initStore(opts)
+	i.window.applyOpts(opts)
 	i.opts = &opts
 	return nil
 }
 
-func (i *stddev) generateStdDev(t time.Time) error {
-	total := len(i.srcvalues)
-	firstidx := total - i.lookback
-	if firstidx < 0 {
-		return nil
-	}
+func (i *stddev) reset() {
+	resetIndicator(i.src)
+	i.window.reset()
+	i.resetStore()
+}
 
-	avgtot := decimal.NewFromFloat(0.0)
-	for j := firstidx; j < total; j++ {
-		avgtot = avgtot.Add(decimal.NewFromFloat(i.srcvalues[j].Value))
-	}
-	avg := avgtot.Div(decimal.NewFromFloat(float64(i.lookback)))
-	stddevtot := decimal.NewFromFloat(0.0)
-	for j := firstidx; j < total; j++ {
-		diff := decimal.NewFromFloat(i.srcvalues[j].Value).Sub(avg)
-		t := diff.Pow(decimal.NewFromFloat(2.0))
-		stddevtot = stddevtot.Add(t)
-	}
-	stddevtot = stddevtot.Div(decimal.NewFromFloat(float64(i.lookback)))
-	tot, _ := stddevtot.Float64()
-	stddev := math.Sqrt(tot)
-	tv := NewTimeValue(t, stddev)
-	_, ok := i.genval[t]
-	if !ok {
-		if len(i.genvalues) == cap(i.genvalues) {
-			var old *TimeValue
-			old, i.genvalues = i.genvalues[0], i.genvalues[1:]
-			delete(i.genval, old.Time)
-		}
-		i.genval[t] = tv
-		i.genvalues = append(i.genvalues, tv)
-	} else {
-		i.genval[t] = tv
+func (i *stddev) rewind(t time.Time) error {
+	if err := rewindIndicator(i.src, t); err != nil {
+		return err
 	}
+	i.window.rewind(t)
+	i.rewindStore(t)
 	return nil
 }
 
+func (i *stddev) generateStdDev(t time.Time) {
+	if !i.window.full() {
+		return
+	}
+	i.setValue(t, i.window.stdDev())
+}
+
 const SqrtMaxIter = 100000
 
 // SqrtRound returns the square root of d, the result will have
@@ -174,3 +109,13 @@ func SqrtRound(d decimal.Decimal, precision int32) (decimal.Decimal, bool) {
 	}
 	return mid, false
 }
+
+func (i *stddev) encodeState(e *encoder) {
+	i.valueStore.encode(e)
+	i.window.encode(e)
+}
+
+func (i *stddev) decodeState(d *decoder) {
+	i.valueStore.decode(d)
+	i.window.decode(d)
+}
@@ -0,0 +1,273 @@
+package pine
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Stoch holds the outputs of the stochastic oscillator.
+// Each output can be registered on Series separately
+type Stoch struct {
+	// K is the simple moving average of raw stochastic over K smoothing intervals
+	K Indicator
+	// D is the simple moving average of K over D smoothing intervals
+	D Indicator
+}
+
+const (
+	stochOutputK = iota
+	stochOutputD
+)
+
+type stoch struct {
+	kLength    int
+	kSmoothing int
+	dSmoothing int
+	high       Indicator
+	low        Indicator
+	raw        naWindow
+	k          naWindow
+	genvalues  *ring[stochValue]
+}
+
+// stochValue keeps outputs of an interval where NaN is na
+type stochValue struct {
+	K float64
+	D float64
+}
+
+// NewStoch creates a new stochastic oscillator reading high, low and close of
+// OHLCV. Raw value is Pine's ta.stoch over kLength intervals
+func NewStoch(kLength, kSmoothing, dSmoothing int) Stoch {
+	s := &stoch{
+		kLength:    kLength,
+		kSmoothing: kSmoothing,
+		dSmoothing: dSmoothing,
+		high:       NewHighest(NewOHLCProp(OHLCPropHigh), kLength),
+		low:        NewLowest(NewOHLCProp(OHLCPropLow), kLength),
+		raw:        newNaWindow(kSmoothing),
+		k:          newNaWindow(dSmoothing),
+		genvalues:  newRing[stochValue](defaultMax, 0),
+	}
+	return Stoch{
+		K: newOutput(s, stochOutputK),
+		D: newOutput(s, stochOutputD),
+	}
+}
+
+func (i *stoch) getOutput(t time.Time, idx int) (float64, bool) {
+	v, ok := i.genvalues.getTime(t)
+	if !ok {
+		return 0, false
+	}
+	switch idx {
+	case stochOutputK:
+		return v.K, !math.IsNaN(v.K)
+	case stochOutputD:
+		return v.D, !math.IsNaN(v.D)
+	}
+	return 0, false
+}
+
+func (i *stoch) Update(v OHLCV) error {
+	if err := i.high.Update(v); err != nil {
+		return fmt.Errorf("error updating high in Stoch: %w", err)
+	}
+	if err := i.low.Update(v); err != nil {
+		return fmt.Errorf("error updating low in Stoch: %w", err)
+	}
+	return i.updateNode(v)
+}
+
+func (i *stoch) inputs() []node {
+	return nodesOf(i.high, i.low)
+}
+
+func (i *stoch) updateNode(v OHLCV) error {
+	if i.raw.before(v.S) {
+		// already generated
+		return nil
+	}
+	h, ok := valueOf(i.high, v.S)
+	l, ok2 := valueOf(i.low, v.S)
+	if !ok || !ok2 {
+		return nil
+	}
+	if h == l {
+		// division by zero is na in Pine
+		i.raw.set(v.S, 0, true)
+	} else {
+		i.raw.set(v.S, 100*(v.C-l)/(h-l), false)
+	}
+	if !i.raw.full() {
+		return nil
+	}
+	gv := stochValue{
+		K: math.NaN(),
+		D: math.NaN(),
+	}
+	if k, ok := i.raw.mean(); ok {
+		gv.K = k
+		i.k.set(v.S, k, false)
+	} else {
+		i.k.set(v.S, 0, true)
+	}
+	if d, ok := i.k.mean(); ok {
+		gv.D = d
+	}
+	i.genvalues.set(v.S, gv)
+	return nil
+}
+
+func (i *stoch) ApplyOpts(opts SeriesOpts) error {
+	if opts.Max < i.kLength || opts.Max < i.kSmoothing || opts.Max < i.dSmoothing {
+		return errors.New("SeriesOpts max cannot be less than Stoch lookback value")
+	}
+	if err := i.high.ApplyOpts(opts); err != nil {
+		return fmt.Errorf("error applying opts in high: %w", err)
+	}
+	if err := i.low.ApplyOpts(opts); err != nil {
+		return fmt.Errorf("error applying opts in low: %w", err)
+	}
+	if i.genvalues.capacity() != opts.Max {
+		i.genvalues = newSeriesRing[stochValue](opts)
+	}
+	i.raw.applyOpts(opts)
+	i.k.applyOpts(opts)
+	return nil
+}
+
+func (i *stoch) reset() {
+	resetIndicator(i.high)
+	resetIndicator(i.low)
+	i.raw.reset()
+	i.k.reset()
+	i.genvalues.clear()
+}
+
+func (i *stoch) rewind(t time.Time) error {
+	if err := rewindIndicator(i.high, t); err != nil {
+		return err
+	}
+	if err := rewindIndicator(i.low, t); err != nil {
+		return err
+	}
+	i.raw.rewind(t)
+	i.k.rewind(t)
+	i.genvalues.rewind(t)
+	return nil
+}
+
+func (i *stoch) encodeState(e *encoder) {
+	i.raw.encode(e)
+	i.k.encode(e)
+	encodeRing(e, i.genvalues, func(e *encoder, v stochValue) {
+		e.float(v.K)
+		e.float(v.D)
+	})
+}
+
+func (i *stoch) decodeState(d *decoder) {
+	i.raw.decode(d)
+	i.k.decode(d)
+	decodeRing(d, i.genvalues, func(d *decoder) stochValue {
+		return stochValue{
+			K: d.float(),
+			D: d.float(),
+		}
+	})
+}
+
+type naValue struct {
+	Value float64
+	Na    bool
+}
+
+// naWindow keeps the last size values where values can be na. Once SeriesOpts
+// are applied values before them are kept too so the window can be rewound
+type naWindow struct {
+	size   int
+	values *ring[naValue]
+}
+
+func newNaWindow(size int) naWindow {
+	return naWindow{
+		size:   size,
+		values: newRing[naValue](size, 0),
+	}
+}
+
+// before returns true if t is before the last value in the window
+func (w *naWindow) before(t time.Time) bool {
+	return w.values.len() > 0 && t.Before(w.values.lastTime())
+}
+
+// set replaces the last value if t is the same interval, otherwise appends it
+func (w *naWindow) set(t time.Time, v float64, na bool) {
+	if w.size <= 0 {
+		return
+	}
+	w.values.set(t, naValue{
+		Value: v,
+		Na:    na,
+	})
+}
+
+func (w *naWindow) full() bool {
+	return w.size > 0 && w.values.len() >= w.size
+}
+
+// applyOpts keeps values of the window of every interval kept by opts
+func (w *naWindow) applyOpts(opts SeriesOpts) {
+	w.values = windowRing(w.values, w.size, opts)
+}
+
+// reset removes all values
+func (w *naWindow) reset() {
+	w.values.clear()
+}
+
+// rewind removes values of intervals from t onwards
+func (w *naWindow) rewind(t time.Time) {
+	w.values.rewind(t)
+}
+
+func (w *naWindow) encode(e *encoder) {
+	encodeRing(e, w.values, func(e *encoder, v naValue) {
+		e.float(v.Value)
+		e.bool(v.Na)
+	})
+}
+
+func (w *naWindow) decode(d *decoder) {
+	decodeRing(d, w.values, func(d *decoder) naValue {
+		return naValue{
+			Value: d.float(),
+			Na:    d.bool(),
+		}
+	})
+}
+
+// get returns the j-th oldest value in the window
+func (w *naWindow) get(j int) float64 {
+	v, _ := w.values.get(w.values.lastIndex() - w.size + 1 + j)
+	return v.Value
+}
+
+// mean returns the average if the window is full and has no na values
+func (w *naWindow) mean() (float64, bool) {
+	if !w.full() {
+		return 0, false
+	}
+	var sum float64
+	for j := w.values.lastIndex() - w.size + 1; j <= w.values.lastIndex(); j++ {
+		v, _ := w.values.get(j)
+		if v.Na {
+			return 0, false
+		}
+		sum += v.Value
+	}
+	return sum / float64(w.size), true
+}
@@ -0,0 +1,141 @@
+package pine
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// defaultMax is the number of intervals indicators keep until SeriesOpts are
+// applied, e.g. when they are updated without a series
+const defaultMax = 1000
+
+// valueStore keeps values generated by an indicator for up to SeriesOpts.Max
+// intervals. Indicators embed it to implement GetValueForInterval
+type valueStore struct {
+	vals *ring[float64]
+}
+
+// initStore allocates storage for opts. Values are kept if capacity is unchanged
+func (s *valueStore) initStore(opts SeriesOpts) {
+	if s.vals != nil && s.vals.capacity() == opts.Max {
+		return
+	}
+	s.vals = newSeriesRing[float64](opts)
+}
+
+func (s *valueStore) GetValueForInterval(t time.Time) *Interval {
+	v, ok := s.value(t)
+	if !ok {
+		return nil
+	}
+	return &Interval{
+		StartTime: t,
+		Value:     v,
+	}
+}
+
+// value returns generated value of interval t without allocating
+func (s *valueStore) value(t time.Time) (float64, bool) {
+	if s.vals == nil {
+		return 0, false
+	}
+	v, ok := s.vals.getTime(t)
+	if !ok || math.IsNaN(v) {
+		return 0, false
+	}
+	return v, true
+}
+
+// setValue sets generated value of interval t
+func (s *valueStore) setValue(t time.Time, v float64) {
+	if s.vals == nil {
+		s.vals = newRing[float64](defaultMax, 0)
+	}
+	s.vals.set(t, v)
+}
+
+// unsetValue marks interval t as having no value
+func (s *valueStore) unsetValue(t time.Time) {
+	if s.vals == nil {
+		return
+	}
+	if _, ok := s.vals.indexOf(t); ok {
+		s.vals.set(t, math.NaN())
+	}
+}
+
+// resetStore removes all generated values
+func (s *valueStore) resetStore() {
+	if s.vals != nil {
+		s.vals.clear()
+	}
+}
+
+// rewindStore removes values of intervals from t onwards
+func (s *valueStore) rewindStore(t time.Time) {
+	if s.vals != nil {
+		s.vals.rewind(t)
+	}
+}
+
+// lastTime returns the most recent interval a value was set for
+func (s *valueStore) lastTime() time.Time {
+	if s.vals == nil {
+		return time.Time{}
+	}
+	return s.vals.lastTime()
+}
+
+// valuer is implemented by indicators of this package to read values
+// without allocating an Interval
+type valuer interface {
+	value(t time.Time) (float64, bool)
+}
+
+// resetter is implemented by indicators of this package to remove generated
+// values and state so intervals can be generated again from scratch
+type resetter interface {
+	reset()
+}
+
+// resetIndicator resets i and its sources if supported
+func resetIndicator(i Indicator) {
+	if r, ok := i.(resetter); ok {
+		r.reset()
+	}
+}
+
+// rewinder is implemented by indicators of this package keeping the state of
+// every kept interval so late execs generate values again from the amended
+// interval only
+type rewinder interface {
+	// rewind removes values and state of intervals from t onwards
+	rewind(t time.Time) error
+}
+
+// errNoRewind is returned rewinding indicators which do not keep the state of
+// every kept interval
+var errNoRewind = errors.New("indicator cannot be rewound")
+
+// rewindIndicator rewinds i and its sources or returns errNoRewind if one of
+// them is not supported
+func rewindIndicator(i Indicator, t time.Time) error {
+	r, ok := i.(rewinder)
+	if !ok {
+		return errNoRewind
+	}
+	return r.rewind(t)
+}
+
+// valueOf returns value of indicator for interval t
+func valueOf(i Indicator, t time.Time) (float64, bool) {
+	if v, ok := i.(valuer); ok {
+		return v.value(t)
+	}
+	itvl := i.GetValueForInterval(t)
+	if itvl == nil {
+		return 0, false
+	}
+	return itvl.Value, true
+}
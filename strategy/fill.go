@@ -0,0 +1,158 @@
+package strategy
+
+import (
+	"math"
+	"time"
+
+	"github.com/xpt-nl/pine"
+)
+
+// trigger is a price an order fills at once reached
+type trigger struct {
+	price float64
+	// below triggers at prices at or below price rather than at or above
+	below bool
+	seq   int
+	fill  func(price float64, t time.Time)
+}
+
+// reached returns the fill price and the distance moved from cur if price
+// moving from cur to end reaches tg
+func (tg trigger) reached(cur, end float64) (float64, float64, bool) {
+	if tg.below && cur <= tg.price || !tg.below && cur >= tg.price {
+		// beyond the price already like gaps at the open
+		return cur, 0, true
+	}
+	if tg.below && end <= tg.price || !tg.below && end >= tg.price {
+		return tg.price, math.Abs(cur - tg.price), true
+	}
+	return 0, 0, false
+}
+
+// path returns the prices of v in the order they are assumed to be reached.
+// Prices move from the open to the nearer of high and low first
+func path(v pine.OHLCV) []float64 {
+	if v.H-v.O < v.O-v.L {
+		return []float64{v.O, v.H, v.L, v.C}
+	}
+	return []float64{v.O, v.L, v.H, v.C}
+}
+
+// fill fills pending orders with the prices of bar v
+func (st *Strategy) fill(v pine.OHLCV) {
+	st.fillMarket(v.O, v.S)
+	p := path(v)
+	cur := p[0]
+	for _, end := range p[1:] {
+		// fills change the position and so the orders to fill next
+		for {
+			tg, price, ok := st.next(cur, end)
+			if !ok {
+				break
+			}
+			tg.fill(price, v.S)
+			cur = price
+		}
+		cur = end
+	}
+}
+
+// next returns the first trigger reached moving from cur to end with its fill
+// price. Triggers reached at the same price are filled in order of placement
+func (st *Strategy) next(cur, end float64) (trigger, float64, bool) {
+	var best trigger
+	var bestPrice, bestDist float64
+	found := false
+	for _, tg := range st.triggers() {
+		price, dist, ok := tg.reached(cur, end)
+		if !ok {
+			continue
+		}
+		if !found || dist < bestDist || dist == bestDist && tg.seq < best.seq {
+			best, bestPrice, bestDist, found = tg, price, dist, true
+		}
+	}
+	return best, bestPrice, found
+}
+
+// triggers returns the triggers of pending limit, stop and exit orders
+func (st *Strategy) triggers() []trigger {
+	var trs []trigger
+	for _, o := range st.orders {
+		o := o
+		switch {
+		case o.kind == orderExit:
+			for _, tr := range st.open {
+				if !o.closes(tr) {
+					continue
+				}
+				tr := tr
+				close := func(price float64, t time.Time) {
+					st.closeTrade(tr, o.id, price, t)
+				}
+				limit, stop := o.exitPrices(tr)
+				if limit > 0 {
+					trs = append(trs, trigger{limit, tr.Direction == Short, o.seq, close})
+				}
+				if stop > 0 {
+					trs = append(trs, trigger{stop, tr.Direction == Long, o.seq, close})
+				}
+			}
+		case o.stop > 0:
+			// stop-limit entries become limit entries once the stop is reached
+			trs = append(trs, trigger{o.stop, o.dir == Short, o.seq, func(price float64, t time.Time) {
+				if o.limit > 0 {
+					o.stop = 0
+					return
+				}
+				st.enter(o, price, t)
+			}})
+		case o.limit > 0:
+			trs = append(trs, trigger{o.limit, o.dir == Long, o.seq, func(price float64, t time.Time) {
+				st.enter(o, price, t)
+			}})
+		}
+	}
+	return trs
+}
+
+// fillMarket fills pending market orders at price
+func (st *Strategy) fillMarket(price float64, t time.Time) {
+	for _, o := range append([]*order(nil), st.orders...) {
+		if !o.market() {
+			continue
+		}
+		switch o.kind {
+		case orderEntry:
+			st.enter(o, price, t)
+		case orderClose:
+			st.removeOrder(o)
+			st.closeTrades(func(tr *Trade) bool {
+				return tr.EntryID == o.id
+			}, o.id, price, t)
+		case orderCloseAll:
+			st.removeOrder(o)
+			st.closeTrades(func(tr *Trade) bool {
+				return true
+			}, "", price, t)
+		}
+	}
+}
+
+// enter fills entry o at price closing trades in the opposite direction
+func (st *Strategy) enter(o *order, price float64, t time.Time) {
+	st.removeOrder(o)
+	same := 0
+	for _, tr := range st.open {
+		if tr.Direction == o.dir {
+			same++
+		}
+	}
+	if same >= st.opts.Pyramiding {
+		return
+	}
+	st.closeTrades(func(tr *Trade) bool {
+		return tr.Direction != o.dir
+	}, o.id, price, t)
+	st.openTrade(o.id, o.dir, o.qty, price, t)
+}
@@ -0,0 +1,191 @@
+package strategy
+
+import (
+	"errors"
+	"fmt"
+)
+
+type orderKind int
+
+const (
+	orderEntry orderKind = iota
+	orderClose
+	orderCloseAll
+	orderExit
+)
+
+// order is a pending order. Entries without limit and stop and closes are
+// market orders
+type order struct {
+	kind orderKind
+	// id is the entry id of entries and closes and the exit id of exits
+	id    string
+	dir   Direction
+	qty   float64
+	limit float64
+	stop  float64
+	exit  ExitOpts
+	// seq is the order orders are placed in, used to fill orders reached at
+	// the same price in order
+	seq int
+}
+
+func (o *order) market() bool {
+	return o.kind != orderExit && o.limit == 0 && o.stop == 0
+}
+
+// EntryOpts is options of an entry order
+type EntryOpts struct {
+	// Qty is the qty of the entry. Defaults to Opts.DefaultQty
+	Qty float64
+	// Limit is the limit price of limit and stop-limit entries
+	Limit float64
+	// Stop is the stop price of stop and stop-limit entries
+	Stop float64
+}
+
+// ExitOpts is options of an exit order. At least one of the prices must be
+// set. Limit and Profit take profit while Stop and Loss stop the loss of a
+// trade and the first one reached fills
+type ExitOpts struct {
+	// FromEntry is the entry id of the trades the exit closes. The exit
+	// closes all trades if empty
+	FromEntry string
+	// Limit is the take profit price
+	Limit float64
+	// Stop is the stop loss price
+	Stop float64
+	// Profit is the take profit distance from the entry price of each trade
+	// if Limit is not set
+	Profit float64
+	// Loss is the stop loss distance from the entry price of each trade if
+	// Stop is not set
+	Loss float64
+}
+
+// Entry places entry order id like strategy.entry. Entries in the direction
+// opposite to the position close all open trades and reverse it. Entries in
+// the direction of the position are ignored when Opts.Pyramiding trades are
+// open. A pending entry with the same id is replaced
+func (st *Strategy) Entry(id string, dir Direction, opts EntryOpts) error {
+	if opts.Qty < 0 || opts.Limit < 0 || opts.Stop < 0 {
+		return errors.New("qty, limit and stop must not be negative")
+	}
+	if opts.Qty == 0 {
+		opts.Qty = st.opts.DefaultQty
+	}
+	st.place(&order{
+		kind:  orderEntry,
+		id:    id,
+		dir:   dir,
+		qty:   opts.Qty,
+		limit: opts.Limit,
+		stop:  opts.Stop,
+	})
+	return nil
+}
+
+// Close places a market order closing the open trades of entry id like
+// strategy.close
+func (st *Strategy) Close(id string) {
+	st.place(&order{kind: orderClose, id: id})
+}
+
+// CloseAll places a market order closing all open trades like
+// strategy.close_all
+func (st *Strategy) CloseAll() {
+	st.place(&order{kind: orderCloseAll})
+}
+
+// Exit places exit order id with take profit and stop loss prices like
+// strategy.exit. Exits stay pending while trades or entries they close exist
+// and are checked on the bar their entry fills. A pending exit with the same
+// id is replaced
+func (st *Strategy) Exit(id string, opts ExitOpts) error {
+	if opts.Limit < 0 || opts.Stop < 0 || opts.Profit < 0 || opts.Loss < 0 {
+		return errors.New("exit prices must not be negative")
+	}
+	if opts.Limit == 0 && opts.Stop == 0 && opts.Profit == 0 && opts.Loss == 0 {
+		return fmt.Errorf("exit %s has no limit, stop, profit or loss", id)
+	}
+	st.place(&order{kind: orderExit, id: id, exit: opts})
+	return nil
+}
+
+// Cancel cancels pending entries and exits with id like strategy.cancel
+func (st *Strategy) Cancel(id string) {
+	st.removeOrders(func(o *order) bool {
+		return (o.kind == orderEntry || o.kind == orderExit) && o.id == id
+	})
+}
+
+// CancelAll cancels all pending orders like strategy.cancel_all
+func (st *Strategy) CancelAll() {
+	st.orders = nil
+}
+
+// place adds o replacing a pending order of the same kind and id
+func (st *Strategy) place(o *order) {
+	st.seq++
+	o.seq = st.seq
+	st.removeOrders(func(p *order) bool {
+		return p.kind == o.kind && p.id == o.id
+	})
+	st.orders = append(st.orders, o)
+}
+
+func (st *Strategy) removeOrders(fn func(o *order) bool) {
+	// a new slice so fn may read the pending orders
+	orders := make([]*order, 0, len(st.orders))
+	for _, o := range st.orders {
+		if !fn(o) {
+			orders = append(orders, o)
+		}
+	}
+	st.orders = orders
+}
+
+func (st *Strategy) removeOrder(o *order) {
+	st.removeOrders(func(p *order) bool {
+		return p == o
+	})
+}
+
+// dropExits removes exits without open trades or pending entries to close
+func (st *Strategy) dropExits() {
+	st.removeOrders(func(o *order) bool {
+		if o.kind != orderExit {
+			return false
+		}
+		for _, tr := range st.open {
+			if o.closes(tr) {
+				return false
+			}
+		}
+		for _, p := range st.orders {
+			if p.kind == orderEntry && (o.exit.FromEntry == "" || p.id == o.exit.FromEntry) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// closes returns whether exit o closes trade tr
+func (o *order) closes(tr *Trade) bool {
+	return o.exit.FromEntry == "" || o.exit.FromEntry == tr.EntryID
+}
+
+// exitPrices returns the take profit and stop loss prices of exit o for
+// trade tr which are 0 if not set
+func (o *order) exitPrices(tr *Trade) (limit, stop float64) {
+	limit, stop = o.exit.Limit, o.exit.Stop
+	sign := tr.Direction.sign()
+	if limit == 0 && o.exit.Profit > 0 {
+		limit = tr.EntryPrice + sign*o.exit.Profit
+	}
+	if stop == 0 && o.exit.Loss > 0 {
+		stop = tr.EntryPrice - sign*o.exit.Loss
+	}
+	return limit, stop
+}
@@ -0,0 +1,269 @@
+// Package strategy backtests and runs trading strategies on the closed bars
+// of a pine Series like Pine's strategy.* functions.
+//
+// A strategy is called once per closed bar and places entry, close and exit
+// orders. Orders are filled by the bars after the one they are placed on like
+// TradingView's broker emulator: market orders fill at the open, limit and
+// stop orders fill at their price or at the open if it is beyond it, and
+// prices are assumed to move from the open to the nearer of high and low, then
+// to the other one and then to the close
+package strategy
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/xpt-nl/pine"
+)
+
+// Direction is the side of an entry
+type Direction int
+
+const (
+	// Long buys to enter and sells to exit
+	Long Direction = iota
+	// Short sells to enter and buys to exit
+	Short
+)
+
+func (d Direction) String() string {
+	if d == Short {
+		return "short"
+	}
+	return "long"
+}
+
+// sign is 1 for long and -1 for short
+func (d Direction) sign() float64 {
+	if d == Short {
+		return -1
+	}
+	return 1
+}
+
+// Opts is options of a strategy
+type Opts struct {
+	// InitialCapital is the equity before the first trade
+	InitialCapital float64
+	// DefaultQty is the qty of entries without Qty. Defaults to 1
+	DefaultQty float64
+	// Pyramiding is the number of open trades allowed in the same direction.
+	// Defaults to 1 so entries are ignored while a position is open
+	Pyramiding int
+	// CommissionPercent is the commission of every fill as percentage of its
+	// value
+	CommissionPercent float64
+	// ProcessOrdersOnClose fills market orders at the close of the bar they
+	// are placed on rather than at the open of the next bar
+	ProcessOrdersOnClose bool
+}
+
+// Position is the net position of the open trades
+type Position struct {
+	// Size is positive for long and negative for short positions
+	Size float64
+	// AvgPrice is the average entry price of the open trades
+	AvgPrice float64
+}
+
+// Trade is a filled entry and its exit once closed
+type Trade struct {
+	EntryID    string
+	Direction  Direction
+	Qty        float64
+	EntryTime  time.Time
+	EntryPrice float64
+	// ExitID is the id of the exit of closed trades, the entry id of closes
+	// and reversing entries and empty for CloseAll
+	ExitID    string
+	ExitTime  time.Time
+	ExitPrice float64
+	// Commission is the commission of the entry and exit fills
+	Commission float64
+	// Profit is the profit of closed trades net of commission
+	Profit float64
+}
+
+// openProfit returns the profit of an open trade at price net of entry
+// commission
+func (t *Trade) openProfit(price float64) float64 {
+	return (price-t.EntryPrice)*t.Qty*t.Direction.sign() - t.Commission
+}
+
+// Strategy fills the orders placed by a function called on every closed bar
+// of a series
+type Strategy struct {
+	opts   Opts
+	onBar  func(st *Strategy, itvl *pine.Interval)
+	unsub  func()
+	orders []*order
+	seq    int
+	open   []*Trade
+	closed []*Trade
+	// price is the last price open trades are valued at
+	price     float64
+	netProfit float64
+	peak      float64
+	drawdown  float64
+}
+
+// New creates a strategy calling onBar with every bar of s closed from now
+// on. Bars passed to NewSeries are closed already so backtests add their bars
+// with AddOHLCV
+func New(s pine.Series, opts Opts, onBar func(st *Strategy, itvl *pine.Interval)) (*Strategy, error) {
+	var err error
+	if opts.InitialCapital < 0 {
+		err = errors.New("`InitialCapital` must not be negative")
+	} else if opts.DefaultQty < 0 {
+		err = errors.New("`DefaultQty` must not be negative")
+	} else if opts.Pyramiding < 0 {
+		err = errors.New("`Pyramiding` must not be negative")
+	} else if opts.CommissionPercent < 0 {
+		err = errors.New("`CommissionPercent` must not be negative")
+	} else if onBar == nil {
+		err = errors.New("onBar is required")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error validating strategy opts: %w", err)
+	}
+	if opts.DefaultQty == 0 {
+		opts.DefaultQty = 1
+	}
+	if opts.Pyramiding == 0 {
+		opts.Pyramiding = 1
+	}
+	st := &Strategy{
+		opts:  opts,
+		onBar: onBar,
+		peak:  opts.InitialCapital,
+	}
+	st.unsub = s.Subscribe(func(e pine.Event) {
+		if e.Type == pine.EventBarClosed {
+			st.bar(e.Interval)
+		}
+	})
+	return st, nil
+}
+
+// Stop stops filling orders and calling onBar
+func (st *Strategy) Stop() {
+	st.unsub()
+}
+
+// bar fills pending orders with a closed bar and calls onBar
+func (st *Strategy) bar(itvl *pine.Interval) {
+	v := *itvl.OHLCV
+	st.fill(v)
+	st.dropExits()
+	st.price = v.C
+	st.onBar(st, itvl)
+	if st.opts.ProcessOrdersOnClose {
+		st.fillMarket(v.C, v.S)
+	}
+	equity := st.Equity()
+	st.peak = math.Max(st.peak, equity)
+	st.drawdown = math.Max(st.drawdown, st.peak-equity)
+}
+
+// Position returns the net position of the open trades
+func (st *Strategy) Position() Position {
+	var p Position
+	var cost float64
+	for _, t := range st.open {
+		p.Size += t.Qty * t.Direction.sign()
+		cost += t.Qty * t.EntryPrice
+	}
+	if p.Size != 0 {
+		p.AvgPrice = cost / math.Abs(p.Size)
+	}
+	return p
+}
+
+// OpenTrades returns the open trades in order of entry
+func (st *Strategy) OpenTrades() []Trade {
+	return copyTrades(st.open)
+}
+
+// ClosedTrades returns the closed trades in order of exit
+func (st *Strategy) ClosedTrades() []Trade {
+	return copyTrades(st.closed)
+}
+
+func copyTrades(trades []*Trade) []Trade {
+	res := make([]Trade, len(trades))
+	for j, t := range trades {
+		res[j] = *t
+	}
+	return res
+}
+
+// NetProfit returns the profit of closed trades
+func (st *Strategy) NetProfit() float64 {
+	return st.netProfit
+}
+
+// OpenProfit returns the profit of open trades at the close of the last bar
+func (st *Strategy) OpenProfit() float64 {
+	var profit float64
+	for _, t := range st.open {
+		profit += t.openProfit(st.price)
+	}
+	return profit
+}
+
+// Equity returns the initial capital with the profit of closed and open
+// trades
+func (st *Strategy) Equity() float64 {
+	return st.opts.InitialCapital + st.netProfit + st.OpenProfit()
+}
+
+// MaxDrawdown returns the largest drop of equity from a previous high at the
+// close of bars
+func (st *Strategy) MaxDrawdown() float64 {
+	return st.drawdown
+}
+
+// commission returns the commission of a fill
+func (st *Strategy) commission(price, qty float64) float64 {
+	return math.Abs(price*qty) * st.opts.CommissionPercent / 100
+}
+
+// openTrade adds a trade filled by entry id
+func (st *Strategy) openTrade(id string, dir Direction, qty, price float64, t time.Time) {
+	st.open = append(st.open, &Trade{
+		EntryID:    id,
+		Direction:  dir,
+		Qty:        qty,
+		EntryTime:  t,
+		EntryPrice: price,
+		Commission: st.commission(price, qty),
+	})
+}
+
+// closeTrade closes open trade tr by exit id
+func (st *Strategy) closeTrade(tr *Trade, id string, price float64, t time.Time) {
+	tr.ExitID = id
+	tr.ExitTime = t
+	tr.ExitPrice = price
+	tr.Commission += st.commission(price, tr.Qty)
+	tr.Profit = (price-tr.EntryPrice)*tr.Qty*tr.Direction.sign() - tr.Commission
+	st.netProfit += tr.Profit
+	st.closed = append(st.closed, tr)
+	for j, o := range st.open {
+		if o == tr {
+			st.open = append(st.open[:j], st.open[j+1:]...)
+			break
+		}
+	}
+}
+
+// closeTrades closes the open trades matching fn by exit id
+func (st *Strategy) closeTrades(fn func(tr *Trade) bool, id string, price float64, t time.Time) {
+	for _, tr := range append([]*Trade(nil), st.open...) {
+		if fn(tr) {
+			st.closeTrade(tr, id, price, t)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package pine_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/xpt-nl/pine"
+)
+
+func TestATR(t *testing.T) {
+	itvl := 300
+	opts := SeriesOpts{
+		Interval: itvl,
+		Max:      100,
+	}
+	candles := []OHLCV{
+		{H: 53.0, L: 51.9, C: 52.22},
+		{H: 53.2, L: 52.1, C: 52.78},
+		{H: 53.5, L: 52.6, C: 53.02},
+		{H: 54.0, L: 53.0, C: 53.67},
+		{H: 53.9, L: 53.1, C: 53.67},
+		{H: 54.1, L: 53.3, C: 53.74},
+		{H: 53.8, L: 53.2, C: 53.45},
+		{H: 53.9, L: 53.3, C: 53.72},
+		{H: 53.6, L: 53.0, C: 53.39},
+		{H: 52.9, L: 52.3, C: 52.51},
+		{H: 52.6, L: 51.9, C: 52.32},
+		{H: 51.9, L: 51.2, C: 51.45},
+	}
+	io := []*float64{
+		nil,
+		nil,
+		nil,
+		fptr(1.025),
+		fptr(0.96875),
+		fptr(0.926563),
+		fptr(0.844922),
+		fptr(0.783691),
+		fptr(0.767769),
+		fptr(0.848326),
+		fptr(0.811245),
+		fptr(0.888434),
+	}
+	now := time.Now()
+	for idx := range candles {
+		candles[idx].S = now.Add(time.Duration(idx*itvl) * time.Second)
+	}
+	s, err := NewSeries(candles, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := "atr"
+	if err := s.AddIndicator(name, NewATR(4)); err != nil {
+		t.Fatal(err)
+	}
+	for idx, exp := range io {
+		v := s.GetValueForInterval(candles[idx].S)
+		if v == nil {
+			t.Fatalf("interval should not be nil at idx: %d", idx)
+		}
+		if err := compareValue(exp, v.Indicators[name], 0.000001); err != nil {
+			t.Errorf("idx %d: %+v", idx, err)
+		}
+	}
+
+	// revising the last interval smooths from the previous interval
+	last := candles[len(candles)-1].S.Add(time.Duration(itvl) * time.Second)
+	for _, px := range []float64{52, 50} {
+		if err := s.AddExec(TPQ{Timestamp: last, Px: px, Qty: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	v := s.GetValueForInterval(last)
+	if err := compareValue(fptr(1.166325), v.Indicators[name], 0.000001); err != nil {
+		t.Errorf("after revision: %+v", err)
+	}
+}
+
+func TestATRApplyOpts(t *testing.T) {
+	s, err := NewSeries(nil, SeriesOpts{Interval: 300, Max: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddIndicator("atr", NewATR(4)); err == nil {
+		t.Fatal("expected error adding ATR with lookback larger than max")
+	}
+}
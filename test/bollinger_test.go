@@ -0,0 +1,89 @@
+package pine_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/xpt-nl/pine"
+)
+
+func TestBollingerBands(t *testing.T) {
+	itvl := 300
+	opts := SeriesOpts{
+		Interval: itvl,
+		Max:      100,
+	}
+	candles := []OHLCV{
+		{C: 52.22},
+		{C: 52.78},
+		{C: 53.02},
+		{C: 53.67},
+		{C: 53.67},
+		{C: 53.74},
+		{C: 53.45},
+		{C: 53.72},
+		{C: 53.39},
+		{C: 52.51},
+		{C: 52.32},
+		{C: 51.45},
+	}
+	io := [][]*float64{
+		nil,
+		nil,
+		nil,
+		{fptr(53.962728), fptr(52.9225), fptr(51.882272), fptr(0.859296), fptr(0.039311)},
+		{fptr(54.07348), fptr(53.285), fptr(52.49652), fptr(0.744141), fptr(0.029595)},
+		{fptr(54.110918), fptr(53.525), fptr(52.939082), fptr(0.683473), fptr(0.021893)},
+		{fptr(53.850846), fptr(53.6325), fptr(53.414154), fptr(0.082085), fptr(0.008142)},
+		{fptr(53.875868), fptr(53.645), fptr(53.414132), fptr(0.662431), fptr(0.008607)},
+		{fptr(53.888209), fptr(53.575), fptr(53.261791), fptr(0.20467), fptr(0.011692)},
+		{fptr(54.176827), fptr(53.2675), fptr(52.358173), fptr(0.083483), fptr(0.034142)},
+		{fptr(54.156367), fptr(52.985), fptr(51.813633), fptr(0.216144), fptr(0.044215)},
+		{fptr(53.79586), fptr(52.4175), fptr(51.03914), fptr(0.149039), fptr(0.052592)},
+	}
+	now := time.Now()
+	for idx := range candles {
+		candles[idx].S = now.Add(time.Duration(idx*itvl) * time.Second)
+	}
+	s, err := NewSeries(candles, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bb := NewBollingerBands(NewOHLCProp(OHLCPropClose), 4, 2)
+	names := []string{"upper", "basis", "lower", "percentb", "bandwidth"}
+	for idx, ind := range []Indicator{bb.Upper, bb.Basis, bb.Lower, bb.PercentB, bb.Bandwidth} {
+		if err := s.AddIndicator(names[idx], ind); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for idx, o := range io {
+		v := s.GetValueForInterval(candles[idx].S)
+		if v == nil {
+			t.Fatalf("interval should not be nil at idx: %d", idx)
+		}
+		for j, name := range names {
+			var exp *float64
+			if o != nil {
+				exp = o[j]
+			}
+			if err := compareValue(exp, v.Indicators[name], 0.000001); err != nil {
+				t.Errorf("%s at idx %d: %+v", name, idx, err)
+			}
+		}
+	}
+}
+
+func TestBollingerBandsWithoutSeries(t *testing.T) {
+	now := time.Now()
+	bb := NewBollingerBands(NewOHLCProp(OHLCPropClose), 2, 2)
+	for idx, c := range []float64{10, 12} {
+		if err := bb.Basis.Update(OHLCV{C: c, S: now.Add(time.Duration(idx) * time.Minute)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	res := bb.Basis.GetValueForInterval(now.Add(time.Minute))
+	if res == nil || res.Value != 11 {
+		t.Errorf("expected 11 but got %+v", res)
+	}
+}
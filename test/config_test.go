@@ -0,0 +1,284 @@
+package pine_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	. "github.com/xpt-nl/pine"
+	"github.com/xpt-nl/pine/config"
+)
+
+func TestConfigApply(t *testing.T) {
+	jsonDoc := `{"indicators": [
+	{"name": "basis", "type": "sma", "params": {"source": "close", "length": 5}},
+	{"name": "upper", "type": "arith", "params": {"op": "add", "a": "basis", "b": 10}},
+	{"name": "fast", "type": "ema", "params": {"source": "hl2", "length": 3}, "hidden": true},
+	{"name": "spread", "type": "arith", "params": {"op": "sub", "a": "fast", "b": {"type": "wma", "params": {"source": "close", "length": 4}}}},
+	{"name": "macd", "type": "macd", "params": {"source": "close", "fast": 3, "slow": 6, "signal": 2}},
+	{"name": "signal", "type": "ema", "params": {"source": "macd.signal", "length": 2}},
+	{"name": "mom", "type": "change", "params": {"source": "close", "length": 2}},
+	{"name": "k", "type": "stoch", "params": {"k_length": 4}}
+]}`
+	yamlDoc := `
+indicators:
+  - name: basis
+    type: sma
+    params: {source: close, length: 5}
+  - name: upper
+    type: arith
+    params: {op: add, a: basis, b: 10}
+  - name: fast
+    type: ema
+    params: {source: hl2, length: 3}
+    hidden: true
+  - name: spread
+    type: arith
+    params:
+      op: sub
+      a: fast
+      b: {type: wma, params: {source: close, length: 4}}
+  - name: macd
+    type: macd
+    params: {source: close, fast: 3, slow: 6, signal: 2}
+  - name: signal
+    type: ema
+    params: {source: macd.signal, length: 2}
+  - name: mom
+    type: change
+    params: {source: close, length: 2}
+  - name: k
+    type: stoch
+    params: {k_length: 4}
+`
+	close := NewOHLCProp(OHLCPropClose)
+	basis := NewSMA(close, 5)
+	macd := NewMACD(close, 3, 6, 2)
+	stoch := NewStoch(4, 1, 3)
+	expected := map[string]Indicator{
+		"basis":          basis,
+		"upper":          NewArithmetic(ArithmeticAddition, basis, NewConstant(10), ArithmeticOpts{}),
+		"spread":         NewArithmetic(ArithmeticSubtraction, NewEMA(NewOHLCProp(OHLCPropHL2), 3), NewWMA(close, 4), ArithmeticOpts{}),
+		"macd.macd":      macd.MACD,
+		"macd.signal":    macd.Signal,
+		"macd.histogram": macd.Histogram,
+		"signal":         NewEMA(macd.Signal, 2),
+		"mom":            NewChange(close, 2, &ChangeOpts{DiffType: ChangeDiffTypeDiff}),
+		"k.k":            stoch.K,
+		"k.d":            stoch.D,
+	}
+
+	start := time.Date(2022, 1, 3, 10, 0, 0, 0, time.UTC)
+	candles := scriptCandles(start, 30)
+	opts := SeriesOpts{Interval: 60, Max: 50}
+	manual, err := NewSeries(candles, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, ind := range expected {
+		if err := manual.AddIndicator(name, ind); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := config.NewRegistry()
+	for _, doc := range []struct {
+		name   string
+		decode func([]byte) (*config.Config, error)
+		data   string
+	}{
+		{"json", config.DecodeJSON, jsonDoc},
+		{"yaml", config.DecodeYAML, yamlDoc},
+	} {
+		cfg, err := doc.decode([]byte(doc.data))
+		if err != nil {
+			t.Fatalf("%s: %v", doc.name, err)
+		}
+		inds, err := r.Build(cfg)
+		if err != nil {
+			t.Fatalf("%s: %v", doc.name, err)
+		}
+		if len(inds) != len(expected) {
+			t.Errorf("%s: expected %d indicators but got %d", doc.name, len(expected), len(inds))
+		}
+		s, err := NewSeries(candles, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Apply(cfg, s); err != nil {
+			t.Fatalf("%s: %v", doc.name, err)
+		}
+		for _, c := range candles {
+			exp := manual.GetValueForInterval(c.S)
+			act := s.GetValueForInterval(c.S)
+			if _, ok := act.Indicators["fast"]; ok {
+				t.Fatalf("%s: expected hidden indicator not to be added", doc.name)
+			}
+			for name := range expected {
+				if err := compareValue(exp.Indicators[name], act.Indicators[name], 1e-9); err != nil {
+					t.Errorf("%s: %s at %v: %v", doc.name, name, c.S, err)
+				}
+			}
+		}
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		err  error
+	}{
+		{"unknown type", `{"indicators": [{"name": "a", "type": "nope"}]}`, config.ErrUnknownType},
+		{"unknown reference", `{"indicators": [{"name": "a", "type": "sma", "params": {"source": "b", "length": 2}}]}`, config.ErrUnknownReference},
+		{"unknown output", `{"indicators": [
+			{"name": "m", "type": "macd", "params": {"source": "close", "fast": 3, "slow": 6, "signal": 2}},
+			{"name": "a", "type": "sma", "params": {"source": "m.upper", "length": 2}}]}`, config.ErrUnknownReference},
+		{"output required", `{"indicators": [
+			{"name": "m", "type": "macd", "params": {"source": "close", "fast": 3, "slow": 6, "signal": 2}},
+			{"name": "a", "type": "sma", "params": {"source": "m", "length": 2}}]}`, config.ErrUnknownReference},
+		{"cycle", `{"indicators": [
+			{"name": "a", "type": "sma", "params": {"source": "b", "length": 2}},
+			{"name": "b", "type": "ema", "params": {"source": "a", "length": 2}}]}`, config.ErrCycle},
+		{"self reference", `{"indicators": [{"name": "a", "type": "sma", "params": {"source": "a", "length": 2}}]}`, config.ErrCycle},
+		{"missing param", `{"indicators": [{"name": "a", "type": "sma", "params": {"source": "close"}}]}`, config.ErrInvalidParam},
+		{"unknown param", `{"indicators": [{"name": "a", "type": "sma", "params": {"source": "close", "length": 2, "len": 3}}]}`, config.ErrInvalidParam},
+		{"wrong type", `{"indicators": [{"name": "a", "type": "sma", "params": {"source": "close", "length": "2"}}]}`, config.ErrInvalidParam},
+		{"fractional length", `{"indicators": [{"name": "a", "type": "sma", "params": {"source": "close", "length": 2.5}}]}`, config.ErrInvalidParam},
+		{"length overflow", `{"indicators": [{"name": "a", "type": "sma", "params": {"source": "close", "length": 1e19}}]}`, config.ErrInvalidParam},
+		{"non-positive length", `{"indicators": [{"name": "a", "type": "sma", "params": {"source": "close", "length": 0}}]}`, config.ErrInvalidParam},
+		{"enum", `{"indicators": [{"name": "a", "type": "arith", "params": {"op": "pow", "a": "close", "b": 2}}]}`, config.ErrInvalidParam},
+		{"not an indicator", `{"indicators": [{"name": "a", "type": "sma", "params": {"source": true, "length": 2}}]}`, config.ErrInvalidParam},
+		{"inline with outputs", `{"indicators": [{"name": "a", "type": "sma", "params": {"length": 2,
+			"source": {"type": "macd", "params": {"source": "close", "fast": 3, "slow": 6, "signal": 2}}}}]}`, config.ErrInvalidParam},
+		{"duplicate name", `{"indicators": [{"name": "a", "type": "tr"}, {"name": "a", "type": "tr"}]}`, nil},
+		{"reserved name", `{"indicators": [{"name": "close", "type": "tr"}]}`, nil},
+		{"dotted name", `{"indicators": [{"name": "a.b", "type": "tr"}]}`, nil},
+	}
+	r := config.NewRegistry()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg, err := config.DecodeJSON([]byte(test.doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = r.Build(cfg)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if test.err != nil && !errors.Is(err, test.err) {
+				t.Errorf("expected %v but got %v", test.err, err)
+			}
+		})
+	}
+
+	if _, err := config.DecodeYAML([]byte("indicators:\n  - name: a\n    kind: sma\n")); err == nil {
+		t.Error("expected error for unknown field")
+	}
+}
+
+func TestConfigRegister(t *testing.T) {
+	r := config.NewRegistry()
+	midpoint := config.Constructor{
+		Params: []config.Param{
+			{Name: "length", Kind: config.ParamLength, Optional: true, Default: 3},
+		},
+		New: func(a config.Args) []Indicator {
+			l := a.Int("length")
+			return []Indicator{NewArithmetic(ArithmeticAddition,
+				NewHighest(NewOHLCProp(OHLCPropHigh), l),
+				NewLowest(NewOHLCProp(OHLCPropLow), l),
+				ArithmeticOpts{})}
+		},
+	}
+	if err := r.Register("midpoint", midpoint); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register("midpoint", midpoint); err == nil {
+		t.Error("expected error registering a type twice")
+	}
+	if err := r.Register("sma", midpoint); err == nil {
+		t.Error("expected error registering a builtin type")
+	}
+	invalid := midpoint
+	invalid.Params = []config.Param{{Name: "length", Kind: config.ParamLength, Optional: true, Default: -1}}
+	if err := r.Register("invalid", invalid); !errors.Is(err, config.ErrInvalidParam) {
+		t.Errorf("expected invalid default error but got %v", err)
+	}
+	invalid.Params = []config.Param{{Name: "length", Kind: config.ParamLength, Optional: true}}
+	if err := r.Register("invalid", invalid); err == nil {
+		t.Error("expected error registering an optional param without default")
+	}
+	invalid.Params = []config.Param{{Name: "source", Kind: config.ParamIndicator, Optional: true, Default: "close"}}
+	if err := r.Register("source", invalid); err != nil {
+		t.Errorf("expected indicator default to be accepted but got %v", err)
+	}
+
+	cfg, err := config.DecodeYAML([]byte("indicators:\n  - name: mid\n    type: midpoint\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2022, 1, 3, 10, 0, 0, 0, time.UTC)
+	candles := scriptCandles(start, 10)
+	s, err := NewSeries(candles, SeriesOpts{Interval: 60, Max: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Apply(cfg, s); err != nil {
+		t.Fatal(err)
+	}
+	last := candles[len(candles)-1]
+	exp := candles[7].H
+	for _, c := range candles[7:] {
+		exp = math.Max(exp, c.H)
+	}
+	low := candles[7].L
+	for _, c := range candles[7:] {
+		low = math.Min(low, c.L)
+	}
+	v := s.GetValueForInterval(last.S).Indicators["mid"]
+	if v == nil || *v != exp+low {
+		t.Errorf("expected %v but got %v", exp+low, v)
+	}
+
+	// constructors returning the wrong number of indicators are rejected
+	bands := config.Constructor{
+		Outputs: []string{"upper", "lower"},
+		New: func(a config.Args) []Indicator {
+			return []Indicator{NewOHLCProp(OHLCPropHigh)}
+		},
+	}
+	if err := r.Register("bands", bands); err != nil {
+		t.Fatal(err)
+	}
+	empty := config.Constructor{
+		New: func(a config.Args) []Indicator {
+			return nil
+		},
+	}
+	if err := r.Register("empty", empty); err != nil {
+		t.Fatal(err)
+	}
+	for _, typ := range []string{"bands", "empty"} {
+		cfg, err := config.DecodeYAML([]byte("indicators:\n  - name: x\n    type: " + typ + "\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.Build(cfg); err == nil {
+			t.Errorf("expected error building %s", typ)
+		}
+	}
+
+	types := r.Types()
+	found := false
+	for j, name := range types {
+		if j > 0 && types[j-1] >= name {
+			t.Errorf("expected types in order but got %v", types)
+		}
+		found = found || name == "midpoint"
+	}
+	if !found {
+		t.Error("expected midpoint in types")
+	}
+}
@@ -0,0 +1,127 @@
+package pine_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/xpt-nl/pine"
+)
+
+func TestCross(t *testing.T) {
+	itvl := 300
+	opts := SeriesOpts{
+		Interval: itvl,
+		Max:      100,
+	}
+	candles := []OHLCV{
+		{C: 9},
+		{C: 11},
+		{C: 12},
+		{C: 10},
+		{C: 9},
+		{C: 10},
+		{C: 11},
+	}
+	io := []struct {
+		crossover  float64
+		crossunder float64
+		cross      float64
+	}{
+		{0, 0, 0},
+		{1, 0, 1},
+		{0, 0, 0},
+		{0, 0, 0},
+		{0, 1, 1},
+		{0, 0, 0},
+		{1, 0, 1},
+	}
+	now := time.Now()
+	for idx := range candles {
+		candles[idx].S = now.Add(time.Duration(idx*itvl) * time.Second)
+	}
+	s, err := NewSeries(candles, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	close := NewOHLCProp(OHLCPropClose)
+	level := NewConstant(10)
+	if err := s.AddIndicator("crossover", NewCrossover(close, level)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddIndicator("crossunder", NewCrossunder(close, level)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddIndicator("cross", NewCross(close, level)); err != nil {
+		t.Fatal(err)
+	}
+	for idx, o := range io {
+		v := s.GetValueForInterval(candles[idx].S)
+		if v == nil {
+			t.Fatalf("interval should not be nil at idx: %d", idx)
+		}
+		expected := map[string]float64{
+			"crossover":  o.crossover,
+			"crossunder": o.crossunder,
+			"cross":      o.cross,
+		}
+		for name, exp := range expected {
+			if err := compareValue(&exp, v.Indicators[name], 0); err != nil {
+				t.Errorf("%s at idx %d: %+v", name, idx, err)
+			}
+		}
+	}
+
+	// crossing under intrabar and revising back above removes the cross
+	last := candles[len(candles)-1].S.Add(time.Duration(itvl) * time.Second)
+	execs := []struct {
+		px         float64
+		crossunder float64
+	}{
+		{px: 9, crossunder: 1},
+		{px: 12, crossunder: 0},
+	}
+	for idx, e := range execs {
+		if err := s.AddExec(TPQ{Timestamp: last, Px: e.px, Qty: 1}); err != nil {
+			t.Fatal(err)
+		}
+		v := s.GetValueForInterval(last)
+		if err := compareValue(&e.crossunder, v.Indicators["crossunder"], 0); err != nil {
+			t.Errorf("crossunder at exec idx %d: %+v", idx, err)
+		}
+		if err := compareValue(fptr(0), v.Indicators["crossover"], 0); err != nil {
+			t.Errorf("crossover at exec idx %d: %+v", idx, err)
+		}
+	}
+}
+
+func TestCrossMissingPrevious(t *testing.T) {
+	itvl := 300
+	opts := SeriesOpts{
+		Interval: itvl,
+		Max:      100,
+	}
+	candles := []OHLCV{
+		{C: 9},
+		{C: 12},
+		{C: 13},
+	}
+	now := time.Now()
+	for idx := range candles {
+		candles[idx].S = now.Add(time.Duration(idx*itvl) * time.Second)
+	}
+	s, err := NewSeries(candles, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// sma has no value on the first two intervals so no cross can be detected
+	close := NewOHLCProp(OHLCPropClose)
+	if err := s.AddIndicator("crossover", NewCrossover(NewSMA(close, 2), NewConstant(10))); err != nil {
+		t.Fatal(err)
+	}
+	for idx := range candles {
+		v := s.GetValueForInterval(candles[idx].S)
+		if err := compareValue(fptr(0), v.Indicators["crossover"], 0); err != nil {
+			t.Errorf("idx %d: %+v", idx, err)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package pine_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/xpt-nl/pine"
+)
+
+func TestHighestLowest(t *testing.T) {
+	itvl := 300
+	opts := SeriesOpts{
+		Interval: itvl,
+		Max:      100,
+	}
+	candles := []OHLCV{
+		{C: 5},
+		{C: 3},
+		{C: 4},
+		{C: 8},
+		{C: 8},
+		{C: 2},
+		{C: 1},
+		{C: 6},
+		{C: 7},
+		{C: 7},
+		{C: 3},
+		{C: 9},
+	}
+	io := [][]float64{
+		nil,
+		nil,
+		{5, 3, -2, -1},
+		{8, 3, 0, -2},
+		{8, 4, 0, -2},
+		{8, 2, -1, 0},
+		{8, 1, -2, 0},
+		{6, 1, 0, -1},
+		{7, 1, 0, -2},
+		{7, 6, 0, -2},
+		{7, 3, -1, 0},
+		{9, 3, 0, -1},
+	}
+	now := time.Now()
+	for idx := range candles {
+		candles[idx].S = now.Add(time.Duration(idx*itvl) * time.Second)
+	}
+	s, err := NewSeries(candles, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	close := NewOHLCProp(OHLCPropClose)
+	names := []string{"highest", "lowest", "highestbars", "lowestbars"}
+	inds := []Indicator{
+		NewHighest(close, 3),
+		NewLowest(close, 3),
+		NewHighestBars(close, 3),
+		NewLowestBars(close, 3),
+	}
+	for idx, ind := range inds {
+		if err := s.AddIndicator(names[idx], ind); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for idx, o := range io {
+		v := s.GetValueForInterval(candles[idx].S)
+		if v == nil {
+			t.Fatalf("interval should not be nil at idx: %d", idx)
+		}
+		for j, name := range names {
+			var exp *float64
+			if o != nil {
+				exp = &o[j]
+			}
+			if err := compareValue(exp, v.Indicators[name], 0); err != nil {
+				t.Errorf("%s at idx %d: %+v", name, idx, err)
+			}
+		}
+	}
+
+	// a new high that is revised lower must bring back the previous high
+	last := candles[len(candles)-1].S.Add(time.Duration(itvl) * time.Second)
+	execs := []struct {
+		px      float64
+		highest float64
+		bars    float64
+	}{
+		{px: 10, highest: 10, bars: 0},
+		{px: 4, highest: 9, bars: -1},
+	}
+	for idx, e := range execs {
+		if err := s.AddExec(TPQ{Timestamp: last, Px: e.px, Qty: 1}); err != nil {
+			t.Fatal(err)
+		}
+		v := s.GetValueForInterval(last)
+		if err := compareValue(&e.highest, v.Indicators["highest"], 0); err != nil {
+			t.Errorf("highest at exec idx %d: %+v", idx, err)
+		}
+		if err := compareValue(&e.bars, v.Indicators["highestbars"], 0); err != nil {
+			t.Errorf("highestbars at exec idx %d: %+v", idx, err)
+		}
+	}
+}
+
+func TestHighestApplyOpts(t *testing.T) {
+	s, err := NewSeries(nil, SeriesOpts{Interval: 300, Max: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddIndicator("highest", NewHighest(NewOHLCProp(OHLCPropHigh), 4)); err == nil {
+		t.Fatal("expected error adding Highest with lookback larger than max")
+	}
+}
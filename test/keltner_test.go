@@ -0,0 +1,101 @@
+package pine_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/xpt-nl/pine"
+)
+
+func TestKeltnerChannels(t *testing.T) {
+	itvl := 300
+	opts := SeriesOpts{
+		Interval: itvl,
+		Max:      100,
+	}
+	candles := []OHLCV{
+		{H: 53.0, L: 51.9, C: 52.22},
+		{H: 53.2, L: 52.1, C: 52.78},
+		{H: 53.5, L: 52.6, C: 53.02},
+		{H: 54.0, L: 53.0, C: 53.67},
+		{H: 53.9, L: 53.1, C: 53.67},
+		{H: 54.1, L: 53.3, C: 53.74},
+		{H: 53.8, L: 53.2, C: 53.45},
+		{H: 53.9, L: 53.3, C: 53.72},
+		{H: 53.6, L: 53.0, C: 53.39},
+		{H: 52.9, L: 52.3, C: 52.51},
+		{H: 52.6, L: 51.9, C: 52.32},
+		{H: 51.9, L: 51.2, C: 51.45},
+	}
+	io := []struct {
+		useTrueRange bool
+		output       [][]float64
+	}{
+		{
+			useTrueRange: false,
+			output: [][]float64{
+				nil,
+				nil,
+				nil,
+				{54.46, 52.9225, 51.385},
+				{54.624, 53.2215, 51.819},
+				{54.7504, 53.4289, 52.1074},
+				{54.59024, 53.43734, 52.28444},
+				{54.602144, 53.550404, 52.498664},
+				{54.477286, 53.486242, 52.495198},
+				{54.050372, 53.095745, 52.141119},
+				{53.778223, 52.785447, 51.792671},
+				{53.266934, 52.251268, 51.235603},
+			},
+		},
+		{
+			useTrueRange: true,
+			output: [][]float64{
+				nil,
+				nil,
+				nil,
+				nil,
+				{54.6465, 53.2215, 51.7965},
+				{54.7639, 53.4289, 52.0939},
+				{54.59834, 53.43734, 52.27634},
+				{54.607004, 53.550404, 52.493804},
+				{54.552202, 53.486242, 52.420282},
+				{54.389321, 53.095745, 51.802169},
+				{53.981593, 52.785447, 51.589302},
+				{53.640956, 52.251268, 50.861581},
+			},
+		},
+	}
+	now := time.Now()
+	for idx := range candles {
+		candles[idx].S = now.Add(time.Duration(idx*itvl) * time.Second)
+	}
+	names := []string{"upper", "basis", "lower"}
+	for _, o := range io {
+		s, err := NewSeries(candles, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		kc := NewKeltnerChannels(NewOHLCProp(OHLCPropClose), 4, 1.5, o.useTrueRange)
+		for idx, ind := range []Indicator{kc.Upper, kc.Basis, kc.Lower} {
+			if err := s.AddIndicator(names[idx], ind); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for idx, exp := range o.output {
+			v := s.GetValueForInterval(candles[idx].S)
+			if v == nil {
+				t.Fatalf("interval should not be nil at idx: %d", idx)
+			}
+			for j, name := range names {
+				var e *float64
+				if exp != nil {
+					e = &exp[j]
+				}
+				if err := compareValue(e, v.Indicators[name], 0.000001); err != nil {
+					t.Errorf("%s at idx %d with true range %t: %+v", name, idx, o.useTrueRange, err)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package pine_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/xpt-nl/pine"
+)
+
+func TestMACD(t *testing.T) {
+	itvl := 300
+	opts := SeriesOpts{
+		Interval: itvl,
+		Max:      100,
+	}
+	candles := []OHLCV{
+		{C: 52.22},
+		{C: 52.78},
+		{C: 53.02},
+		{C: 53.67},
+		{C: 53.67},
+		{C: 53.74},
+		{C: 53.45},
+		{C: 53.72},
+		{C: 53.39},
+		{C: 52.51},
+		{C: 52.32},
+		{C: 51.45},
+	}
+	io := []struct {
+		macd   *float64
+		signal *float64
+		hist   *float64
+	}{
+		{},
+		{},
+		{},
+		{},
+		{macd: fptr(0.348833)},
+		{macd: fptr(0.28575), signal: fptr(0.317292), hist: fptr(-0.031542)},
+		{macd: fptr(0.168764), signal: fptr(0.218273), hist: fptr(-0.049509)},
+		{macd: fptr(0.146641), signal: fptr(0.170519), hist: fptr(-0.023877)},
+		{macd: fptr(0.059827), signal: fptr(0.096724), hist: fptr(-0.036897)},
+		{macd: fptr(-0.125749), signal: fptr(-0.051591), hist: fptr(-0.074158)},
+		{macd: fptr(-0.198316), signal: fptr(-0.149408), hist: fptr(-0.048908)},
+		{macd: fptr(-0.334453), signal: fptr(-0.272771), hist: fptr(-0.061682)},
+	}
+	now := time.Now()
+	for idx := range candles {
+		candles[idx].S = now.Add(time.Duration(idx*itvl) * time.Second)
+	}
+	s, err := NewSeries(candles, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMACD(NewOHLCProp(OHLCPropClose), 3, 5, 2)
+	if err := s.AddIndicator("macd", m.MACD); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddIndicator("signal", m.Signal); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddIndicator("hist", m.Histogram); err != nil {
+		t.Fatal(err)
+	}
+
+	for idx, o := range io {
+		v := s.GetValueForInterval(candles[idx].S)
+		if v == nil {
+			t.Fatalf("interval should not be nil at idx: %d", idx)
+		}
+		expected := map[string]*float64{
+			"macd":   o.macd,
+			"signal": o.signal,
+			"hist":   o.hist,
+		}
+		for name, exp := range expected {
+			if err := compareValue(exp, v.Indicators[name], 0.000001); err != nil {
+				t.Errorf("%s at idx %d: %+v", name, idx, err)
+			}
+		}
+	}
+
+	// new interval and its revision
+	last := candles[len(candles)-1].S.Add(time.Duration(itvl) * time.Second)
+	for _, px := range []float64{52, 51} {
+		if err := s.AddExec(TPQ{Timestamp: last, Px: px, Qty: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	v := s.GetValueForInterval(last)
+	expected := map[string]*float64{
+		"macd":   fptr(-0.399089),
+		"signal": fptr(-0.356983),
+		"hist":   fptr(-0.042106),
+	}
+	for name, exp := range expected {
+		if err := compareValue(exp, v.Indicators[name], 0.000001); err != nil {
+			t.Errorf("%s after revision: %+v", name, err)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package pine_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/xpt-nl/pine"
+)
+
+func movingAverageCandles(itvl int, now time.Time) []OHLCV {
+	closes := []float64{52.22, 52.78, 53.02, 53.67, 53.67, 53.74, 53.45, 53.72, 53.39, 52.51, 52.32, 51.45, 51.60, 52.43, 52.47, 52.91, 52.07, 53.12, 52.77, 52.73}
+	volumes := []float64{100, 120, 90, 150, 80, 60, 70, 110, 130, 90, 100, 140, 120, 80, 95, 105, 115, 85, 70, 100}
+	candles := make([]OHLCV, len(closes))
+	for idx := range closes {
+		candles[idx] = OHLCV{
+			O: closes[idx],
+			H: closes[idx],
+			L: closes[idx],
+			C: closes[idx],
+			V: volumes[idx],
+			S: now.Add(time.Duration(idx*itvl) * time.Second),
+		}
+	}
+	return candles
+}
+
+func TestMovingAverages(t *testing.T) {
+	itvl := 300
+	opts := SeriesOpts{
+		Interval: itvl,
+		Max:      100,
+	}
+	now := time.Now()
+	candles := movingAverageCandles(itvl, now)
+	close := NewOHLCProp(OHLCPropClose)
+	io := []struct {
+		name      string
+		indicator Indicator
+		output    []*float64
+	}{
+		{
+			name:      "wma",
+			indicator: NewWMA(close, 4),
+			output:    []*float64{nil, nil, nil, fptr(53.152), fptr(53.451), fptr(53.633), fptr(53.603), fptr(53.638), fptr(53.536), fptr(53.11), fptr(52.731), fptr(52.117), fptr(51.79), fptr(51.974), fptr(52.182), fptr(52.551), fptr(52.438), fptr(52.698), fptr(52.749), fptr(52.754)},
+		},
+		{
+			name:      "rma",
+			indicator: NewRMA(close, 4),
+			output:    []*float64{nil, nil, nil, fptr(52.9225), fptr(53.109375), fptr(53.267031), fptr(53.312773), fptr(53.41458), fptr(53.408435), fptr(53.183826), fptr(52.96787), fptr(52.588402), fptr(52.341302), fptr(52.363476), fptr(52.390107), fptr(52.52008), fptr(52.40756), fptr(52.58567), fptr(52.631753), fptr(52.656315)},
+		},
+		{
+			name:      "hma",
+			indicator: NewHMA(close, 4),
+			output:    []*float64{nil, nil, nil, nil, fptr(53.844222), fptr(53.829889), fptr(53.593667), fptr(53.578111), fptr(53.516667), fptr(52.819111), fptr(52.189333), fptr(51.587222), fptr(51.327667), fptr(51.991778), fptr(52.598444), fptr(52.894222), fptr(52.499889), fptr(52.648667), fptr(52.963556), fptr(52.829889)},
+		},
+		{
+			name:      "vwma",
+			indicator: NewVWMA(close, 4),
+			output:    []*float64{nil, nil, nil, fptr(52.995435), fptr(53.294318), fptr(53.527105), fptr(53.638889), fptr(53.652188), fptr(53.556216), fptr(53.29325), fptr(53.041395), fptr(52.394783), fptr(51.895333), fptr(51.866818), fptr(51.894368), fptr(52.3165), fptr(52.462405), fptr(52.608625), fptr(52.673867), fptr(52.622027)},
+		},
+		{
+			name:      "alma",
+			indicator: NewALMA(close, 4, 0.85, 6),
+			output:    []*float64{nil, nil, nil, fptr(53.337969), fptr(53.641975), fptr(53.705104), fptr(53.590424), fptr(53.598889), fptr(53.541739), fptr(52.959303), fptr(52.451916), fptr(51.888828), fptr(51.563266), fptr(52.013413), fptr(52.414497), fptr(52.690311), fptr(52.466538), fptr(52.636579), fptr(52.898355), fptr(52.764376)},
+		},
+		{
+			name:      "swma",
+			indicator: NewSWMA(close),
+			output:    []*float64{nil, nil, nil, fptr(52.915), fptr(53.305), fptr(53.573333), fptr(53.656667), fptr(53.628333), fptr(53.578333), fptr(53.363333), fptr(52.973333), fptr(52.416667), fptr(51.941667), fptr(51.808333), fptr(51.996667), fptr(52.385), fptr(52.543333), fptr(52.591667), fptr(52.676667), fptr(52.763333)},
+		},
+		{
+			name:      "dema",
+			indicator: NewDEMA(close, 4),
+			output:    []*float64{nil, nil, nil, nil, nil, nil, fptr(53.62212), fptr(53.72911), fptr(53.554969), fptr(52.902683), fptr(52.483431), fptr(51.749551), fptr(51.533426), fptr(51.997473), fptr(52.259334), fptr(52.668911), fptr(52.317333), fptr(52.823191), fptr(52.82879), fptr(52.795799)},
+		},
+		{
+			name:      "tema",
+			indicator: NewTEMA(close, 4),
+			output:    []*float64{nil, nil, nil, nil, nil, nil, nil, nil, nil, fptr(52.753459), fptr(52.328524), fptr(51.536787), fptr(51.432397), fptr(52.109866), fptr(52.411036), fptr(52.856368), fptr(52.330874), fptr(52.950039), fptr(52.881383), fptr(52.801035)},
+		},
+		{
+			name:      "zlema",
+			indicator: NewZLEMA(close, 4),
+			output:    []*float64{nil, nil, nil, nil, fptr(53.6475), fptr(53.7125), fptr(53.4915), fptr(53.6909), fptr(53.43854), fptr(52.715124), fptr(52.481074), fptr(51.720645), fptr(51.732387), fptr(52.343432), fptr(52.410059), fptr(52.786036), fptr(52.163621), fptr(52.966173), fptr(52.747704), fptr(52.724622)},
+		},
+	}
+	for _, o := range io {
+		s, err := NewSeries(candles, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.AddIndicator(o.name, o.indicator); err != nil {
+			t.Fatal(err)
+		}
+		for idx, exp := range o.output {
+			v := s.GetValueForInterval(candles[idx].S)
+			if v == nil {
+				t.Fatalf("interval should not be nil at idx: %d", idx)
+			}
+			if err := compareValue(exp, v.Indicators[o.name], 0.000001); err != nil {
+				t.Errorf("%s at idx %d: %+v", o.name, idx, err)
+			}
+		}
+	}
+}
+
+func TestMovingAveragesUpdateLastInterval(t *testing.T) {
+	itvl := 300
+	opts := SeriesOpts{
+		Interval: itvl,
+		Max:      100,
+	}
+	now := time.Now()
+	candles := movingAverageCandles(itvl, now)
+	s, err := NewSeries(candles, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	close := NewOHLCProp(OHLCPropClose)
+	io := []struct {
+		name      string
+		indicator Indicator
+		output    float64
+	}{
+		{name: "dema", indicator: NewDEMA(close, 4), output: 51.666194},
+		{name: "hma", indicator: NewHMA(close, 4), output: 51.623111},
+		{name: "vwma", indicator: NewVWMA(close, 4), output: 52.85642},
+	}
+	for _, o := range io {
+		if err := s.AddIndicator(o.name, o.indicator); err != nil {
+			t.Fatal(err)
+		}
+	}
+	last := candles[len(candles)-1].S.Add(time.Duration(itvl) * time.Second)
+	for _, px := range []float64{52, 51} {
+		if err := s.AddExec(TPQ{Timestamp: last, Px: px, Qty: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	v := s.GetValueForInterval(last)
+	for _, o := range io {
+		if err := compareValue(&o.output, v.Indicators[o.name], 0.000001); err != nil {
+			t.Errorf("%s after revision: %+v", o.name, err)
+		}
+	}
+}
+
+func TestMovingAverageApplyOpts(t *testing.T) {
+	s, err := NewSeries(nil, SeriesOpts{Interval: 300, Max: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddIndicator("wma", NewWMA(NewOHLCProp(OHLCPropClose), 4)); err == nil {
+		t.Fatal("expected error adding WMA with lookback larger than max")
+	}
+	if err := s.AddIndicator("hma", NewHMA(NewOHLCProp(OHLCPropClose), 1)); err == nil {
+		t.Fatal("expected error adding HMA with lookback 1")
+	}
+}
@@ -0,0 +1,172 @@
+package pine_test
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"testing"
+	"time"
+
+	. "github.com/xpt-nl/pine"
+)
+
+func TestPercentile(t *testing.T) {
+	opts := SeriesOpts{
+		Interval: 60,
+		Max:      10,
+	}
+	src := NewOHLCProp(OHLCPropClose)
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	closes := []float64{3, 1, 4, 2, 5}
+	var data []OHLCV
+	for j, c := range closes {
+		data = append(data, OHLCV{C: c, S: start.Add(time.Duration(j) * time.Minute)})
+	}
+	s, err := NewSeries(data, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inds := map[string]Indicator{
+		"linear25":  NewPercentile(src, 4, 25),
+		"linear90":  NewPercentile(src, 4, 90),
+		"nearest25": NewPercentileNearestRank(src, 4, 25),
+		"nearest90": NewPercentileNearestRank(src, 4, 90),
+		"median":    NewMedian(src, 4),
+		"rank":      NewPercentRank(src, 4),
+	}
+	for name, ind := range inds {
+		if err := s.AddIndicator(name, ind); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// window at minute 3 is 3, 1, 4, 2 and at minute 4 is 1, 4, 2, 5
+	io := []struct {
+		time   time.Time
+		output map[string]*float64
+	}{
+		{
+			time: start.Add(3 * time.Minute),
+			output: map[string]*float64{
+				"linear25":  fptr(1.75),
+				"linear90":  fptr(3.7),
+				"nearest25": fptr(1),
+				"nearest90": fptr(4),
+				"median":    fptr(2.5),
+				"rank":      nil,
+			},
+		},
+		{
+			time: start.Add(4 * time.Minute),
+			output: map[string]*float64{
+				"linear25":  fptr(1.75),
+				"linear90":  fptr(4.7),
+				"nearest25": fptr(1),
+				"nearest90": fptr(5),
+				"median":    fptr(3),
+				"rank":      fptr(100),
+			},
+		},
+	}
+	for i, o := range io {
+		v := s.GetValueForInterval(o.time)
+		for name, exp := range o.output {
+			if err := compareValue(exp, v.Indicators[name], 1e-9); err != nil {
+				t.Errorf("%s at idx %d: %v", name, i, err)
+			}
+		}
+	}
+	// revising the current interval replaces its value in the window
+	if err := s.AddOHLCV(OHLCV{C: 0, S: start.Add(4 * time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	v := s.GetValueForInterval(start.Add(4 * time.Minute))
+	for name, exp := range map[string]*float64{
+		"median": fptr(1.5),
+		"rank":   fptr(0),
+	} {
+		if err := compareValue(exp, v.Indicators[name], 1e-9); err != nil {
+			t.Errorf("%s after revision: %v", name, err)
+		}
+	}
+}
+
+func naivePercentile(xs []float64, p float64) float64 {
+	sorted := append([]float64(nil), xs...)
+	sort.Float64s(sorted)
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	if lo+1 >= len(sorted) {
+		return sorted[lo]
+	}
+	return sorted[lo] + (rank-float64(lo))*(sorted[lo+1]-sorted[lo])
+}
+
+func TestPercentileWithRevisions(t *testing.T) {
+	const lookback = 15
+	opts := SeriesOpts{
+		Interval: 60,
+		Max:      30,
+	}
+	s, err := NewSeries(nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := NewOHLCProp(OHLCPropClose)
+	ps := []float64{0, 10, 50, 75, 100}
+	for _, p := range ps {
+		if err := s.AddIndicator(fmt.Sprint(p), NewPercentile(src, lookback, p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	var closes []float64
+	for bar := 0; bar < 300; bar++ {
+		ts := start.Add(time.Duration(bar) * time.Minute)
+		var px float64
+		for rev := 0; rev < 3; rev++ {
+			// few distinct values so the window has duplicates
+			px = float64((bar*7 + rev*3) % 11)
+			if err := s.AddOHLCV(OHLCV{C: px, S: ts}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		closes = append(closes, px)
+		if len(closes) < lookback {
+			continue
+		}
+		v := s.GetValueForInterval(ts)
+		for _, p := range ps {
+			exp := naivePercentile(closes[len(closes)-lookback:], p)
+			if err := compareValue(&exp, v.Indicators[fmt.Sprint(p)], 1e-9); err != nil {
+				t.Fatalf("percentile %v at bar %d: %v", p, bar, err)
+			}
+		}
+	}
+}
+
+// per update cost should grow logarithmically with lookback
+func BenchmarkMedian(b *testing.B) {
+	for _, lookback := range []int{10, 100, 1000, 10000} {
+		b.Run(fmt.Sprint(lookback), func(b *testing.B) {
+			s, err := NewSeries(nil, SeriesOpts{
+				Interval: 60,
+				Max:      lookback,
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+			if err := s.AddIndicator("median", NewMedian(NewOHLCProp(OHLCPropClose), lookback)); err != nil {
+				b.Fatal(err)
+			}
+			start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				px := float64((n * 7919) % 1000)
+				if err := s.AddOHLCV(OHLCV{C: px, S: start.Add(time.Duration(n) * time.Minute)}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
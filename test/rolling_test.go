@@ -0,0 +1,121 @@
+package pine_test
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	. "github.com/xpt-nl/pine"
+)
+
+// naiveWindowStats computes average, population standard deviation and linear
+// regression of xs evaluated at the last value
+func naiveWindowStats(xs []float64) (float64, float64, float64) {
+	n := float64(len(xs))
+	var sum float64
+	for _, x := range xs {
+		sum += x
+	}
+	avg := sum / n
+	var sq, sxy, sxx float64
+	meanx := (n - 1) / 2
+	for j, x := range xs {
+		sq += (x - avg) * (x - avg)
+		sxy += (float64(j) - meanx) * (x - avg)
+		sxx += (float64(j) - meanx) * (float64(j) - meanx)
+	}
+	slope := sxy / sxx
+	return avg, math.Sqrt(sq / n), avg + slope*(n-1-meanx)
+}
+
+func TestRollingIndicatorsWithRevisions(t *testing.T) {
+	const lookback = 20
+	opts := SeriesOpts{
+		Interval: 60,
+		Max:      50,
+	}
+	s, err := NewSeries(nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := NewOHLCProp(OHLCPropClose)
+	for name, ind := range map[string]Indicator{
+		"sma":    NewSMA(src, lookback),
+		"stddev": NewStdDev(src, lookback),
+		"linreg": NewLinReg(src, lookback),
+	} {
+		if err := s.AddIndicator(name, ind); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	var closes []float64
+	for bar := 0; bar < 500; bar++ {
+		ts := start.Add(time.Duration(bar) * time.Minute)
+		var px float64
+		// revise each interval a few times before moving on
+		for rev := 0; rev < 3; rev++ {
+			px = 4000 + 50*math.Sin(float64(bar)/7) + float64((bar*31+rev*17)%13)
+			if err := s.AddOHLCV(OHLCV{O: px, H: px, L: px, C: px, S: ts}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		closes = append(closes, px)
+		if len(closes) < lookback {
+			continue
+		}
+		avg, dev, lr := naiveWindowStats(closes[len(closes)-lookback:])
+		v := s.GetValueForInterval(ts)
+		for name, exp := range map[string]float64{
+			"sma":    avg,
+			"stddev": dev,
+			"linreg": lr,
+		} {
+			act := v.Indicators[name]
+			if err := compareValue(&exp, act, 1e-9*math.Max(1, math.Abs(exp))); err != nil {
+				t.Fatal(fmt.Errorf("%s at bar %d: %w", name, bar, err))
+			}
+		}
+	}
+}
+
+func benchmarkRolling(b *testing.B, newInd func(Indicator, int) Indicator, lookback int) {
+	s, err := NewSeries(nil, SeriesOpts{
+		Interval: 60,
+		Max:      lookback,
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := s.AddIndicator("ind", newInd(NewOHLCProp(OHLCPropClose), lookback)); err != nil {
+		b.Fatal(err)
+	}
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		px := 100 + float64(n%7)
+		if err := s.AddOHLCV(OHLCV{O: px, H: px, L: px, C: px, S: start.Add(time.Duration(n) * time.Minute)}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// per update cost should not grow with lookback
+func BenchmarkRollingIndicators(b *testing.B) {
+	for _, ind := range []struct {
+		name string
+		new  func(Indicator, int) Indicator
+	}{
+		{"SMA", NewSMA},
+		{"StdDev", NewStdDev},
+		{"LinReg", NewLinReg},
+	} {
+		for _, lookback := range []int{10, 100, 1000, 10000} {
+			b.Run(fmt.Sprintf("%s/%d", ind.name, lookback), func(b *testing.B) {
+				benchmarkRolling(b, ind.new, lookback)
+			})
+		}
+	}
+}
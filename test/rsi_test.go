@@ -0,0 +1,186 @@
+package pine_test
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	. "github.com/xpt-nl/pine"
+)
+
+func TestRSI(t *testing.T) {
+	itvl := 300
+	opts := SeriesOpts{
+		Interval: itvl,
+		Max:      32,
+	}
+	name := "rsi"
+	rsiTests := struct {
+		candles  []OHLCV
+		expected []*Interval
+	}{
+		candles: []OHLCV{
+			{C: 52.22},
+			{C: 52.78},
+			{C: 53.02},
+			{C: 53.67},
+			{C: 53.67},
+			{C: 53.74},
+			{C: 53.45},
+			{C: 53.72},
+			{C: 53.39},
+			{C: 52.51},
+			{C: 52.32},
+			{C: 51.45},
+			{C: 51.60},
+			{C: 52.43},
+			{C: 52.47},
+			{C: 52.91},
+			{C: 52.07},
+			{C: 53.12},
+			{C: 52.77},
+			{C: 52.73},
+			{C: 52.09},
+			{C: 53.19},
+			{C: 53.73},
+			{C: 53.87},
+			{C: 53.85},
+			{C: 53.88},
+			{C: 54.08},
+			{C: 54.14},
+			{C: 54.50},
+			{C: 54.30},
+			{C: 54.40},
+			{C: 54.16},
+		},
+		expected: []*Interval{
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			{Value: 52.327747},
+			{Value: 56.193234},
+			{Value: 48.164027},
+			{Value: 56.526067},
+			{Value: 53.431892},
+			{Value: 53.074325},
+			{Value: 47.587123},
+			{Value: 56.006051},
+			{Value: 59.449551},
+			{Value: 60.316779},
+			{Value: 60.118975},
+			{Value: 60.329132},
+			{Value: 61.775293},
+			{Value: 62.220241},
+			{Value: 64.863050},
+			{Value: 62.257437},
+			{Value: 63.056543},
+			{Value: 59.784937},
+		},
+	}
+
+	prettybad := 0.00001
+	now := time.Now()
+	for idx := range rsiTests.candles {
+		t := now.Add(time.Duration(idx*itvl) * time.Second)
+		rsiTests.candles[idx].S = t
+	}
+
+	s, err := NewSeries(rsiTests.candles, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	close := NewOHLCProp(OHLCPropClose)
+	rsi := NewRSI(close, 14)
+	if err := s.AddIndicator(name, rsi); err != nil {
+		t.Fatal(err)
+	}
+	for idx, exp := range rsiTests.expected {
+		tim := now.Add(time.Duration(idx*itvl) * time.Second)
+		v := s.GetValueForInterval(tim)
+		if v == nil {
+			t.Fatal(fmt.Errorf("interval should not be nil: %w", err))
+		}
+		if exp == nil && v.Indicators[name] == nil {
+			continue // ok
+		}
+		if exp == nil && v.Indicators[name] != nil {
+			t.Errorf("expected v to be nil but got %+v at idx: %d", v, idx)
+		}
+		if v.Indicators[name] == nil {
+			t.Errorf("expected indicator to have value but got none at idx %d", idx)
+		} else if math.Abs(exp.Value-*v.Indicators[name])/exp.Value > prettybad {
+			t.Errorf("expected %+v but got %+v for idx: %d", exp.Value, *v.Indicators[name], idx)
+		}
+	}
+}
+
+func TestRSIUpdateLastInterval(t *testing.T) {
+	opts := SeriesOpts{
+		Interval: 300,
+		Max:      100,
+	}
+	now := time.Now()
+	five := now.Add(5 * time.Minute)
+	ten := now.Add(10 * time.Minute)
+	fifteen := now.Add(15 * time.Minute)
+	data := []OHLCV{
+		{C: 10, S: now},
+		{C: 12, S: five},
+		{C: 11, S: ten},
+	}
+	s, err := NewSeries(data, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := "rsi"
+	if err := s.AddIndicator(name, NewRSI(NewOHLCProp(OHLCPropClose), 2)); err != nil {
+		t.Fatal(err)
+	}
+	prettybad := 0.00001
+	io := []struct {
+		exec   TPQ
+		output float64
+	}{
+		// new interval gains 2 on top of seeded averages
+		{
+			exec:   TPQ{Timestamp: fifteen, Px: 13, Qty: 1},
+			output: 85.714286,
+		},
+		// revising the same interval must smooth from the previous interval
+		{
+			exec:   TPQ{Timestamp: fifteen, Px: 9, Qty: 1},
+			output: 28.571429,
+		},
+	}
+
+	v := s.GetValueForInterval(ten)
+	if v == nil || v.Indicators[name] == nil {
+		t.Fatalf("expected rsi to be non nil but got %+v", v)
+	} else if math.Abs(*v.Indicators[name]-66.666667) > prettybad {
+		t.Fatalf("expected rsi to be 66.666667 but got %+v", *v.Indicators[name])
+	}
+	for i, o := range io {
+		if err := s.AddExec(o.exec); err != nil {
+			t.Fatal(fmt.Errorf("error adding exec: %+v: %w", o.exec, err))
+		}
+		v := s.GetValueForInterval(fifteen)
+		if v == nil || v.Indicators[name] == nil {
+			t.Fatalf("expected rsi to be non nil but got %+v at idx: %d", v, i)
+		}
+		if math.Abs(*v.Indicators[name]-o.output) > prettybad {
+			t.Errorf("expected: %+v but got %+v for idx: %d", o.output, *v.Indicators[name], i)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package pine_test
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/xpt-nl/pine/script"
+)
+
+func TestScriptGenerate(t *testing.T) {
+	src := `//@version=5
+indicator("Pine test")
+
+lengthshort = input.int(5, "Short")
+lengthlong = 20
+span = 10
+unused = 3
+
+source = close
+basis = ta.sma(source, lengthshort)
+basis2 = ta.sma(source, lengthlong)
+multi = basis * basis2
+upperBB = basis + span
+[macdLine, signalLine, hist] = ta.macd(close, 12, 26, 9)
+s = ta.rsi(hl2, lengthshort)
+`
+	sc, err := script.Compile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := sc.Generate(script.GenerateOpts{Package: "indicators", Func: "AddPineTest", Source: "test.pine"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `// Code generated by pine2go from test.pine. DO NOT EDIT.
+
+package indicators
+
+import "github.com/xpt-nl/pine"
+
+// AddPineTest adds the indicators of Pine test to s
+func AddPineTest(s pine.Series) error {
+	lengthshort := 5
+	lengthlong := 20
+	span := 10
+
+	source := pine.NewOHLCProp(pine.OHLCPropClose)
+	basis := pine.NewSMA(source, lengthshort)
+	basis2 := pine.NewSMA(source, lengthlong)
+	multi := pine.NewArithmetic(pine.ArithmeticMultiplication, basis, basis2, pine.ArithmeticOpts{})
+	upperBB := pine.NewArithmetic(pine.ArithmeticAddition, basis, pine.NewConstant(float64(span)), pine.ArithmeticOpts{})
+	macd := pine.NewMACD(source, 12, 26, 9)
+	macdLine := macd.MACD
+	signalLine := macd.Signal
+	hist := macd.Histogram
+	s2 := pine.NewRSI(pine.NewOHLCProp(pine.OHLCPropHL2), lengthshort)
+
+	if err := s.AddIndicator("source", source); err != nil {
+		return err
+	}
+	if err := s.AddIndicator("basis", basis); err != nil {
+		return err
+	}
+	if err := s.AddIndicator("basis2", basis2); err != nil {
+		return err
+	}
+	if err := s.AddIndicator("multi", multi); err != nil {
+		return err
+	}
+	if err := s.AddIndicator("upperBB", upperBB); err != nil {
+		return err
+	}
+	if err := s.AddIndicator("macdLine", macdLine); err != nil {
+		return err
+	}
+	if err := s.AddIndicator("signalLine", signalLine); err != nil {
+		return err
+	}
+	if err := s.AddIndicator("hist", hist); err != nil {
+		return err
+	}
+	if err := s.AddIndicator("s", s2); err != nil {
+		return err
+	}
+	return nil
+}
+`
+	if string(code) != expected {
+		t.Errorf("unexpected source:\n%s", code)
+	}
+}
+
+func TestScriptGenerateTypeChecks(t *testing.T) {
+	src := `fast = input.int(3)
+mult = 2
+ratio = input.float(0.5)
+useTR = input.bool(true)
+basis = ta.ema(close, fast)
+[middle, upper, lower] = ta.bb(close, fast, mult)
+[kcMiddle, kcUpper, kcLower] = ta.kc(close, fast, mult, useTR)
+alma = ta.alma(close, 9, ratio, mult)
+pct = ta.percentile_linear_interpolation(close, 10, mult)
+mom = ta.change(close)
+range = math.abs(high - low) / mult
+vwap = ta.vwap
+k = ta.stoch(close, high, low, fast)
+prev = basis[2]
+pine = -ta.atr(fast)
+err = ta.highest(fast)
+`
+	sc, err := script.Compile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := sc.Generate(script.GenerateOpts{Package: "indicators", Func: "Add"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "generated.go", code, 0)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, code)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("indicators", fset, []*ast.File{f}, nil); err != nil {
+		t.Fatalf("%v\n%s", err, code)
+	}
+	if !strings.Contains(string(code), `s.AddIndicator("pine", pine2)`) {
+		t.Errorf("expected variables to be renamed to avoid the package name:\n%s", code)
+	}
+}
+
+func TestScriptGenerateOpts(t *testing.T) {
+	sc, err := script.Compile("x = close")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sc.Generate(script.GenerateOpts{Package: "my-pkg", Func: "Add"}); err == nil {
+		t.Error("expected error for invalid package name")
+	}
+	if _, err := sc.Generate(script.GenerateOpts{Package: "indicators", Func: ""}); err == nil {
+		t.Error("expected error for invalid function name")
+	}
+}
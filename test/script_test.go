@@ -0,0 +1,304 @@
+package pine_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/xpt-nl/pine"
+	"github.com/xpt-nl/pine/script"
+)
+
+func scriptCandles(start time.Time, n int) []OHLCV {
+	candles := make([]OHLCV, n)
+	for j := range candles {
+		c := float64(20 + (j*7)%11)
+		candles[j] = OHLCV{
+			O: c - 1,
+			H: c + 2,
+			L: c - 3,
+			C: c,
+			V: float64(100 + (j*13)%17),
+			S: start.Add(time.Duration(j) * time.Minute),
+		}
+	}
+	return candles
+}
+
+func TestScriptCompile(t *testing.T) {
+	src := `//@version=5
+indicator("Pine test", overlay=true)
+
+lengthshort = input.int(5, "Short")
+lengthlong = input(20)
+int span = 10
+mult = input.float(defval=2, title="Mult")
+
+source = input.source(close, "Source")
+basis = ta.sma(source, lengthshort)
+basis2 = ta.sma(source, lengthlong)
+multi = basis * basis2
+upperBB = basis + span
+lowerBB = basis - span * 2
+neg = -basis
+mom = close - close[2]
+spread = math.max(high - low,
+     ta.atr(length = 3))
+[macdLine, signalLine, hist] = ta.macd(close, 3, 6, 2)
+[middle, _, lower] = ta.bb(source, lengthshort, mult)
+up = ta.crossover(close, basis)
+hh = ta.highest(4)
+
+plot(upperBB, color=color.red)
+`
+	sc, err := script.Compile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc.Title != "Pine test" {
+		t.Errorf("expected title Pine test but got %s", sc.Title)
+	}
+	consts := map[string]float64{"lengthshort": 5, "lengthlong": 20, "span": 10, "mult": 2}
+	if len(sc.Consts) != len(consts) {
+		t.Errorf("expected %d constants but got %+v", len(consts), sc.Consts)
+	}
+	for _, c := range sc.Consts {
+		if c.Value.Num != consts[c.Name] {
+			t.Errorf("expected %s to be %v but got %v", c.Name, consts[c.Name], c.Value.Num)
+		}
+	}
+
+	close := NewOHLCProp(OHLCPropClose)
+	high := NewOHLCProp(OHLCPropHigh)
+	low := NewOHLCProp(OHLCPropLow)
+	basis := NewSMA(close, 5)
+	macd := NewMACD(close, 3, 6, 2)
+	bb := NewBollingerBands(close, 5, 2)
+	expected := map[string]Indicator{
+		"source":     close,
+		"basis":      basis,
+		"basis2":     NewSMA(close, 20),
+		"multi":      NewArithmetic(ArithmeticMultiplication, basis, NewSMA(close, 20), ArithmeticOpts{}),
+		"upperBB":    NewArithmetic(ArithmeticAddition, basis, NewConstant(10), ArithmeticOpts{}),
+		"lowerBB":    NewArithmetic(ArithmeticSubtraction, basis, NewConstant(20), ArithmeticOpts{}),
+		"neg":        NewArithmetic(ArithmeticSubtraction, NewConstant(0), basis, ArithmeticOpts{}),
+		"mom":        NewArithmetic(ArithmeticSubtraction, close, NewPrevious(close, 2), ArithmeticOpts{}),
+		"spread":     NewArithmetic(ArithmeticMax, NewArithmetic(ArithmeticSubtraction, high, low, ArithmeticOpts{}), NewATR(3), ArithmeticOpts{}),
+		"macdLine":   macd.MACD,
+		"signalLine": macd.Signal,
+		"hist":       macd.Histogram,
+		"middle":     bb.Basis,
+		"lower":      bb.Lower,
+		"up":         NewCrossover(close, basis),
+		"hh":         NewHighest(high, 4),
+	}
+	if len(sc.Vars) != len(expected) {
+		t.Errorf("expected %d variables but got %d", len(expected), len(sc.Vars))
+	}
+
+	start := time.Date(2022, 1, 3, 10, 0, 0, 0, time.UTC)
+	candles := scriptCandles(start, 30)
+	opts := SeriesOpts{Interval: 60, Max: 50}
+	compiled, err := NewSeries(candles, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sc.Register(compiled); err != nil {
+		t.Fatal(err)
+	}
+	manual, err := NewSeries(candles, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, ind := range expected {
+		if err := manual.AddIndicator(name, ind); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range candles {
+		exp := manual.GetValueForInterval(c.S)
+		act := compiled.GetValueForInterval(c.S)
+		for name := range expected {
+			if err := compareValue(exp.Indicators[name], act.Indicators[name], 1e-9); err != nil {
+				t.Errorf("%s at %v: %v", name, c.S, err)
+			}
+		}
+	}
+}
+
+func TestScriptIndicatorsAreNotShared(t *testing.T) {
+	sc, err := script.Compile("basis = ta.ema(close, 3)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2022, 1, 3, 10, 0, 0, 0, time.UTC)
+	a, err := NewSeries(scriptCandles(start, 10), SeriesOpts{Interval: 60, Max: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewSeries([]OHLCV{{O: 1, H: 1, L: 1, C: 1, S: start}}, SeriesOpts{Interval: 60, Max: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []Series{a, b} {
+		if err := sc.Register(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if v := b.GetValueForInterval(start).Indicators["basis"]; v != nil {
+		t.Errorf("expected no value before lookback but got %v", *v)
+	}
+	if v := a.GetValueForInterval(start.Add(9 * time.Minute)).Indicators["basis"]; v == nil {
+		t.Error("expected value after lookback")
+	}
+}
+
+func TestScriptErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		line int
+		col  int
+	}{
+		{"reassignment", "x = 1\nx := 2", 2, 3},
+		{"redeclaration", "x = 1\nx = 2", 2, 1},
+		{"if statement", "x = close\nif x > 1\n    y = 1", 2, 1},
+		{"function definition", "f(x) => x * 2", 1, 6},
+		{"unknown function", "x = foo(close)", 1, 5},
+		{"unsupported ta function", "x = ta.supertrend(3, 10)", 1, 5},
+		{"undeclared identifier", "x = ta.sma(src, 5)", 1, 12},
+		{"conditional", "x = close > open ? 1 : 0", 1, 18},
+		{"comparison", "x = close > open", 1, 11},
+		{"length not int", "x = ta.sma(close, 2.5)", 1, 19},
+		{"length series", "x = ta.sma(close, close)", 1, 19},
+		{"length overflow", "x = ta.sma(close, 10000000000000000000)", 1, 19},
+		{"missing argument", "x = ta.sma(close)", 1, 5},
+		{"unknown argument", "x = ta.sma(close, len = 5)", 1, 19},
+		{"too many arguments", "x = ta.sma(close, 5, 6)", 1, 22},
+		{"tuple to variable", "m = ta.macd(close, 12, 26, 9)", 1, 5},
+		{"tuple size", "[a, b] = ta.macd(close, 12, 26, 9)", 1, 1},
+		{"unterminated string", "indicator(\"abc)", 1, 11},
+		{"unexpected character", "x = close $ 2", 1, 11},
+		{"missing paren", "x = (close + 1", 1, 15},
+		{"negative history offset", "x = close[-1]", 1, 11},
+		{"history offset overflow", "x = close[10000000000000000000]", 1, 11},
+		{"var", "var x = close", 1, 1},
+		{"na", "x = na", 1, 5},
+		{"stoch source", "x = ta.stoch(open, high, low, 14)", 1, 14},
+	}
+	for _, tt := range tests {
+		_, err := script.Compile(tt.src)
+		var serr *script.Error
+		if !errors.As(err, &serr) {
+			t.Errorf("%s: expected script error but got %v", tt.name, err)
+			continue
+		}
+		if serr.Pos.Line != tt.line || serr.Pos.Col != tt.col {
+			t.Errorf("%s: expected error at %d:%d but got %v", tt.name, tt.line, tt.col, err)
+		}
+	}
+}
+
+func TestScriptFunctions(t *testing.T) {
+	src := `ema = ta.ema(close, 3)
+rma = ta.rma(close, 3)
+wma = ta.wma(close, 3)
+hma = ta.hma(close, 4)
+vwma = ta.vwma(close, 3)
+alma = ta.alma(close, 5, 0.85, 6)
+swma = ta.swma(close)
+rsi = ta.rsi(close, 3)
+stdev = ta.stdev(close, 3)
+linreg = ta.linreg(close, 3, 0)
+median = ta.median(close, 3)
+rank = ta.percentrank(close, 3)
+pct = ta.percentile_linear_interpolation(close, 4, 25)
+nearest = ta.percentile_nearest_rank(close, 4, 25)
+chg = ta.change(close)
+mom = ta.mom(close, 2)
+atr = ta.atr(3)
+tr = ta.tr
+wpr = ta.wpr(3)
+lowest = ta.lowest(low, 3)
+hbars = ta.highestbars(3)
+lbars = ta.lowestbars(close, 3)
+under = ta.crossunder(close, ema)
+cross = ta.cross(close, ema)
+stoch = ta.stoch(close, high, low, 4)
+vwap = ta.vwap(hlc3)
+[kcMiddle, kcUpper, kcLower] = ta.kc(close, 4, 2, false)
+minimum = math.min(close, 22)
+abs = math.abs(close - 25)
+`
+	sc, err := script.Compile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	close := NewOHLCProp(OHLCPropClose)
+	ema := NewEMA(close, 3)
+	kc := NewKeltnerChannels(close, 4, 2, false)
+	expected := map[string]Indicator{
+		"ema":      ema,
+		"rma":      NewRMA(close, 3),
+		"wma":      NewWMA(close, 3),
+		"hma":      NewHMA(close, 4),
+		"vwma":     NewVWMA(close, 3),
+		"alma":     NewALMA(close, 5, 0.85, 6),
+		"swma":     NewSWMA(close),
+		"rsi":      NewRSI(close, 3),
+		"stdev":    NewStdDev(close, 3),
+		"linreg":   NewLinReg(close, 3),
+		"median":   NewMedian(close, 3),
+		"rank":     NewPercentRank(close, 3),
+		"pct":      NewPercentile(close, 4, 25),
+		"nearest":  NewPercentileNearestRank(close, 4, 25),
+		"chg":      NewChange(close, 1, nil),
+		"mom":      NewChange(close, 2, nil),
+		"atr":      NewATR(3),
+		"tr":       NewTrueRange(),
+		"wpr":      NewWilliamsR(3),
+		"lowest":   NewLowest(NewOHLCProp(OHLCPropLow), 3),
+		"hbars":    NewHighestBars(NewOHLCProp(OHLCPropHigh), 3),
+		"lbars":    NewLowestBars(close, 3),
+		"under":    NewCrossunder(close, ema),
+		"cross":    NewCross(close, ema),
+		"stoch":    NewStoch(4, 1, 1).K,
+		"vwap":     NewVWAP(VWAPOpts{}).VWAP,
+		"kcMiddle": kc.Basis,
+		"kcUpper":  kc.Upper,
+		"kcLower":  kc.Lower,
+		"minimum":  NewArithmetic(ArithmeticMin, close, NewConstant(22), ArithmeticOpts{}),
+		"abs":      NewArithmetic(ArithmeticAbsDiff, NewArithmetic(ArithmeticSubtraction, close, NewConstant(25), ArithmeticOpts{}), NewConstant(0), ArithmeticOpts{}),
+	}
+	if len(sc.Vars) != len(expected) {
+		t.Errorf("expected %d variables but got %d", len(expected), len(sc.Vars))
+	}
+	start := time.Date(2022, 1, 3, 10, 0, 0, 0, time.UTC)
+	candles := scriptCandles(start, 30)
+	opts := SeriesOpts{Interval: 60, Max: 50}
+	compiled, err := NewSeries(candles, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sc.Register(compiled); err != nil {
+		t.Fatal(err)
+	}
+	manual, err := NewSeries(candles, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, ind := range expected {
+		if err := manual.AddIndicator(name, ind); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range candles {
+		exp := manual.GetValueForInterval(c.S)
+		act := compiled.GetValueForInterval(c.S)
+		for name := range expected {
+			if err := compareValue(exp.Indicators[name], act.Indicators[name], 1e-9); err != nil {
+				t.Errorf("%s at %v: %v", name, c.S, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package pine_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/xpt-nl/pine"
+)
+
+func TestSecurity(t *testing.T) {
+	start := time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC)
+	var data []OHLCV
+	for j := 0; j < 7; j++ {
+		c := float64(j + 1)
+		data = append(data, OHLCV{O: c - 0.5, H: c + 1, L: c - 1, C: c, V: 10, S: start.Add(time.Duration(j) * 5 * time.Minute)})
+	}
+	s, err := NewSeries(data, SeriesOpts{
+		Interval: 300,
+		Max:      100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tf, err := NewTimeframe(SeriesOpts{
+		Interval: 900,
+		Max:      100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	htfClose := NewOHLCProp(OHLCPropClose)
+	inds := map[string]Indicator{
+		"off":    NewSecurity(tf, htfClose, LookaheadOff),
+		"on":     NewSecurity(tf, htfClose, LookaheadOn),
+		"smaoff": NewSecurity(tf, NewSMA(NewOHLCProp(OHLCPropClose), 2), LookaheadOff),
+	}
+	for name, ind := range inds {
+		if err := s.AddIndicator(name, ind); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// higher timeframe intervals close at the 3rd, 6th and 9th interval
+	io := []map[string]*float64{
+		{"off": nil, "on": fptr(3), "smaoff": nil},
+		{"off": nil, "on": fptr(3), "smaoff": nil},
+		{"off": fptr(3), "on": fptr(3), "smaoff": nil},
+		{"off": fptr(3), "on": fptr(6), "smaoff": nil},
+		{"off": fptr(3), "on": fptr(6), "smaoff": nil},
+		{"off": fptr(6), "on": fptr(6), "smaoff": fptr(4.5)},
+		{"off": fptr(6), "on": fptr(7), "smaoff": fptr(4.5)},
+	}
+	for j, exp := range io {
+		v := s.GetValueForInterval(data[j].S)
+		for name, e := range exp {
+			if err := compareValue(e, v.Indicators[name], 1e-9); err != nil {
+				t.Errorf("%s at idx %d: %v", name, j, err)
+			}
+		}
+	}
+
+	htf := tf.Series().GetValueForInterval(start.Add(15 * time.Minute))
+	if htf == nil {
+		t.Fatal("expected higher timeframe interval")
+	}
+	expOHLCV := OHLCV{O: 3.5, H: 7, L: 3, C: 6, V: 30, S: start.Add(15 * time.Minute)}
+	if *htf.OHLCV != expOHLCV {
+		t.Errorf("expected %+v but got %+v", expOHLCV, *htf.OHLCV)
+	}
+
+	// revising the last interval revises the developing higher timeframe interval
+	if err := s.AddOHLCV(OHLCV{O: 6.5, H: 12, L: 6, C: 11, V: 5, S: data[6].S}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddOHLCV(OHLCV{O: 11, H: 11, L: 8, C: 9, V: 5, S: data[6].S.Add(5 * time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	htf = tf.Series().GetValueForInterval(start.Add(30 * time.Minute))
+	expOHLCV = OHLCV{O: 6.5, H: 12, L: 6, C: 9, V: 10, S: start.Add(30 * time.Minute)}
+	if *htf.OHLCV != expOHLCV {
+		t.Errorf("expected %+v but got %+v", expOHLCV, *htf.OHLCV)
+	}
+	v := s.GetValueForInterval(data[6].S.Add(5 * time.Minute))
+	for name, e := range map[string]*float64{"off": fptr(6), "on": fptr(9)} {
+		if err := compareValue(e, v.Indicators[name], 1e-9); err != nil {
+			t.Errorf("%s after revision: %v", name, err)
+		}
+	}
+}
+
+func TestSecurityInvalidInterval(t *testing.T) {
+	s, err := NewSeries(nil, SeriesOpts{
+		Interval: 300,
+		Max:      100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tf, err := NewTimeframe(SeriesOpts{
+		Interval: 400,
+		Max:      100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddIndicator("sec", NewSecurity(tf, NewOHLCProp(OHLCPropClose), LookaheadOff)); err == nil {
+		t.Error("expected error for interval that isn't a multiple of series interval")
+	}
+}
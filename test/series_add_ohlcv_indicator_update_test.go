@@ -0,0 +1,136 @@
+package pine_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/xpt-nl/pine"
+)
+
+func TestSeriesAddOHLCVUpdatesIndicators(t *testing.T) {
+	opts := SeriesOpts{
+		Interval:  300,
+		Max:       100,
+		EmptyInst: EmptyInstUseLastClose,
+	}
+	s, err := NewSeries(nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// indicators are registered before any OHLCV is streamed
+	close := NewOHLCProp(OHLCPropClose)
+	if err := s.AddIndicator("close", close); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddIndicator("sma", NewSMA(close, 2)); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	five := now.Add(5 * time.Minute)
+	ten := now.Add(10 * time.Minute)
+	twenty := now.Add(20 * time.Minute)
+	io := []struct {
+		ohlcv  OHLCV
+		time   time.Time
+		output map[string]*float64
+	}{
+		// first interval
+		{
+			ohlcv:  OHLCV{O: 10, H: 10, L: 10, C: 10, S: now},
+			time:   now,
+			output: map[string]*float64{"close": fptr(10), "sma": nil},
+		},
+		// new interval
+		{
+			ohlcv:  OHLCV{O: 12, H: 12, L: 12, C: 12, S: five},
+			time:   five,
+			output: map[string]*float64{"close": fptr(12), "sma": fptr(11)},
+		},
+		// revision of the current interval
+		{
+			ohlcv:  OHLCV{O: 12, H: 14, L: 12, C: 14, S: five},
+			time:   five,
+			output: map[string]*float64{"close": fptr(14), "sma": fptr(12)},
+		},
+		// skipping an interval fills it with the last close
+		{
+			ohlcv:  OHLCV{O: 20, H: 20, L: 20, C: 20, S: ten.Add(5 * time.Minute)},
+			time:   ten,
+			output: map[string]*float64{"close": fptr(14), "sma": fptr(14)},
+		},
+		{
+			time:   ten.Add(5 * time.Minute),
+			output: map[string]*float64{"close": fptr(20), "sma": fptr(17)},
+		},
+		// next interval after the filled gap
+		{
+			ohlcv:  OHLCV{O: 22, H: 22, L: 22, C: 22, S: twenty},
+			time:   twenty,
+			output: map[string]*float64{"close": fptr(22), "sma": fptr(21)},
+		},
+	}
+	for idx, o := range io {
+		if !o.ohlcv.S.IsZero() {
+			if err := s.AddOHLCV(o.ohlcv); err != nil {
+				t.Fatal(fmt.Errorf("error adding ohlcv: %+v: %w", o.ohlcv, err))
+			}
+		}
+		v := s.GetValueForInterval(o.time)
+		if v == nil {
+			t.Fatalf("expected v to be non nil at idx: %d", idx)
+		}
+		for name, exp := range o.output {
+			if err := compareValue(exp, v.Indicators[name], 0.000001); err != nil {
+				t.Errorf("%s at idx %d: %+v", name, idx, err)
+			}
+		}
+	}
+
+	// indicators added later replay revised values
+	if err := s.AddIndicator("sma-late", NewSMA(NewOHLCProp(OHLCPropClose), 2)); err != nil {
+		t.Fatal(err)
+	}
+	v := s.GetValueForInterval(five)
+	if err := compareValue(fptr(12), v.Indicators["sma-late"], 0.000001); err != nil {
+		t.Errorf("late sma: %+v", err)
+	}
+}
+
+func TestSeriesAddOHLCVUpdatesIndicatorsWithZeros(t *testing.T) {
+	opts := SeriesOpts{
+		Interval:  300,
+		Max:       100,
+		EmptyInst: EmptyInstUseZeros,
+	}
+	now := time.Now()
+	s, err := NewSeries([]OHLCV{{O: 10, H: 10, L: 10, C: 10, S: now}}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddIndicator("close", NewOHLCProp(OHLCPropClose)); err != nil {
+		t.Fatal(err)
+	}
+	fifteen := now.Add(15 * time.Minute)
+	if err := s.AddOHLCV(OHLCV{O: 20, H: 20, L: 20, C: 20, S: fifteen}); err != nil {
+		t.Fatal(err)
+	}
+	io := []struct {
+		time   time.Time
+		output float64
+	}{
+		{time: now.Add(5 * time.Minute), output: 0},
+		{time: now.Add(10 * time.Minute), output: 0},
+		{time: fifteen, output: 20},
+	}
+	for idx, o := range io {
+		v := s.GetValueForInterval(o.time)
+		if v == nil {
+			t.Fatalf("expected v to be non nil at idx: %d", idx)
+		}
+		if err := compareValue(&o.output, v.Indicators["close"], 0); err != nil {
+			t.Errorf("idx %d: %+v", idx, err)
+		}
+	}
+}
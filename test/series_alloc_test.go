@@ -0,0 +1,106 @@
+package pine_test
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	. "github.com/xpt-nl/pine"
+)
+
+func TestSeriesSteadyStateAllocs(t *testing.T) {
+	opts := SeriesOpts{
+		Interval: 60,
+		Max:      50,
+	}
+	s, err := NewSeries(nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := NewOHLCProp(OHLCPropClose)
+	inds := map[string]Indicator{
+		"sma":    NewSMA(src, 10),
+		"stddev": NewStdDev(src, 10),
+		"linreg": NewLinReg(src, 10),
+		"rsi":    NewRSI(src, 10),
+		"hma":    NewHMA(src, 10),
+		"stoch":  NewStoch(10, 3, 3).K,
+	}
+	for name, ind := range inds {
+		if err := s.AddIndicator(name, ind); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	n := 0
+	next := func() {
+		px := 100 + float64(n%7)
+		if err := s.AddOHLCV(OHLCV{O: px, H: px + 1, L: px - 1, C: px, V: 1, S: start.Add(time.Duration(n) * time.Minute)}); err != nil {
+			t.Fatal(err)
+		}
+		n++
+	}
+	// fill storage so values are overwritten from here on
+	for n < opts.Max*2 {
+		next()
+	}
+	if allocs := testing.AllocsPerRun(100, next); allocs != 0 {
+		t.Errorf("expected no allocations per interval but got %v", allocs)
+	}
+}
+
+func BenchmarkSeriesAddOHLCV(b *testing.B) {
+	s, err := NewSeries(nil, SeriesOpts{
+		Interval: 60,
+		Max:      1000,
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	src := NewOHLCProp(OHLCPropClose)
+	if err := s.AddIndicator("sma", NewSMA(src, 20)); err != nil {
+		b.Fatal(err)
+	}
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		px := 100 + float64(n%7)
+		if err := s.AddOHLCV(OHLCV{O: px, H: px + 1, L: px - 1, C: px, V: 1, S: start.Add(time.Duration(n) * time.Minute)}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestSeriesLargeLookbackAllocs(t *testing.T) {
+	s, err := NewSeries(nil, SeriesOpts{Interval: 60, Max: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := NewOHLCProp(OHLCPropClose)
+	inds := map[string]func() Indicator{
+		"highest": func() Indicator { return NewHighest(src, 200000000) },
+		"sma":     func() Indicator { return NewSMA(src, 200000000) },
+		"stddev":  func() Indicator { return NewStdDev(src, 200000000) },
+		"linreg":  func() Indicator { return NewLinReg(src, 200000000) },
+		"median":  func() Indicator { return NewMedian(src, 50000000) },
+		"ema":     func() Indicator { return NewEMA(src, 200000000) },
+		"rsi":     func() Indicator { return NewRSI(src, 200000000) },
+		"bb":      func() Indicator { return NewBollingerBands(src, 200000000, 2).Basis },
+		"stoch":   func() Indicator { return NewStoch(14, 200000000, 3).K },
+		"wma":     func() Indicator { return NewWMA(src, 200000000) },
+		"hma":     func() Indicator { return NewHMA(src, 200000000) },
+		"alma":    func() Indicator { return NewALMA(src, 200000000, 0.85, 6) },
+	}
+	for name, ind := range inds {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		if err := s.AddIndicator(name, ind()); err == nil {
+			t.Errorf("%s: expected error for lookback above max", name)
+		}
+		runtime.ReadMemStats(&after)
+		if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 1<<20 {
+			t.Errorf("%s: expected lookback to be checked before allocating but allocated %d bytes", name, alloc)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package pine_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/xpt-nl/pine"
+)
+
+func TestSeriesBarState(t *testing.T) {
+	start := time.Date(2022, 1, 3, 10, 0, 0, 0, time.UTC)
+	var closed []Interval
+	s, err := NewSeries([]OHLCV{
+		{O: 1, H: 1, L: 1, C: 1, V: 1, S: start},
+		{O: 2, H: 2, L: 2, C: 2, V: 1, S: start.Add(time.Minute)},
+	}, SeriesOpts{
+		Interval: 60,
+		Max:      10,
+		OnClose: func(itvl *Interval) {
+			closed = append(closed, *itvl)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddIndicator("close", NewOHLCProp(OHLCPropClose)); err != nil {
+		t.Fatal(err)
+	}
+	execs := []struct {
+		exec   TPQ
+		states map[time.Duration]BarState
+		closed int
+	}{
+		{
+			exec: TPQ{Timestamp: start.Add(2 * time.Minute), Px: 3, Qty: 1},
+			states: map[time.Duration]BarState{
+				0:               {IsConfirmed: true},
+				time.Minute:     {IsConfirmed: true},
+				2 * time.Minute: {IsNew: true, IsRealtime: true},
+			},
+			closed: 1,
+		},
+		{
+			exec: TPQ{Timestamp: start.Add(2*time.Minute + 30*time.Second), Px: 4, Qty: 1},
+			states: map[time.Duration]BarState{
+				2 * time.Minute: {IsRealtime: true},
+			},
+			closed: 1,
+		},
+		{
+			// the fourth interval is filled with the last close
+			exec: TPQ{Timestamp: start.Add(4 * time.Minute), Px: 5, Qty: 1},
+			states: map[time.Duration]BarState{
+				2 * time.Minute: {IsConfirmed: true, IsRealtime: true},
+				3 * time.Minute: {IsConfirmed: true, IsRealtime: true},
+				4 * time.Minute: {IsNew: true, IsRealtime: true},
+			},
+			closed: 3,
+		},
+	}
+	for _, e := range execs {
+		if err := s.AddExec(e.exec); err != nil {
+			t.Fatal(err)
+		}
+		for d, exp := range e.states {
+			v := s.GetValueForInterval(start.Add(d))
+			if v == nil || v.BarState != exp {
+				t.Errorf("expected %+v at %v but got %+v", exp, start.Add(d), v)
+			}
+		}
+		if len(closed) != e.closed {
+			t.Fatalf("expected %d closed intervals but got %d", e.closed, len(closed))
+		}
+	}
+	exp := []OHLCV{
+		{O: 2, H: 2, L: 2, C: 2, V: 1, S: start.Add(time.Minute)},
+		{O: 3, H: 4, L: 3, C: 4, V: 2, S: start.Add(2 * time.Minute)},
+		{O: 4, H: 4, L: 4, C: 4, V: 0, S: start.Add(3 * time.Minute)},
+	}
+	for j, itvl := range closed {
+		if *itvl.OHLCV != exp[j] || !itvl.BarState.IsConfirmed {
+			t.Errorf("expected closed interval %+v but got %+v", exp[j], itvl)
+		}
+		if c := itvl.Indicators["close"]; c == nil || *c != exp[j].C {
+			t.Errorf("expected close %v but got %v", exp[j].C, c)
+		}
+	}
+}
+
+func TestSeriesUpdateOnClose(t *testing.T) {
+	start := time.Date(2022, 1, 3, 10, 0, 0, 0, time.UTC)
+	var closes []float64
+	s, err := NewSeries(nil, SeriesOpts{
+		Interval:      60,
+		Max:           10,
+		UpdateOnClose: true,
+		OnClose: func(itvl *Interval) {
+			if v := itvl.Indicators["sma"]; v != nil {
+				closes = append(closes, *v)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sma := NewSMA(NewOHLCProp(OHLCPropClose), 2)
+	if err := s.AddIndicator("sma", sma); err != nil {
+		t.Fatal(err)
+	}
+	execs := []TPQ{
+		{Timestamp: start, Px: 1, Qty: 1},
+		{Timestamp: start.Add(time.Minute), Px: 5, Qty: 1},
+		{Timestamp: start.Add(time.Minute + 30*time.Second), Px: 3, Qty: 1},
+	}
+	for _, e := range execs {
+		if err := s.AddExec(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the second interval is not closed so it has no value yet
+	if v := sma.GetValueForInterval(start.Add(time.Minute)); v != nil {
+		t.Errorf("expected no value before close but got %+v", v)
+	}
+	if err := s.AddExec(TPQ{Timestamp: start.Add(2 * time.Minute), Px: 7, Qty: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if v := sma.GetValueForInterval(start.Add(time.Minute)); v == nil || v.Value != 2 {
+		t.Errorf("expected sma 2 on close but got %+v", v)
+	}
+	if len(closes) != 1 || closes[0] != 2 {
+		t.Errorf("expected close callback with sma 2 but got %v", closes)
+	}
+	// indicators added later are updated with closed intervals only
+	later := NewOHLCProp(OHLCPropClose)
+	if err := s.AddIndicator("later", later); err != nil {
+		t.Fatal(err)
+	}
+	if v := later.GetValueForInterval(start.Add(2 * time.Minute)); v != nil {
+		t.Errorf("expected no value for open interval but got %+v", v)
+	}
+	if v := later.GetValueForInterval(start.Add(time.Minute)); v == nil || v.Value != 3 {
+		t.Errorf("expected close 3 but got %+v", v)
+	}
+}
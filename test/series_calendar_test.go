@@ -0,0 +1,153 @@
+package pine_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/xpt-nl/pine"
+)
+
+func TestSeriesCalendarIntervals(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone data not available")
+	}
+	tests := []struct {
+		name   string
+		opts   SeriesOpts
+		time   time.Time
+		output time.Time
+	}{
+		{
+			name:   "weekly starting monday",
+			opts:   SeriesOpts{Interval: 1, IntervalUnit: IntervalUnitWeek, WeekStart: time.Monday},
+			time:   time.Date(2022, 3, 17, 13, 0, 0, 0, time.UTC),
+			output: time.Date(2022, 3, 14, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "weekly starting sunday",
+			opts:   SeriesOpts{Interval: 1, IntervalUnit: IntervalUnitWeek},
+			time:   time.Date(2022, 3, 13, 0, 0, 0, 0, time.UTC),
+			output: time.Date(2022, 3, 13, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "two weeks",
+			opts:   SeriesOpts{Interval: 2, IntervalUnit: IntervalUnitWeek, WeekStart: time.Monday},
+			time:   time.Date(2022, 3, 17, 13, 0, 0, 0, time.UTC),
+			output: time.Date(2022, 3, 7, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "monthly",
+			opts:   SeriesOpts{Interval: 1, IntervalUnit: IntervalUnitMonth},
+			time:   time.Date(2022, 3, 31, 23, 59, 0, 0, time.UTC),
+			output: time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "quarterly",
+			opts:   SeriesOpts{Interval: 3, IntervalUnit: IntervalUnitMonth},
+			time:   time.Date(2022, 6, 30, 0, 0, 0, 0, time.UTC),
+			output: time.Date(2022, 4, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "three days",
+			opts:   SeriesOpts{Interval: 3, IntervalUnit: IntervalUnitDay},
+			time:   time.Date(1970, 1, 6, 12, 0, 0, 0, time.UTC),
+			output: time.Date(1970, 1, 4, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "daily closing at 17:00 new york before close",
+			opts:   SeriesOpts{Interval: 1, IntervalUnit: IntervalUnitDay, Location: ny, DayStart: 17 * time.Hour},
+			time:   time.Date(2022, 3, 14, 16, 59, 0, 0, ny),
+			output: time.Date(2022, 3, 13, 17, 0, 0, 0, ny),
+		},
+		{
+			name:   "daily closing at 17:00 new york after close",
+			opts:   SeriesOpts{Interval: 1, IntervalUnit: IntervalUnitDay, Location: ny, DayStart: 17 * time.Hour},
+			time:   time.Date(2022, 3, 14, 17, 0, 0, 0, ny),
+			output: time.Date(2022, 3, 14, 17, 0, 0, 0, ny),
+		},
+		{
+			name:   "hourly in new york after daylight saving change",
+			opts:   SeriesOpts{Interval: 3600, Location: ny},
+			time:   time.Date(2022, 3, 13, 10, 30, 0, 0, ny),
+			output: time.Date(2022, 3, 13, 10, 0, 0, 0, ny),
+		},
+		{
+			name:   "interval not dividing a day",
+			opts:   SeriesOpts{Interval: 7 * 60},
+			time:   time.Date(2022, 3, 14, 0, 5, 0, 0, time.UTC),
+			output: time.Date(2022, 3, 14, 0, 1, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		tt.opts.Max = 10
+		s, err := NewSeries(nil, tt.opts)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if err := s.AddOHLCV(OHLCV{C: 1, S: tt.time}); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		v := s.GetValueForInterval(tt.time)
+		if v == nil {
+			t.Fatalf("%s: expected interval", tt.name)
+		}
+		if !v.StartTime.Equal(tt.output) {
+			t.Errorf("%s: expected %v but got %v", tt.name, tt.output, v.StartTime)
+		}
+	}
+}
+
+func TestSeriesCalendarGapFill(t *testing.T) {
+	s, err := NewSeries(nil, SeriesOpts{
+		Interval:     1,
+		IntervalUnit: IntervalUnitMonth,
+		Max:          10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jan := time.Date(2022, 1, 20, 0, 0, 0, 0, time.UTC)
+	apr := time.Date(2022, 4, 2, 0, 0, 0, 0, time.UTC)
+	if err := s.AddOHLCV(OHLCV{O: 1, H: 1, L: 1, C: 1, S: jan}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddOHLCV(OHLCV{O: 2, H: 2, L: 2, C: 2, S: apr}); err != nil {
+		t.Fatal(err)
+	}
+	for m, c := range map[time.Month]float64{2: 1, 3: 1, 4: 2} {
+		v := s.GetValueForInterval(time.Date(2022, m, 1, 0, 0, 0, 0, time.UTC))
+		if v == nil || v.OHLCV.C != c {
+			t.Errorf("expected close %v for month %v but got %+v", c, m, v)
+		}
+	}
+}
+
+func TestSeriesDaylightSavingGapFill(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone data not available")
+	}
+	s, err := NewSeries(nil, SeriesOpts{
+		Interval: 3600,
+		Location: ny,
+		Max:      30,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// clocks skip 02:00 to 03:00 on 2022-03-13 so the day has 23 hours
+	from := time.Date(2022, 3, 12, 22, 0, 0, 0, ny)
+	to := time.Date(2022, 3, 13, 6, 0, 0, 0, ny)
+	if err := s.AddOHLCV(OHLCV{C: 1, S: from}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddOHLCV(OHLCV{C: 2, S: to}); err != nil {
+		t.Fatal(err)
+	}
+	for h := from; !h.After(to); h = h.Add(time.Hour) {
+		if v := s.GetValueForInterval(h); v == nil || !v.StartTime.Equal(h) {
+			t.Errorf("expected interval at %v but got %+v", h, v)
+		}
+	}
+}
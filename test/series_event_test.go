@@ -0,0 +1,119 @@
+package pine_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/xpt-nl/pine"
+)
+
+type recordedEvent struct {
+	typ       EventType
+	start     time.Time
+	indicator string
+	value     float64
+}
+
+func TestSeriesSubscribe(t *testing.T) {
+	start := time.Date(2022, 1, 3, 10, 0, 0, 0, time.UTC)
+	s, err := NewSeries(nil, SeriesOpts{Interval: 60, Max: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddIndicator("close", NewOHLCProp(OHLCPropClose)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddIndicator("sma", NewSMA(NewOHLCProp(OHLCPropClose), 2)); err != nil {
+		t.Fatal(err)
+	}
+	var events []recordedEvent
+	unsubscribe := s.Subscribe(func(e Event) {
+		r := recordedEvent{
+			typ:       e.Type,
+			start:     e.Interval.StartTime,
+			indicator: e.Indicator,
+		}
+		if e.Type == EventIndicatorChanged {
+			r.value = *e.Interval.Indicators[e.Indicator]
+		}
+		events = append(events, r)
+	})
+	execs := []TPQ{
+		{Timestamp: start, Px: 1, Qty: 1},
+		{Timestamp: start.Add(10 * time.Second), Px: 3, Qty: 1},
+		// same close so no indicator changes
+		{Timestamp: start.Add(20 * time.Second), Px: 3, Qty: 1},
+		{Timestamp: start.Add(2 * time.Minute), Px: 5, Qty: 1},
+	}
+	for _, e := range execs {
+		if err := s.AddExec(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m1 := start.Add(time.Minute)
+	m2 := start.Add(2 * time.Minute)
+	exp := []recordedEvent{
+		{typ: EventBarOpened, start: start},
+		{typ: EventIndicatorChanged, start: start, indicator: "close", value: 1},
+		{typ: EventBarUpdated, start: start},
+		{typ: EventIndicatorChanged, start: start, indicator: "close", value: 3},
+		{typ: EventBarUpdated, start: start},
+		{typ: EventBarClosed, start: start},
+		{typ: EventGapFilled, start: m1},
+		{typ: EventIndicatorChanged, start: m1, indicator: "close", value: 3},
+		{typ: EventIndicatorChanged, start: m1, indicator: "sma", value: 3},
+		{typ: EventBarClosed, start: m1},
+		{typ: EventBarOpened, start: m2},
+		{typ: EventIndicatorChanged, start: m2, indicator: "close", value: 5},
+		{typ: EventIndicatorChanged, start: m2, indicator: "sma", value: 4},
+	}
+	if len(events) != len(exp) {
+		t.Fatalf("expected %d events but got %d: %+v", len(exp), len(events), events)
+	}
+	for j := range exp {
+		if events[j] != exp[j] {
+			t.Errorf("event %d: expected %+v but got %+v", j, exp[j], events[j])
+		}
+	}
+	unsubscribe()
+	if err := s.AddExec(TPQ{Timestamp: m2.Add(time.Second), Px: 6, Qty: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != len(exp) {
+		t.Errorf("expected no events after unsubscribing but got %+v", events[len(exp):])
+	}
+}
+
+func TestSeriesSubscribeUpdateOnClose(t *testing.T) {
+	start := time.Date(2022, 1, 3, 10, 0, 0, 0, time.UTC)
+	s, err := NewSeries(nil, SeriesOpts{Interval: 60, Max: 10, UpdateOnClose: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddIndicator("close", NewOHLCProp(OHLCPropClose)); err != nil {
+		t.Fatal(err)
+	}
+	var types []EventType
+	s.Subscribe(func(e Event) {
+		types = append(types, e.Type)
+	})
+	for _, e := range []TPQ{
+		{Timestamp: start, Px: 1, Qty: 1},
+		{Timestamp: start.Add(time.Second), Px: 2, Qty: 1},
+		{Timestamp: start.Add(time.Minute), Px: 3, Qty: 1},
+	} {
+		if err := s.AddExec(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exp := []EventType{EventBarOpened, EventBarUpdated, EventBarClosed, EventIndicatorChanged, EventBarOpened}
+	if len(types) != len(exp) {
+		t.Fatalf("expected %v but got %v", exp, types)
+	}
+	for j := range exp {
+		if types[j] != exp[j] {
+			t.Errorf("expected %v but got %v", exp, types)
+			break
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package pine_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/xpt-nl/pine"
+)
+
+// countingClose is an indicator of another package generating close values
+// and counting its updates
+type countingClose struct {
+	updates int
+	vals    map[time.Time]float64
+}
+
+func newCountingClose() *countingClose {
+	return &countingClose{
+		vals: make(map[time.Time]float64),
+	}
+}
+
+func (i *countingClose) GetValueForInterval(t time.Time) *Interval {
+	v, ok := i.vals[t]
+	if !ok {
+		return nil
+	}
+	return &Interval{
+		StartTime: t,
+		Value:     v,
+	}
+}
+
+func (i *countingClose) Update(v OHLCV) error {
+	i.updates++
+	i.vals[v.S] = v.C
+	return nil
+}
+
+func (i *countingClose) ApplyOpts(opts SeriesOpts) error {
+	return nil
+}
+
+// valueClose is not comparable so it cannot be deduplicated
+type valueClose struct {
+	vals map[time.Time]float64
+}
+
+func (i valueClose) GetValueForInterval(t time.Time) *Interval {
+	v, ok := i.vals[t]
+	if !ok {
+		return nil
+	}
+	return &Interval{
+		StartTime: t,
+		Value:     v,
+	}
+}
+
+func (i valueClose) Update(v OHLCV) error {
+	i.vals[v.S] = v.C
+	return nil
+}
+
+func (i valueClose) ApplyOpts(opts SeriesOpts) error {
+	return nil
+}
+
+func TestSeriesSharedIndicatorsUpdatedOnce(t *testing.T) {
+	start := time.Date(2022, 1, 3, 10, 0, 0, 0, time.UTC)
+	s, err := NewSeries(nil, SeriesOpts{Interval: 60, Max: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := newCountingClose()
+	fast := NewEMA(src, 3)
+	slow := NewEMA(src, 6)
+	macd := NewMACD(src, 3, 6, 2)
+	indicators := map[string]Indicator{
+		"close":     src,
+		"sma":       NewSMA(src, 3),
+		"rsi":       NewRSI(src, 3),
+		"diff":      NewArithmetic(ArithmeticSubtraction, fast, slow, ArithmeticOpts{}),
+		"cross":     NewCrossover(fast, slow),
+		"macd":      macd.MACD,
+		"histogram": macd.Histogram,
+		"value":     NewSMA(valueClose{vals: make(map[time.Time]float64)}, 2),
+	}
+	for name, ind := range indicators {
+		if err := s.AddIndicator(name, ind); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for j := 0; j < 10; j++ {
+		e := TPQ{Timestamp: start.Add(time.Duration(j) * time.Minute), Px: float64(10 + j%4), Qty: 1}
+		if err := s.AddExec(e); err != nil {
+			t.Fatal(err)
+		}
+		if src.updates != j+1 {
+			t.Fatalf("expected %d updates but got %d", j+1, src.updates)
+		}
+	}
+	// values match indicators updated on their own
+	standalone := newCountingClose()
+	diff := NewArithmetic(ArithmeticSubtraction, NewEMA(standalone, 3), NewEMA(standalone, 6), ArithmeticOpts{})
+	if err := diff.ApplyOpts(SeriesOpts{Interval: 60, Max: 20}); err != nil {
+		t.Fatal(err)
+	}
+	for j := 0; j < 10; j++ {
+		it := start.Add(time.Duration(j) * time.Minute)
+		if err := diff.Update(OHLCV{C: float64(10 + j%4), S: it}); err != nil {
+			t.Fatal(err)
+		}
+		exp := diff.GetValueForInterval(it)
+		act := s.GetValueForInterval(it).Indicators["diff"]
+		var expv *float64
+		if exp != nil {
+			expv = &exp.Value
+		}
+		if err := compareValue(expv, act, 1e-9); err != nil {
+			t.Errorf("diff at %v: %v", it, err)
+		}
+	}
+	if v := s.GetValueForInterval(start.Add(9 * time.Minute)).Indicators["value"]; v == nil || *v != 10.5 {
+		t.Errorf("expected value 10.5 but got %v", v)
+	}
+}
+
+func TestSeriesAddIndicatorSharedNode(t *testing.T) {
+	start := time.Date(2022, 1, 3, 10, 0, 0, 0, time.UTC)
+	s, err := NewSeries(nil, SeriesOpts{Interval: 60, Max: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := newCountingClose()
+	if err := s.AddIndicator("close", src); err != nil {
+		t.Fatal(err)
+	}
+	for j := 0; j < 5; j++ {
+		if err := s.AddExec(TPQ{Timestamp: start.Add(time.Duration(j) * time.Minute), Px: float64(j), Qty: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// src is up to date so it is not replayed
+	if err := s.AddIndicator("sma", NewSMA(src, 2)); err != nil {
+		t.Fatal(err)
+	}
+	if src.updates != 5 {
+		t.Errorf("expected 5 updates but got %d", src.updates)
+	}
+	if v := s.GetValueForInterval(start.Add(4 * time.Minute)).Indicators["sma"]; v == nil || *v != 3.5 {
+		t.Errorf("expected sma 3.5 but got %v", v)
+	}
+}
+
+func TestSeriesAddIndicatorReplace(t *testing.T) {
+	start := time.Date(2022, 1, 3, 10, 0, 0, 0, time.UTC)
+	s, err := NewSeries(nil, SeriesOpts{Interval: 60, Max: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := newCountingClose()
+	if err := s.AddIndicator("sma", NewSMA(old, 2)); err != nil {
+		t.Fatal(err)
+	}
+	for j := 0; j < 5; j++ {
+		if err := s.AddExec(TPQ{Timestamp: start.Add(time.Duration(j) * time.Minute), Px: float64(j), Qty: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the new indicator is replayed and the replaced one is dropped
+	src := newCountingClose()
+	if err := s.AddIndicator("sma", NewSMA(src, 3)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddExec(TPQ{Timestamp: start.Add(5 * time.Minute), Px: 5, Qty: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if old.updates != 5 {
+		t.Errorf("expected 5 updates of the replaced source but got %d", old.updates)
+	}
+	if src.updates != 6 {
+		t.Errorf("expected 6 updates but got %d", src.updates)
+	}
+	if v := s.GetValueForInterval(start.Add(5 * time.Minute)).Indicators["sma"]; v == nil || *v != 4 {
+		t.Errorf("expected sma 4 but got %v", v)
+	}
+}
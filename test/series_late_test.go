@@ -0,0 +1,389 @@
+package pine_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/xpt-nl/pine"
+)
+
+func TestSeriesLateExecDrop(t *testing.T) {
+	start := time.Date(2022, 1, 3, 10, 0, 0, 0, time.UTC)
+	s, err := NewSeries(nil, SeriesOpts{Interval: 60, Max: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	execs := []TPQ{
+		{Timestamp: start, Px: 1, Qty: 1},
+		{Timestamp: start.Add(time.Minute), Px: 2, Qty: 1},
+		{Timestamp: start.Add(30 * time.Second), Px: 5, Qty: 1},
+	}
+	for _, e := range execs {
+		if err := s.AddExec(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s.DroppedExecs() != 1 {
+		t.Errorf("expected 1 dropped exec but got %d", s.DroppedExecs())
+	}
+	if v := s.GetValueForInterval(start); v == nil || v.OHLCV.H != 1 || v.OHLCV.V != 1 {
+		t.Errorf("expected interval to be unchanged but got %+v", v)
+	}
+}
+
+func TestSeriesLateExecReject(t *testing.T) {
+	start := time.Date(2022, 1, 3, 10, 0, 0, 0, time.UTC)
+	s, err := NewSeries(nil, SeriesOpts{Interval: 60, Max: 10, LateInst: LateInstReject})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddExec(TPQ{Timestamp: start.Add(time.Minute), Px: 2, Qty: 1}); err != nil {
+		t.Fatal(err)
+	}
+	err = s.AddExec(TPQ{Timestamp: start, Px: 1, Qty: 1})
+	var late *LateExecError
+	if !errors.As(err, &late) {
+		t.Fatalf("expected LateExecError but got %v", err)
+	}
+	if !late.Last.Equal(start.Add(time.Minute)) || !late.Exec.Timestamp.Equal(start) {
+		t.Errorf("unexpected error values %+v", late)
+	}
+	if s.DroppedExecs() != 0 {
+		t.Errorf("expected no dropped execs but got %d", s.DroppedExecs())
+	}
+}
+
+func TestSeriesLateExecAmend(t *testing.T) {
+	start := time.Date(2022, 1, 3, 10, 0, 0, 0, time.UTC)
+	s, err := NewSeries(nil, SeriesOpts{Interval: 60, Max: 3, LateInst: LateInstAmend})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddIndicator("sma", NewSMA(NewOHLCProp(OHLCPropClose), 2)); err != nil {
+		t.Fatal(err)
+	}
+	execs := []TPQ{
+		{Timestamp: start.Add(10 * time.Second), Px: 10, Qty: 1},
+		{Timestamp: start.Add(20 * time.Second), Px: 12, Qty: 1},
+		// the second and third intervals are filled with the last close and
+		// the first one is no longer kept once the fourth is added
+		{Timestamp: start.Add(3 * time.Minute), Px: 20, Qty: 1},
+	}
+	for _, e := range execs {
+		if err := s.AddExec(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name   string
+		exec   TPQ
+		time   time.Time
+		ohlcv  OHLCV
+		sma    float64
+		hasSMA bool
+	}{
+		{
+			name:   "last exec of interval",
+			exec:   TPQ{Timestamp: start.Add(time.Minute + 50*time.Second), Px: 14, Qty: 2},
+			time:   start.Add(time.Minute),
+			ohlcv:  OHLCV{O: 14, H: 14, L: 14, C: 14, V: 2, S: start.Add(time.Minute)},
+			sma:    13,
+			hasSMA: true,
+		},
+		{
+			name:   "first exec of interval",
+			exec:   TPQ{Timestamp: start.Add(time.Minute + 10*time.Second), Px: 11, Qty: 1},
+			time:   start.Add(time.Minute),
+			ohlcv:  OHLCV{O: 11, H: 14, L: 11, C: 14, V: 3, S: start.Add(time.Minute)},
+			sma:    13,
+			hasSMA: true,
+		},
+		{
+			name:   "exec between others",
+			exec:   TPQ{Timestamp: start.Add(time.Minute + 30*time.Second), Px: 16, Qty: 1},
+			time:   start.Add(time.Minute),
+			ohlcv:  OHLCV{O: 11, H: 16, L: 11, C: 14, V: 4, S: start.Add(time.Minute)},
+			sma:    13,
+			hasSMA: true,
+		},
+	}
+	for _, tt := range tests {
+		if err := s.AddExec(tt.exec); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		v := s.GetValueForInterval(tt.time)
+		if v == nil || *v.OHLCV != tt.ohlcv {
+			t.Fatalf("%s: expected %+v but got %+v", tt.name, tt.ohlcv, v)
+		}
+		sma := s.GetValueForInterval(start.Add(2 * time.Minute)).Indicators["sma"]
+		if (sma != nil) != tt.hasSMA || (sma != nil && *sma != tt.sma) {
+			t.Errorf("%s: expected sma %v but got %v", tt.name, tt.sma, sma)
+		}
+	}
+	// the first interval is no longer kept
+	if err := s.AddExec(TPQ{Timestamp: start, Px: 1, Qty: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if s.DroppedExecs() != 1 {
+		t.Errorf("expected 1 dropped exec but got %d", s.DroppedExecs())
+	}
+	// filled intervals keep the close they were filled with
+	sma := s.GetValueForInterval(start.Add(3 * time.Minute)).Indicators["sma"]
+	if sma == nil || *sma != 16 {
+		t.Errorf("expected sma 16 but got %v", sma)
+	}
+}
+
+func TestSeriesOutOfOrderExecs(t *testing.T) {
+	start := time.Date(2022, 1, 3, 10, 0, 0, 0, time.UTC)
+	s, err := NewSeries(nil, SeriesOpts{Interval: 60, Max: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	execs := []TPQ{
+		{Timestamp: start.Add(20 * time.Second), Px: 2, Qty: 1},
+		{Timestamp: start.Add(40 * time.Second), Px: 3, Qty: 1},
+		{Timestamp: start.Add(10 * time.Second), Px: 1, Qty: 1},
+		{Timestamp: start.Add(30 * time.Second), Px: 4, Qty: 1},
+	}
+	for _, e := range execs {
+		if err := s.AddExec(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exp := OHLCV{O: 1, H: 4, L: 1, C: 3, V: 4, S: start}
+	if v := s.GetValueForInterval(start); v == nil || *v.OHLCV != exp {
+		t.Errorf("expected %+v but got %+v", exp, v)
+	}
+}
+
+func TestSeriesLateExecAmendRecomputes(t *testing.T) {
+	start := time.Date(2022, 1, 3, 10, 0, 0, 0, time.UTC)
+	indicators := func() map[string]Indicator {
+		close := NewOHLCProp(OHLCPropClose)
+		macd := NewMACD(close, 3, 6, 2)
+		bb := NewBollingerBands(close, 4, 2)
+		kc := NewKeltnerChannels(close, 4, 2, true)
+		stoch := NewStoch(4, 2, 2)
+		vwap := NewVWAP(VWAPOpts{BandMults: []float64{1}})
+		tf, err := NewTimeframe(SeriesOpts{Interval: 180, Max: 20})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return map[string]Indicator{
+			"ema":        NewEMA(close, 3),
+			"rsi":        NewRSI(close, 3),
+			"atr":        NewATR(3),
+			"tr":         NewTrueRange(),
+			"stddev":     NewStdDev(close, 3),
+			"linreg":     NewLinReg(close, 3),
+			"highest":    NewHighestBars(close, 3),
+			"wpr":        NewWilliamsR(3),
+			"hma":        NewHMA(close, 4),
+			"tema":       NewTEMA(close, 3),
+			"zlema":      NewZLEMA(close, 3),
+			"vwma":       NewVWMA(close, 3),
+			"median":     NewMedian(close, 3),
+			"rank":       NewPercentRank(close, 3),
+			"cross":      NewCrossover(close, NewSMA(close, 3)),
+			"change":     NewChange(close, 2, nil),
+			"macd":       macd.Histogram,
+			"bb":         bb.PercentB,
+			"kc":         kc.Upper,
+			"stoch":      stoch.D,
+			"vwap":       vwap.Upper[0],
+			"security":   NewSecurity(tf, NewSMA(NewOHLCProp(OHLCPropClose), 2), LookaheadOff),
+			"arithmetic": NewArithmetic(ArithmeticSubtraction, close, NewRMA(close, 3), ArithmeticOpts{}),
+		}
+	}
+	newSeries := func(max int) Series {
+		s, err := NewSeries(nil, SeriesOpts{Interval: 60, Max: max, LateInst: LateInstAmend})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, ind := range indicators() {
+			if err := s.AddIndicator(name, ind); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return s
+	}
+	var execs []TPQ
+	for j := 0; j < 15; j++ {
+		execs = append(execs, TPQ{
+			Timestamp: start.Add(time.Duration(j) * time.Minute),
+			Px:        float64(10 + (j*7)%5),
+			Qty:       float64(1 + j%3),
+		})
+	}
+	tests := []struct {
+		name string
+		max  int
+		late TPQ
+	}{
+		{
+			name: "all intervals kept",
+			max:  20,
+			late: TPQ{Timestamp: start.Add(5*time.Minute + 30*time.Second), Px: 30, Qty: 4},
+		},
+		{
+			name: "oldest kept interval",
+			max:  8,
+			late: TPQ{Timestamp: start.Add(7*time.Minute + 30*time.Second), Px: 30, Qty: 4},
+		},
+	}
+	for _, tt := range tests {
+		amended := newSeries(tt.max)
+		for _, e := range execs {
+			if err := amended.AddExec(e); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := amended.AddExec(tt.late); err != nil {
+			t.Fatal(err)
+		}
+		// the same execs in order
+		k := int(tt.late.Timestamp.Sub(start)/time.Minute) + 1
+		ordered := newSeries(tt.max)
+		for _, e := range append(append(append([]TPQ{}, execs[:k]...), tt.late), execs[k:]...) {
+			if err := ordered.AddExec(e); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for j := range execs {
+			it := start.Add(time.Duration(j) * time.Minute)
+			a := amended.GetValueForInterval(it)
+			o := ordered.GetValueForInterval(it)
+			if o == nil {
+				if a != nil {
+					t.Errorf("%s: expected no values at %v but got %+v", tt.name, it, a)
+				}
+				continue
+			}
+			if *a.OHLCV != *o.OHLCV {
+				t.Errorf("%s: expected %+v at %v but got %+v", tt.name, *o.OHLCV, it, *a.OHLCV)
+			}
+			for name, ov := range o.Indicators {
+				if err := compareValue(ov, a.Indicators[name], 1e-9); err != nil {
+					t.Errorf("%s: %s at %v: %v", tt.name, name, it, err)
+				}
+			}
+			if len(a.Indicators) != len(o.Indicators) {
+				t.Errorf("%s: expected %d indicator values at %v but got %d", tt.name, len(o.Indicators), it, len(a.Indicators))
+			}
+		}
+	}
+}
+
+func TestSeriesLateExecAmendKeepsHistory(t *testing.T) {
+	start := time.Date(2022, 1, 3, 10, 0, 0, 0, time.UTC)
+	s, err := NewSeries(nil, SeriesOpts{Interval: 60, Max: 5, LateInst: LateInstAmend})
+	if err != nil {
+		t.Fatal(err)
+	}
+	close := NewOHLCProp(OHLCPropClose)
+	macd := NewMACD(close, 2, 4, 2)
+	indicators := map[string]Indicator{
+		"ema":  NewEMA(close, 3),
+		"rsi":  NewRSI(close, 3),
+		"macd": macd.Signal,
+	}
+	for name, ind := range indicators {
+		if err := s.AddIndicator(name, ind); err != nil {
+			t.Fatal(err)
+		}
+	}
+	px := func(j int) float64 {
+		return float64(100 + (j*7)%11)
+	}
+	for j := 0; j < 50; j++ {
+		e := TPQ{Timestamp: start.Add(time.Duration(j) * time.Minute), Px: px(j), Qty: 1}
+		if err := s.AddExec(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	last := start.Add(49 * time.Minute)
+	before := s.GetValueForInterval(last).Indicators
+	// a late exec at the close price leaves the close of every interval unchanged
+	amend := start.Add(46 * time.Minute)
+	if err := s.AddExec(TPQ{Timestamp: amend.Add(30 * time.Second), Px: px(46), Qty: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if v := s.GetValueForInterval(amend); v == nil || v.OHLCV.V != 2 {
+		t.Fatalf("expected amended interval but got %+v", v)
+	}
+	after := s.GetValueForInterval(last).Indicators
+	for name := range indicators {
+		if before[name] == nil {
+			t.Fatalf("%s: expected a value", name)
+		}
+		if err := compareValue(before[name], after[name], 0); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+// closes is an indicator of another package so series cannot rewind it
+type closes map[time.Time]float64
+
+func (i closes) GetValueForInterval(t time.Time) *Interval {
+	v, ok := i[t]
+	if !ok {
+		return nil
+	}
+	return &Interval{
+		StartTime: t,
+		Value:     v,
+	}
+}
+
+func (i closes) Update(v OHLCV) error {
+	i[v.S] = v.C
+	return nil
+}
+
+func (i closes) ApplyOpts(opts SeriesOpts) error {
+	return nil
+}
+
+func TestSeriesLateExecAmendResets(t *testing.T) {
+	start := time.Date(2022, 1, 3, 10, 0, 0, 0, time.UTC)
+	s, err := NewSeries(nil, SeriesOpts{Interval: 60, Max: 10, LateInst: LateInstAmend})
+	if err != nil {
+		t.Fatal(err)
+	}
+	indicators := map[string]Indicator{
+		"ema":      NewEMA(NewOHLCProp(OHLCPropClose), 2),
+		"external": NewSMA(closes{}, 2),
+	}
+	for name, ind := range indicators {
+		if err := s.AddIndicator(name, ind); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for j := 0; j < 5; j++ {
+		if err := s.AddExec(TPQ{Timestamp: start.Add(time.Duration(j) * time.Minute), Px: float64(10 + j), Qty: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// closes are 10, 11, 20, 13 and 14
+	if err := s.AddExec(TPQ{Timestamp: start.Add(2*time.Minute + 30*time.Second), Px: 20, Qty: 1}); err != nil {
+		t.Fatal(err)
+	}
+	v := s.GetValueForInterval(start.Add(4 * time.Minute))
+	if sma := v.Indicators["external"]; sma == nil || *sma != 13.5 {
+		t.Errorf("expected sma 13.5 but got %v", sma)
+	}
+	if sma := s.GetValueForInterval(start.Add(3 * time.Minute)).Indicators["external"]; sma == nil || *sma != 16.5 {
+		t.Errorf("expected sma 16.5 but got %v", sma)
+	}
+	// ema seeded with 10.5 at the second interval
+	ema := 10.5
+	for _, c := range []float64{20, 13, 14} {
+		ema += (c - ema) * 2 / 3
+	}
+	if err := compareValue(fptr(ema), v.Indicators["ema"], 1e-9); err != nil {
+		t.Error(err)
+	}
+}
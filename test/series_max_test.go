@@ -0,0 +1,71 @@
+package pine_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/xpt-nl/pine"
+)
+
+func TestSeriesMaxEvictsIntervals(t *testing.T) {
+	max := 5
+	opts := SeriesOpts{
+		Interval: 300,
+		Max:      max,
+	}
+	s, err := NewSeries(nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	close := NewOHLCProp(OHLCPropClose)
+	inds := map[string]Indicator{
+		"close":  close,
+		"sma":    NewSMA(close, 2),
+		"ema":    NewEMA(close, 2),
+		"rsi":    NewRSI(close, 2),
+		"stddev": NewStdDev(close, 2),
+		"median": NewMedian(close, 2),
+		"linreg": NewLinReg(close, 2),
+		"wma":    NewWMA(close, 2),
+	}
+	for name, ind := range inds {
+		if err := s.AddIndicator(name, ind); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	total := 1000
+	times := make([]time.Time, total)
+	for idx := 0; idx < total; idx++ {
+		times[idx] = now.Add(time.Duration(idx*5) * time.Minute)
+		px := float64(idx%7 + 1)
+		v := OHLCV{O: px, H: px, L: px, C: px, V: 1, S: times[idx]}
+		if err := s.AddOHLCV(v); err != nil {
+			t.Fatal(fmt.Errorf("error adding ohlcv: %+v: %w", v, err))
+		}
+	}
+
+	for idx := 0; idx < total-max; idx++ {
+		if v := s.GetValueForInterval(times[idx]); v != nil {
+			t.Fatalf("expected evicted interval to be nil at idx %d but got %+v", idx, v)
+		}
+		start := times[idx].Truncate(time.Duration(opts.Interval) * time.Second).UTC()
+		for name, ind := range inds {
+			if v := ind.GetValueForInterval(start); v != nil {
+				t.Fatalf("expected %s to have evicted idx %d but got %+v", name, idx, v)
+			}
+		}
+	}
+	for idx := total - max; idx < total; idx++ {
+		v := s.GetValueForInterval(times[idx])
+		if v == nil {
+			t.Fatalf("expected interval to be kept at idx %d", idx)
+		}
+		for name, ind := range inds {
+			if v.Indicators[name] == nil || ind.GetValueForInterval(v.StartTime) == nil {
+				t.Errorf("expected %s to have value at idx %d", name, idx)
+			}
+		}
+	}
+}
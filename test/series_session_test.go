@@ -0,0 +1,278 @@
+package pine_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/xpt-nl/pine"
+)
+
+func TestSeriesSessionGapFill(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone data not available")
+	}
+	s, err := NewSeries(nil, SeriesOpts{
+		Interval: 1800,
+		Location: ny,
+		Max:      100,
+		Session: &Session{
+			Regular:  []SessionHours{{Start: 9*time.Hour + 30*time.Minute, End: 16 * time.Hour}},
+			Holidays: []time.Time{time.Date(2022, 1, 17, 0, 0, 0, 0, time.UTC)},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// friday before a long weekend and the tuesday after the holiday
+	fri := time.Date(2022, 1, 14, 15, 30, 0, 0, ny)
+	tue := time.Date(2022, 1, 18, 10, 30, 0, 0, ny)
+	if err := s.AddOHLCV(OHLCV{O: 1, H: 1, L: 1, C: 1, S: fri}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddOHLCV(OHLCV{O: 2, H: 2, L: 2, C: 2, S: tue}); err != nil {
+		t.Fatal(err)
+	}
+	for _, closed := range []time.Time{
+		time.Date(2022, 1, 14, 16, 0, 0, 0, ny),
+		time.Date(2022, 1, 15, 10, 0, 0, 0, ny),
+		time.Date(2022, 1, 17, 10, 0, 0, 0, ny),
+		time.Date(2022, 1, 18, 9, 0, 0, 0, ny),
+	} {
+		if v := s.GetValueForInterval(closed); v != nil {
+			t.Errorf("expected no interval at %v but got %+v", closed, v)
+		}
+	}
+	for _, filled := range []time.Time{
+		time.Date(2022, 1, 18, 9, 30, 0, 0, ny),
+		time.Date(2022, 1, 18, 10, 0, 0, 0, ny),
+	} {
+		v := s.GetValueForInterval(filled)
+		if v == nil || v.OHLCV.C != 1 || v.OHLCV.V != 0 {
+			t.Errorf("expected filled interval at %v but got %+v", filled, v)
+		}
+	}
+	if v := s.GetValueForInterval(tue); v == nil || v.OHLCV.C != 2 || !v.Session.IsMarket() {
+		t.Errorf("expected regular interval at %v but got %+v", tue, v)
+	}
+}
+
+func TestSeriesSessionDaily(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone data not available")
+	}
+	s, err := NewSeries(nil, SeriesOpts{
+		Interval:     1,
+		IntervalUnit: IntervalUnitDay,
+		Location:     ny,
+		Max:          10,
+		Session: &Session{
+			Regular:  []SessionHours{{Start: 9*time.Hour + 30*time.Minute, End: 16 * time.Hour}},
+			Holidays: []time.Time{time.Date(2022, 1, 17, 0, 0, 0, 0, time.UTC)},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddOHLCV(OHLCV{C: 1, S: time.Date(2022, 1, 13, 0, 0, 0, 0, ny)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddOHLCV(OHLCV{C: 2, S: time.Date(2022, 1, 19, 0, 0, 0, 0, ny)}); err != nil {
+		t.Fatal(err)
+	}
+	for d, exists := range map[int]bool{14: true, 15: false, 16: false, 17: false, 18: true} {
+		v := s.GetValueForInterval(time.Date(2022, 1, d, 0, 0, 0, 0, ny))
+		if (v != nil) != exists {
+			t.Errorf("expected interval on day %d to exist %v but got %+v", d, exists, v)
+		}
+	}
+}
+
+func TestSeriesSessionExtendedHours(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone data not available")
+	}
+	session := Session{
+		Regular:    []SessionHours{{Start: 9*time.Hour + 30*time.Minute, End: 16 * time.Hour}},
+		PreMarket:  []SessionHours{{Start: 4 * time.Hour, End: 9*time.Hour + 30*time.Minute}},
+		PostMarket: []SessionHours{{Start: 16 * time.Hour, End: 20 * time.Hour}},
+	}
+	tests := []struct {
+		name     string
+		extended bool
+		time     time.Time
+		exists   bool
+		state    SessionState
+	}{
+		{
+			name:     "premarket",
+			extended: true,
+			time:     time.Date(2022, 1, 14, 8, 0, 0, 0, ny),
+			exists:   true,
+			state:    SessionPreMarket,
+		},
+		{
+			name:     "postmarket",
+			extended: true,
+			time:     time.Date(2022, 1, 14, 17, 0, 0, 0, ny),
+			exists:   true,
+			state:    SessionPostMarket,
+		},
+		{
+			name:     "regular",
+			extended: true,
+			time:     time.Date(2022, 1, 14, 10, 0, 0, 0, ny),
+			exists:   true,
+			state:    SessionRegular,
+		},
+		{
+			name:     "overnight",
+			extended: true,
+			time:     time.Date(2022, 1, 14, 2, 0, 0, 0, ny),
+		},
+		{
+			name: "premarket without extended hours",
+			time: time.Date(2022, 1, 14, 8, 0, 0, 0, ny),
+		},
+	}
+	for _, tt := range tests {
+		sess := session
+		sess.Extended = tt.extended
+		s, err := NewSeries(nil, SeriesOpts{Interval: 1800, Location: ny, Max: 10, Session: &sess})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if err := s.AddExec(TPQ{Timestamp: tt.time, Px: 1, Qty: 1}); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		v := s.GetValueForInterval(tt.time)
+		if (v != nil) != tt.exists {
+			t.Fatalf("%s: expected interval to exist %v but got %+v", tt.name, tt.exists, v)
+		}
+		if v != nil && v.Session != tt.state {
+			t.Errorf("%s: expected session %v but got %v", tt.name, tt.state, v.Session)
+		}
+	}
+}
+
+func TestSeriesSessionOvernight(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone data not available")
+	}
+	// futures trade from 18:00 to 17:00 the next day, sunday evening
+	// belongs to monday
+	s, err := NewSeries(nil, SeriesOpts{
+		Interval: 3600,
+		Location: ny,
+		Max:      100,
+		Session: &Session{
+			Regular: []SessionHours{{Start: 18 * time.Hour, End: 17 * time.Hour}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fri := time.Date(2022, 1, 14, 16, 0, 0, 0, ny)
+	sun := time.Date(2022, 1, 16, 19, 0, 0, 0, ny)
+	if err := s.AddOHLCV(OHLCV{C: 1, S: fri}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddOHLCV(OHLCV{C: 2, S: sun}); err != nil {
+		t.Fatal(err)
+	}
+	for _, closed := range []time.Time{
+		time.Date(2022, 1, 14, 17, 0, 0, 0, ny),
+		time.Date(2022, 1, 15, 12, 0, 0, 0, ny),
+		time.Date(2022, 1, 16, 17, 0, 0, 0, ny),
+	} {
+		if v := s.GetValueForInterval(closed); v != nil {
+			t.Errorf("expected no interval at %v but got %+v", closed, v)
+		}
+	}
+	if v := s.GetValueForInterval(time.Date(2022, 1, 16, 18, 0, 0, 0, ny)); v == nil || v.OHLCV.C != 1 {
+		t.Errorf("expected filled interval at sunday open but got %+v", v)
+	}
+	// the daily maintenance break is skipped
+	if err := s.AddOHLCV(OHLCV{C: 3, S: time.Date(2022, 1, 17, 19, 0, 0, 0, ny)}); err != nil {
+		t.Fatal(err)
+	}
+	if v := s.GetValueForInterval(time.Date(2022, 1, 17, 17, 0, 0, 0, ny)); v != nil {
+		t.Errorf("expected no interval during maintenance but got %+v", v)
+	}
+	if v := s.GetValueForInterval(time.Date(2022, 1, 17, 18, 0, 0, 0, ny)); v == nil || !v.Session.IsMarket() {
+		t.Errorf("expected interval after maintenance but got %+v", v)
+	}
+}
+
+func TestSeriesSessionValidation(t *testing.T) {
+	for _, sess := range []*Session{
+		{},
+		{Regular: []SessionHours{{Start: 25 * time.Hour, End: 16 * time.Hour}}},
+	} {
+		if _, err := NewSeries(nil, SeriesOpts{Interval: 60, Max: 10, Session: sess}); err == nil {
+			t.Errorf("expected error for session %+v", sess)
+		}
+	}
+}
+
+func TestSeriesSessionPrevious(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone data not available")
+	}
+	s, err := NewSeries(nil, SeriesOpts{
+		Interval: 1800,
+		Location: ny,
+		Max:      100,
+		Session: &Session{
+			Regular: []SessionHours{{Start: 9*time.Hour + 30*time.Minute, End: 16 * time.Hour}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	close := NewOHLCProp(OHLCPropClose)
+	if err := s.AddIndicator("prev", NewPrevious(close, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddIndicator("change", NewChange(close, 2, nil)); err != nil {
+		t.Fatal(err)
+	}
+	// last bars of friday's session and first bars after the weekend
+	bars := []time.Time{
+		time.Date(2022, 1, 14, 15, 0, 0, 0, ny),
+		time.Date(2022, 1, 14, 15, 30, 0, 0, ny),
+		time.Date(2022, 1, 17, 9, 30, 0, 0, ny),
+		time.Date(2022, 1, 17, 10, 0, 0, 0, ny),
+	}
+	for idx, bt := range bars {
+		px := float64(idx + 1)
+		if err := s.AddOHLCV(OHLCV{O: px, H: px, L: px, C: px, S: bt}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	io := []struct {
+		time   time.Time
+		prev   *float64
+		change *float64
+	}{
+		{bars[0], nil, nil},
+		{bars[1], fptr(1), nil},
+		{bars[2], fptr(2), fptr(2)},
+		{bars[3], fptr(3), fptr(2)},
+	}
+	for _, o := range io {
+		v := s.GetValueForInterval(o.time)
+		if v == nil {
+			t.Fatalf("expected interval at %v", o.time)
+		}
+		for name, exp := range map[string]*float64{"prev": o.prev, "change": o.change} {
+			if err := compareValue(exp, v.Indicators[name], 0); err != nil {
+				t.Errorf("%s at %v: %v", name, o.time, err)
+			}
+		}
+	}
+}
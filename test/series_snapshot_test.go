@@ -0,0 +1,192 @@
+package pine_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	. "github.com/xpt-nl/pine"
+)
+
+type namedIndicator struct {
+	name string
+	ind  Indicator
+}
+
+func snapshotIndicators(t *testing.T) []namedIndicator {
+	close := NewOHLCProp(OHLCPropClose)
+	macd := NewMACD(close, 3, 6, 2)
+	bb := NewBollingerBands(close, 4, 2)
+	kc := NewKeltnerChannels(close, 4, 2, true)
+	stoch := NewStoch(4, 2, 2)
+	vwap := NewVWAP(VWAPOpts{BandMults: []float64{1}})
+	tf, err := NewTimeframe(SeriesOpts{Interval: 180, Max: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []namedIndicator{
+		{"ema", NewEMA(close, 3)},
+		{"rsi", NewRSI(close, 3)},
+		{"atr", NewATR(3)},
+		{"tr", NewTrueRange()},
+		{"stddev", NewStdDev(close, 3)},
+		{"linreg", NewLinReg(close, 3)},
+		{"highest", NewHighestBars(close, 3)},
+		{"wpr", NewWilliamsR(3)},
+		{"hma", NewHMA(close, 4)},
+		{"dema", NewDEMA(close, 3)},
+		{"tema", NewTEMA(close, 3)},
+		{"zlema", NewZLEMA(close, 3)},
+		{"vwma", NewVWMA(close, 3)},
+		{"wma", NewWMA(close, 3)},
+		{"median", NewMedian(close, 3)},
+		{"rank", NewPercentRank(close, 3)},
+		{"cross", NewCrossover(close, NewSMA(close, 3))},
+		{"change", NewChange(close, 2, nil)},
+		{"macd", macd.Histogram},
+		{"bb", bb.PercentB},
+		{"kc", kc.Upper},
+		{"stoch", stoch.D},
+		{"vwap", vwap.Upper[0]},
+		{"security", NewSecurity(tf, NewSMA(NewOHLCProp(OHLCPropClose), 2), LookaheadOff)},
+		{"arithmetic", NewArithmetic(ArithmeticSubtraction, close, NewRMA(close, 3), ArithmeticOpts{})},
+	}
+}
+
+func newSnapshotSeries(t *testing.T, inds []namedIndicator) Series {
+	s, err := NewSeries(nil, SeriesOpts{Interval: 60, Max: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ni := range inds {
+		if err := s.AddIndicator(ni.name, ni.ind); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+func snapshotExec(start time.Time, j int) TPQ {
+	return TPQ{
+		Timestamp: start.Add(time.Duration(j) * 40 * time.Second),
+		Px:        float64(10 + (j*7)%5),
+		Qty:       float64(1 + j%3),
+	}
+}
+
+func TestSeriesSnapshotRestore(t *testing.T) {
+	start := time.Date(2022, 1, 3, 10, 0, 0, 0, time.UTC)
+	orig := newSnapshotSeries(t, snapshotIndicators(t))
+	for j := 0; j < 40; j++ {
+		if err := orig.AddExec(snapshotExec(start, j)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := orig.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := newSnapshotSeries(t, snapshotIndicators(t))
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	for j := 40; j < 80; j++ {
+		for _, s := range []Series{orig, restored} {
+			if err := s.AddExec(snapshotExec(start, j)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// the last 20 intervals are kept
+	for j := 0; j < 20; j++ {
+		it := start.Add(time.Duration(52-j) * time.Minute)
+		o := orig.GetValueForInterval(it)
+		r := restored.GetValueForInterval(it)
+		if o == nil || r == nil {
+			t.Fatalf("expected interval %v but got %+v and %+v", it, o, r)
+		}
+		if *r.OHLCV != *o.OHLCV {
+			t.Errorf("expected %+v at %v but got %+v", *o.OHLCV, it, *r.OHLCV)
+		}
+		for name, ov := range o.Indicators {
+			rv := r.Indicators[name]
+			if rv == nil || math.Abs(*rv-*ov) > 1e-9 {
+				t.Errorf("%s: expected %v at %v but got %v", name, *ov, it, rv)
+			}
+		}
+		if len(r.Indicators) != len(o.Indicators) {
+			t.Errorf("expected %d indicator values at %v but got %d", len(o.Indicators), it, len(r.Indicators))
+		}
+	}
+}
+
+func TestSeriesSnapshotErrors(t *testing.T) {
+	start := time.Date(2022, 1, 3, 10, 0, 0, 0, time.UTC)
+	s := newSnapshotSeries(t, []namedIndicator{{"sma", NewSMA(NewOHLCProp(OHLCPropClose), 2)}})
+	for j := 0; j < 5; j++ {
+		if err := s.AddExec(snapshotExec(start, j)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newer := append([]byte("pine"), 0x7e)
+	newer = append(newer, data[5:]...)
+	if err := s.UnmarshalBinary(newer); !errors.Is(err, ErrSnapshotVersion) {
+		t.Errorf("expected ErrSnapshotVersion but got %v", err)
+	}
+
+	other := newSnapshotSeries(t, []namedIndicator{{"sma", NewEMA(NewOHLCProp(OHLCPropClose), 2)}})
+	if err := other.AddExec(TPQ{Timestamp: start, Px: 99, Qty: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.UnmarshalBinary(data); !errors.Is(err, ErrSnapshotMismatch) {
+		t.Errorf("expected ErrSnapshotMismatch but got %v", err)
+	}
+	if v := other.GetValueForInterval(start); v == nil || v.OHLCV.C != 99 {
+		t.Errorf("expected series to be unchanged but got %+v", v)
+	}
+	if v := other.GetValueForInterval(start.Add(time.Minute)); v != nil {
+		t.Errorf("expected no interval to be restored but got %+v", v)
+	}
+
+	if err := s.UnmarshalBinary(data[:len(data)-3]); err == nil {
+		t.Error("expected error restoring truncated snapshot")
+	}
+
+	ext := newSnapshotSeries(t, []namedIndicator{{"close", valueClose{vals: make(map[time.Time]float64)}}})
+	if _, err := ext.MarshalBinary(); err == nil {
+		t.Error("expected error saving indicator without BinaryMarshaler")
+	}
+}
+
+func TestSeriesSnapshotReplacedIndicator(t *testing.T) {
+	start := time.Date(2022, 1, 3, 10, 0, 0, 0, time.UTC)
+	close := NewOHLCProp(OHLCPropClose)
+	s := newSnapshotSeries(t, []namedIndicator{{"ma", NewSMA(NewEMA(close, 3), 2)}})
+	for j := 0; j < 5; j++ {
+		if err := s.AddExec(snapshotExec(start, j)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.AddIndicator("ma", NewSMA(close, 2)); err != nil {
+		t.Fatal(err)
+	}
+	data, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the nodes of the replaced indicator are not saved
+	restored := newSnapshotSeries(t, []namedIndicator{{"ma", NewSMA(NewOHLCProp(OHLCPropClose), 2)}})
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	it := start.Add(2 * time.Minute)
+	if err := compareValue(s.GetValueForInterval(it).Indicators["ma"], restored.GetValueForInterval(it).Indicators["ma"], 0); err != nil {
+		t.Error(err)
+	}
+}
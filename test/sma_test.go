@@ -92,3 +92,16 @@ func TestSMA(t *testing.T) {
 	}
 
 }
+
+func TestSMAWithoutSeries(t *testing.T) {
+	now := time.Now()
+	sma := NewSMA(NewOHLCProp(OHLCPropClose), 1)
+	v := OHLCV{O: 14, H: 15, L: 13, C: 14, V: 131, S: now}
+	if err := sma.Update(v); err != nil {
+		t.Fatal(err)
+	}
+	res := sma.GetValueForInterval(now)
+	if res == nil || res.Value != 14 {
+		t.Errorf("expected 14 but got %+v", res)
+	}
+}
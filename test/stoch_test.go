@@ -0,0 +1,74 @@
+package pine_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/xpt-nl/pine"
+)
+
+func TestStoch(t *testing.T) {
+	itvl := 300
+	opts := SeriesOpts{
+		Interval: itvl,
+		Max:      100,
+	}
+	candles := []OHLCV{
+		{H: 53.0, L: 51.9, C: 52.22},
+		{H: 53.2, L: 52.1, C: 52.78},
+		{H: 53.5, L: 52.6, C: 53.02},
+		{H: 54.0, L: 53.0, C: 53.67},
+		{H: 53.9, L: 53.1, C: 53.67},
+		{H: 54.1, L: 53.3, C: 53.74},
+		{H: 53.8, L: 53.2, C: 53.45},
+		{H: 53.9, L: 53.3, C: 53.72},
+		{H: 53.6, L: 53.0, C: 53.39},
+		{H: 52.9, L: 52.3, C: 52.51},
+		{H: 52.6, L: 51.9, C: 52.32},
+		{H: 51.9, L: 51.2, C: 51.45},
+	}
+	io := []struct {
+		k *float64
+		d *float64
+	}{
+		{},
+		{},
+		{},
+		{},
+		{},
+		{k: fptr(80.972431)},
+		{k: fptr(66.513557), d: fptr(73.742994)},
+		{k: fptr(59.636364), d: fptr(63.07496)},
+		{k: fptr(46.121212), d: fptr(52.878788)},
+		{k: fptr(36.859848), d: fptr(41.49053)},
+		{k: fptr(23.193182), d: fptr(30.026515)},
+		{k: fptr(14.847222), d: fptr(19.020202)},
+	}
+	now := time.Now()
+	for idx := range candles {
+		candles[idx].S = now.Add(time.Duration(idx*itvl) * time.Second)
+	}
+	s, err := NewSeries(candles, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := NewStoch(4, 3, 2)
+	if err := s.AddIndicator("k", st.K); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddIndicator("d", st.D); err != nil {
+		t.Fatal(err)
+	}
+	for idx, o := range io {
+		v := s.GetValueForInterval(candles[idx].S)
+		if v == nil {
+			t.Fatalf("interval should not be nil at idx: %d", idx)
+		}
+		if err := compareValue(o.k, v.Indicators["k"], 0.000001); err != nil {
+			t.Errorf("k at idx %d: %+v", idx, err)
+		}
+		if err := compareValue(o.d, v.Indicators["d"], 0.000001); err != nil {
+			t.Errorf("d at idx %d: %+v", idx, err)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package pine_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	. "github.com/xpt-nl/pine"
+	"github.com/xpt-nl/pine/strategy"
+)
+
+// strategyBars returns bars a minute apart from OHLC prices
+func strategyBars(prices [][4]float64) []OHLCV {
+	start := time.Date(2022, 1, 3, 10, 0, 0, 0, time.UTC)
+	bars := make([]OHLCV, len(prices))
+	for j, p := range prices {
+		bars[j] = OHLCV{O: p[0], H: p[1], L: p[2], C: p[3], V: 1, S: start.Add(time.Duration(j) * time.Minute)}
+	}
+	return bars
+}
+
+// runStrategy calls onBar with the index of every bar and closes the last bar
+// with an extra one
+func runStrategy(t *testing.T, opts strategy.Opts, bars []OHLCV, onBar func(st *strategy.Strategy, j int)) *strategy.Strategy {
+	t.Helper()
+	s, err := NewSeries(nil, SeriesOpts{Interval: 60, Max: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	j := 0
+	st, err := strategy.New(s, opts, func(st *strategy.Strategy, itvl *Interval) {
+		if !itvl.StartTime.Equal(bars[j].S) {
+			t.Fatalf("expected bar at %v but got %v", bars[j].S, itvl.StartTime)
+		}
+		onBar(st, j)
+		j++
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range bars {
+		if err := s.AddOHLCV(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	last := bars[len(bars)-1]
+	if err := s.AddOHLCV(OHLCV{O: last.C, H: last.C, L: last.C, C: last.C, S: last.S.Add(time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	st.Stop()
+	return st
+}
+
+type expectedTrade struct {
+	entryID  string
+	dir      strategy.Direction
+	entryBar int
+	entry    float64
+	exitID   string
+	exitBar  int
+	exit     float64
+	qty      float64
+	profit   float64
+}
+
+func compareTrades(t *testing.T, bars []OHLCV, expected []expectedTrade, trades []strategy.Trade) {
+	t.Helper()
+	if len(trades) != len(expected) {
+		t.Fatalf("expected %d trades but got %+v", len(expected), trades)
+	}
+	for j, e := range expected {
+		tr := trades[j]
+		if tr.EntryID != e.entryID || tr.Direction != e.dir || tr.Qty != e.qty ||
+			!tr.EntryTime.Equal(bars[e.entryBar].S) || tr.EntryPrice != e.entry ||
+			tr.ExitID != e.exitID || !tr.ExitTime.Equal(bars[e.exitBar].S) || tr.ExitPrice != e.exit ||
+			math.Abs(tr.Profit-e.profit) > 1e-9 {
+			t.Errorf("trade %d: expected %+v but got %+v", j, e, tr)
+		}
+	}
+}
+
+func TestStrategyMarketOrders(t *testing.T) {
+	bars := strategyBars([][4]float64{
+		{10, 11, 9, 10},
+		{11, 12, 10, 11},
+		{12, 13, 11, 12},
+		{13, 14, 12, 13},
+		{12, 13, 11, 12},
+		{11, 12, 10, 11},
+		{10, 11, 9, 10},
+	})
+	st := runStrategy(t, strategy.Opts{InitialCapital: 1000, DefaultQty: 2}, bars, func(st *strategy.Strategy, j int) {
+		switch j {
+		case 0:
+			if err := st.Entry("long", strategy.Long, strategy.EntryOpts{}); err != nil {
+				t.Fatal(err)
+			}
+		case 1:
+			// ignored without pyramiding
+			if err := st.Entry("long2", strategy.Long, strategy.EntryOpts{Qty: 1}); err != nil {
+				t.Fatal(err)
+			}
+			if p := st.Position(); p.Size != 2 || p.AvgPrice != 11 {
+				t.Errorf("expected position 2 at 11 but got %+v", p)
+			}
+			if st.OpenProfit() != 0 {
+				t.Errorf("expected no open profit but got %v", st.OpenProfit())
+			}
+		case 2:
+			if st.OpenProfit() != 2 || st.Equity() != 1002 {
+				t.Errorf("expected open profit 2 and equity 1002 but got %v and %v", st.OpenProfit(), st.Equity())
+			}
+			// reverses the position
+			if err := st.Entry("short", strategy.Short, strategy.EntryOpts{Qty: 1}); err != nil {
+				t.Fatal(err)
+			}
+		case 4:
+			st.Close("short")
+		}
+	})
+	compareTrades(t, bars, []expectedTrade{
+		{"long", strategy.Long, 1, 11, "short", 3, 13, 2, 4},
+		{"short", strategy.Short, 3, 13, "short", 5, 11, 1, 2},
+	}, st.ClosedTrades())
+	if len(st.OpenTrades()) != 0 || st.Position().Size != 0 {
+		t.Errorf("expected no open trades but got %+v", st.OpenTrades())
+	}
+	if st.NetProfit() != 6 || st.Equity() != 1006 {
+		t.Errorf("expected net profit 6 and equity 1006 but got %v and %v", st.NetProfit(), st.Equity())
+	}
+	// equity at the closes is 1000, 1000, 1002, 1004, 1005, 1006, 1006
+	if st.MaxDrawdown() != 0 {
+		t.Errorf("expected no drawdown but got %v", st.MaxDrawdown())
+	}
+}
+
+func TestStrategyExitPath(t *testing.T) {
+	bars := strategyBars([][4]float64{
+		{100, 101, 99, 100},
+		// open closer to high so the take profit at 104 is reached before
+		// the stop loss at 96
+		{100, 104.5, 95, 100},
+		{100, 101, 99, 100},
+		// open closer to low so the stop loss is reached first
+		{100, 106, 95, 100},
+		{100, 101, 99, 100},
+		{100, 101, 99, 100},
+		// gap below the stop loss fills at the open
+		{94, 95, 93, 94},
+	})
+	st := runStrategy(t, strategy.Opts{}, bars, func(st *strategy.Strategy, j int) {
+		if j%2 != 0 {
+			return
+		}
+		if err := st.Entry("long", strategy.Long, strategy.EntryOpts{}); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.Exit("bracket", strategy.ExitOpts{FromEntry: "long", Profit: 4, Loss: 4}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	compareTrades(t, bars, []expectedTrade{
+		{"long", strategy.Long, 1, 100, "bracket", 1, 104, 1, 4},
+		{"long", strategy.Long, 3, 100, "bracket", 3, 96, 1, -4},
+		{"long", strategy.Long, 5, 100, "bracket", 6, 94, 1, -6},
+	}, st.ClosedTrades())
+	// equity at the closes is 0, 4, 4, 0, 0, 0, -6
+	if st.MaxDrawdown() != 10 {
+		t.Errorf("expected drawdown 10 but got %v", st.MaxDrawdown())
+	}
+}
+
+func TestStrategyLimitAndStopOrders(t *testing.T) {
+	bars := strategyBars([][4]float64{
+		{100, 101, 99, 100},
+		// limit at 97 not reached
+		{100, 102, 98, 101},
+		// gap below the limit fills at the open
+		{96, 97, 95, 96},
+		{96, 97, 95, 96},
+		// take profit at 98 reached after the low
+		{96, 99, 94, 98},
+		{98, 99, 97, 98},
+		// stop-limit short with stop 95 and limit 96 reached on the way back
+		{98, 99, 94, 97},
+		{97, 98, 96, 97},
+	})
+	st := runStrategy(t, strategy.Opts{}, bars, func(st *strategy.Strategy, j int) {
+		var err error
+		switch j {
+		case 0:
+			err = st.Entry("buy", strategy.Long, strategy.EntryOpts{Limit: 97})
+		case 3:
+			err = st.Exit("tp", strategy.ExitOpts{Limit: 98})
+		case 5:
+			err = st.Entry("sell", strategy.Short, strategy.EntryOpts{Stop: 95, Limit: 96})
+		case 6:
+			st.CloseAll()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	compareTrades(t, bars, []expectedTrade{
+		{"buy", strategy.Long, 2, 96, "tp", 4, 98, 1, 2},
+		{"sell", strategy.Short, 6, 96, "", 7, 97, 1, -1},
+	}, st.ClosedTrades())
+}
+
+func TestStrategyExitWithoutPosition(t *testing.T) {
+	bars := strategyBars([][4]float64{
+		{100, 101, 99, 100},
+		{100, 101, 90, 100},
+		{100, 101, 99, 100},
+		{100, 101, 90, 100},
+	})
+	st := runStrategy(t, strategy.Opts{}, bars, func(st *strategy.Strategy, j int) {
+		switch j {
+		case 0:
+			// dropped without trades or entries to close
+			if err := st.Exit("sl", strategy.ExitOpts{Stop: 95}); err != nil {
+				t.Fatal(err)
+			}
+		case 1:
+			if err := st.Entry("long", strategy.Long, strategy.EntryOpts{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+	if len(st.ClosedTrades()) != 0 {
+		t.Errorf("expected no closed trades but got %+v", st.ClosedTrades())
+	}
+	if p := st.Position(); p.Size != 1 || p.AvgPrice != 100 {
+		t.Errorf("expected position 1 at 100 but got %+v", p)
+	}
+}
+
+func TestStrategyPyramidingAndCommission(t *testing.T) {
+	bars := strategyBars([][4]float64{
+		{100, 101, 99, 100},
+		{100, 101, 99, 100},
+		{110, 111, 109, 110},
+		{120, 121, 119, 120},
+		{120, 121, 119, 120},
+	})
+	opts := strategy.Opts{InitialCapital: 1000, Pyramiding: 2, CommissionPercent: 1, ProcessOrdersOnClose: true}
+	st := runStrategy(t, opts, bars, func(st *strategy.Strategy, j int) {
+		switch j {
+		case 0, 1, 2:
+			if err := st.Entry("long", strategy.Long, strategy.EntryOpts{}); err != nil {
+				t.Fatal(err)
+			}
+		case 3:
+			st.Close("long")
+		}
+	})
+	// market orders fill at the close of the bar they are placed on
+	compareTrades(t, bars, []expectedTrade{
+		{"long", strategy.Long, 0, 100, "long", 3, 120, 1, 20 - 1 - 1.2},
+		{"long", strategy.Long, 1, 100, "long", 3, 120, 1, 20 - 1 - 1.2},
+	}, st.ClosedTrades())
+	if math.Abs(st.Equity()-(1000+2*17.8)) > 1e-9 {
+		t.Errorf("expected equity %v but got %v", 1000+2*17.8, st.Equity())
+	}
+}
+
+func TestStrategyIndicators(t *testing.T) {
+	s, err := NewSeries(nil, SeriesOpts{Interval: 60, Max: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	close := NewOHLCProp(OHLCPropClose)
+	fast := NewSMA(close, 2)
+	slow := NewSMA(close, 4)
+	if err := s.AddIndicator("up", NewCrossover(fast, slow)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddIndicator("down", NewCrossunder(fast, slow)); err != nil {
+		t.Fatal(err)
+	}
+	st, err := strategy.New(s, strategy.Opts{InitialCapital: 100}, func(st *strategy.Strategy, itvl *Interval) {
+		if v := itvl.Indicators["up"]; v != nil && *v == 1 {
+			st.Entry("long", strategy.Long, strategy.EntryOpts{})
+		}
+		if v := itvl.Indicators["down"]; v != nil && *v == 1 {
+			st.Entry("short", strategy.Short, strategy.EntryOpts{})
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2022, 1, 3, 10, 0, 0, 0, time.UTC)
+	for _, c := range scriptCandles(start, 40) {
+		if err := s.AddOHLCV(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	trades := st.ClosedTrades()
+	if len(trades) == 0 {
+		t.Fatal("expected trades")
+	}
+	for j := 1; j < len(trades); j++ {
+		if trades[j].Direction == trades[j-1].Direction || !trades[j].EntryTime.Equal(trades[j-1].ExitTime) {
+			t.Errorf("expected trades to reverse but got %+v and %+v", trades[j-1], trades[j])
+		}
+	}
+	var profit float64
+	for _, tr := range trades {
+		profit += tr.Profit
+	}
+	if math.Abs(st.Equity()-(100+profit+st.OpenProfit())) > 1e-9 {
+		t.Errorf("expected equity of profits but got %v", st.Equity())
+	}
+
+	if _, err := strategy.New(s, strategy.Opts{Pyramiding: -1}, func(*strategy.Strategy, *Interval) {}); err == nil {
+		t.Error("expected error for negative pyramiding")
+	}
+}
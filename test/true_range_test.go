@@ -0,0 +1,51 @@
+package pine_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/xpt-nl/pine"
+)
+
+func TestTrueRange(t *testing.T) {
+	itvl := 300
+	opts := SeriesOpts{
+		Interval: itvl,
+		Max:      100,
+	}
+	candles := []OHLCV{
+		{H: 53.0, L: 51.9, C: 52.22},
+		{H: 53.2, L: 52.1, C: 52.78},
+		{H: 53.5, L: 52.6, C: 53.02},
+		{H: 54.0, L: 53.0, C: 53.67},
+		{H: 52.9, L: 52.3, C: 52.51},
+	}
+	io := []*float64{
+		nil,
+		fptr(1.1),
+		fptr(0.9),
+		fptr(1.0),
+		fptr(1.37),
+	}
+	now := time.Now()
+	for idx := range candles {
+		candles[idx].S = now.Add(time.Duration(idx*itvl) * time.Second)
+	}
+	s, err := NewSeries(candles, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := "tr"
+	if err := s.AddIndicator(name, NewTrueRange()); err != nil {
+		t.Fatal(err)
+	}
+	for idx, exp := range io {
+		v := s.GetValueForInterval(candles[idx].S)
+		if v == nil {
+			t.Fatalf("interval should not be nil at idx: %d", idx)
+		}
+		if err := compareValue(exp, v.Indicators[name], 0.000001); err != nil {
+			t.Errorf("idx %d: %+v", idx, err)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package pine_test
+
+import (
+	"fmt"
+	"math"
+)
+
+func fptr(f float64) *float64 {
+	return &f
+}
+
+// compareValue returns error if expected and actual differ more than tolerance
+func compareValue(exp, act *float64, tolerance float64) error {
+	if exp == nil && act == nil {
+		return nil
+	}
+	if exp == nil || act == nil {
+		return fmt.Errorf("expected both to be non nil but got %+v vs %+v", exp, act)
+	}
+	if math.Abs(*exp-*act) > tolerance {
+		return fmt.Errorf("expected %+v but got %+v", *exp, *act)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package pine_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/xpt-nl/pine"
+)
+
+func TestVWAP(t *testing.T) {
+	opts := SeriesOpts{
+		Interval: 3600,
+		Max:      100,
+	}
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %+v", err)
+	}
+	// midnight in New York is 05:00 UTC
+	start := time.Date(2024, 3, 5, 3, 0, 0, 0, time.UTC)
+	candles := []OHLCV{
+		{H: 10, L: 10, C: 10, V: 1},
+		{H: 20, L: 20, C: 20, V: 3},
+		{H: 30, L: 30, C: 30, V: 2},
+		{H: 40, L: 40, C: 40, V: 2},
+	}
+	for idx := range candles {
+		candles[idx].S = start.Add(time.Duration(idx) * time.Hour)
+	}
+	io := []struct {
+		vwap  float64
+		upper float64
+		lower float64
+	}{
+		{vwap: 10, upper: 10, lower: 10},
+		{vwap: 17.5, upper: 17.5 + 2*4.330127, lower: 17.5 - 2*4.330127},
+		{vwap: 30, upper: 30, lower: 30},
+		{vwap: 35, upper: 45, lower: 25},
+	}
+	s, err := NewSeries(candles, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := NewVWAP(VWAPOpts{
+		Anchor:    VWAPAnchorDay,
+		Location:  ny,
+		BandMults: []float64{1, 2},
+	})
+	if err := s.AddIndicator("vwap", v.VWAP); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddIndicator("upper", v.Upper[1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddIndicator("lower", v.Lower[1]); err != nil {
+		t.Fatal(err)
+	}
+	for idx, o := range io {
+		itvl := s.GetValueForInterval(candles[idx].S)
+		if itvl == nil {
+			t.Fatalf("interval should not be nil at idx: %d", idx)
+		}
+		expected := map[string]float64{
+			"vwap":  o.vwap,
+			"upper": o.upper,
+			"lower": o.lower,
+		}
+		for name, exp := range expected {
+			if err := compareValue(&exp, itvl.Indicators[name], 0.00001); err != nil {
+				t.Errorf("%s at idx %d: %+v", name, idx, err)
+			}
+		}
+	}
+}
+
+func TestVWAPAnchors(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %+v", err)
+	}
+	io := []struct {
+		name   string
+		opts   VWAPOpts
+		start  time.Time
+		resets []bool
+	}{
+		{
+			// futures session starts 17:00 New York which is 22:00 UTC
+			name: "session",
+			opts: VWAPOpts{
+				Anchor:       VWAPAnchorSession,
+				Location:     ny,
+				SessionStart: 17 * time.Hour,
+			},
+			start:  time.Date(2024, 3, 5, 20, 0, 0, 0, time.UTC),
+			resets: []bool{true, false, true, false},
+		},
+		{
+			// 2024-03-04 is a Monday
+			name:   "week",
+			opts:   VWAPOpts{Anchor: VWAPAnchorWeek},
+			start:  time.Date(2024, 3, 3, 22, 0, 0, 0, time.UTC),
+			resets: []bool{true, false, true, false},
+		},
+		{
+			name:   "month",
+			opts:   VWAPOpts{Anchor: VWAPAnchorMonth},
+			start:  time.Date(2024, 2, 29, 22, 0, 0, 0, time.UTC),
+			resets: []bool{true, false, true, false},
+		},
+	}
+	for _, o := range io {
+		candles := make([]OHLCV, len(o.resets))
+		for idx := range candles {
+			px := float64(idx + 1)
+			candles[idx] = OHLCV{H: px, L: px, C: px, V: 1, S: o.start.Add(time.Duration(idx) * time.Hour)}
+		}
+		s, err := NewSeries(candles, SeriesOpts{Interval: 3600, Max: 100})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.AddIndicator(o.name, NewVWAP(o.opts).VWAP); err != nil {
+			t.Fatal(err)
+		}
+		for idx, reset := range o.resets {
+			v := s.GetValueForInterval(candles[idx].S)
+			if v == nil || v.Indicators[o.name] == nil {
+				t.Fatalf("expected %s vwap to be non nil at idx: %d", o.name, idx)
+			}
+			// a reset makes vwap equal to the price of the interval
+			if (*v.Indicators[o.name] == candles[idx].C) != reset {
+				t.Errorf("expected %s reset to be %t at idx %d but got vwap %+v", o.name, reset, idx, *v.Indicators[o.name])
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package pine_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/xpt-nl/pine"
+)
+
+func TestWilliamsR(t *testing.T) {
+	itvl := 300
+	opts := SeriesOpts{
+		Interval: itvl,
+		Max:      100,
+	}
+	candles := []OHLCV{
+		{H: 53.0, L: 51.9, C: 52.22},
+		{H: 53.2, L: 52.1, C: 52.78},
+		{H: 53.5, L: 52.6, C: 53.02},
+		{H: 54.0, L: 53.0, C: 53.67},
+		{H: 53.9, L: 53.1, C: 53.67},
+		{H: 54.1, L: 53.3, C: 53.74},
+		{H: 53.8, L: 53.2, C: 53.45},
+		{H: 53.9, L: 53.3, C: 53.72},
+		{H: 53.6, L: 53.0, C: 53.39},
+		{H: 52.9, L: 52.3, C: 52.51},
+		{H: 52.6, L: 51.9, C: 52.32},
+		{H: 51.9, L: 51.2, C: 51.45},
+	}
+	io := []*float64{
+		nil,
+		nil,
+		nil,
+		fptr(-15.714286),
+		fptr(-17.368421),
+		fptr(-24.0),
+		fptr(-59.090909),
+		fptr(-38.0),
+		fptr(-64.545455),
+		fptr(-86.875),
+		fptr(-79.0),
+		fptr(-89.583333),
+	}
+	now := time.Now()
+	for idx := range candles {
+		candles[idx].S = now.Add(time.Duration(idx*itvl) * time.Second)
+	}
+	s, err := NewSeries(candles, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := "wpr"
+	if err := s.AddIndicator(name, NewWilliamsR(4)); err != nil {
+		t.Fatal(err)
+	}
+	for idx, exp := range io {
+		v := s.GetValueForInterval(candles[idx].S)
+		if v == nil {
+			t.Fatalf("interval should not be nil at idx: %d", idx)
+		}
+		if err := compareValue(exp, v.Indicators[name], 0.000001); err != nil {
+			t.Errorf("idx %d: %+v", idx, err)
+		}
+	}
+}
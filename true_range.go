@@ -0,0 +1,68 @@
+package pine
+
+import "time"
+
+type tr struct {
+	valueStore
+	// closes include the one before the oldest kept interval so any kept
+	// interval can be generated again
+	closes *ring[float64]
+}
+
+// NewTrueRange creates a new true range indicator reading high, low and
+// previous close of OHLCV. Like Pine's ta.tr it has no value on the first
+// interval since there is no previous close
+func NewTrueRange() Indicator {
+	return &tr{
+		closes: newRing[float64](defaultMax, 0),
+	}
+}
+
+func (i *tr) Update(v OHLCV) error {
+	return i.updateNode(v)
+}
+
+func (i *tr) inputs() []node {
+	return nil
+}
+
+func (i *tr) updateNode(v OHLCV) error {
+	if i.closes.len() > 0 && v.S.Before(i.closes.lastTime()) {
+		// already generated
+		return nil
+	}
+	prev, ok := i.closes.prior(v.S)
+	i.closes.set(v.S, v.C)
+	if !ok {
+		return nil
+	}
+	i.setValue(v.S, trueRange(v, prev))
+	return nil
+}
+
+func (i *tr) ApplyOpts(opts SeriesOpts) error {
+	i.initStore(opts)
+	i.closes = historyRing(i.closes, opts)
+	return nil
+}
+
+func (i *tr) reset() {
+	i.resetStore()
+	i.closes.clear()
+}
+
+func (i *tr) rewind(t time.Time) error {
+	i.rewindStore(t)
+	i.closes.rewind(t)
+	return nil
+}
+
+func (i *tr) encodeState(e *encoder) {
+	i.valueStore.encode(e)
+	encodeRing(e, i.closes, putFloat)
+}
+
+func (i *tr) decodeState(d *decoder) {
+	i.valueStore.decode(d)
+	decodeRing(d, i.closes, getFloat)
+}
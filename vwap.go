@@ -0,0 +1,229 @@
+package pine
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// VWAPAnchor defines the period after which VWAP resets
+type VWAPAnchor int
+
+const (
+	// VWAPAnchorSession resets VWAP at the start of the trading session
+	VWAPAnchorSession VWAPAnchor = iota
+	// VWAPAnchorDay resets VWAP at midnight
+	VWAPAnchorDay
+	// VWAPAnchorWeek resets VWAP at midnight on Monday
+	VWAPAnchorWeek
+	// VWAPAnchorMonth resets VWAP at midnight on the first day of the month
+	VWAPAnchorMonth
+)
+
+// VWAPOpts defines anchoring and bands of VWAP
+type VWAPOpts struct {
+	// Anchor is the period after which VWAP resets
+	Anchor VWAPAnchor
+	// Location is the time zone anchors are computed in. Defaults to UTC
+	Location *time.Location
+	// SessionStart is the offset from midnight the session starts at,
+	// e.g. 17 hours for futures trading in America/New_York
+	SessionStart time.Duration
+	// BandMults are the standard deviation multipliers of the bands
+	BandMults []float64
+}
+
+// VWAP holds the outputs of the VWAP indicator.
+// Each output can be registered on Series separately
+type VWAP struct {
+	// VWAP is the volume weighted average of typical price since the anchor
+	VWAP Indicator
+	// Upper is VWAP plus standard deviation for each of VWAPOpts.BandMults
+	Upper []Indicator
+	// Lower is VWAP minus standard deviation for each of VWAPOpts.BandMults
+	Lower []Indicator
+}
+
+type vwap struct {
+	opts VWAPOpts
+	// states include the one before the oldest kept interval so any kept
+	// interval can be generated again
+	states    *ring[vwapState]
+	genvalues *ring[vwapValue]
+}
+
+type vwapState struct {
+	Time   time.Time
+	Anchor time.Time
+	SumV   float64
+	SumPV  float64
+	SumP2V float64
+}
+
+// vwapValue keeps outputs of an interval where VWAP is NaN if na
+type vwapValue struct {
+	VWAP   float64
+	StdDev float64
+}
+
+// NewVWAP creates a new VWAP indicator like Pine's ta.vwap using the typical
+// price (high + low + close) / 3 and volume of OHLCV
+func NewVWAP(opts VWAPOpts) VWAP {
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+	v := &vwap{
+		opts:      opts,
+		states:    newRing[vwapState](defaultMax, 0),
+		genvalues: newRing[vwapValue](defaultMax, 0),
+	}
+	out := VWAP{
+		VWAP:  newOutput(v, 0),
+		Upper: make([]Indicator, len(opts.BandMults)),
+		Lower: make([]Indicator, len(opts.BandMults)),
+	}
+	// band outputs follow VWAP as upper and lower pairs
+	for j := range opts.BandMults {
+		out.Upper[j] = newOutput(v, 1+j*2)
+		out.Lower[j] = newOutput(v, 2+j*2)
+	}
+	return out
+}
+
+func (i *vwap) getOutput(t time.Time, idx int) (float64, bool) {
+	v, ok := i.genvalues.getTime(t)
+	if !ok || math.IsNaN(v.VWAP) {
+		return 0, false
+	}
+	if idx == 0 {
+		return v.VWAP, true
+	}
+	mult := i.opts.BandMults[(idx-1)/2]
+	var band float64
+	if idx%2 == 1 {
+		band = v.VWAP + v.StdDev*mult
+	} else {
+		band = v.VWAP - v.StdDev*mult
+	}
+	return band, true
+}
+
+func (i *vwap) Update(v OHLCV) error {
+	return i.updateNode(v)
+}
+
+func (i *vwap) inputs() []node {
+	return nil
+}
+
+func (i *vwap) updateNode(v OHLCV) error {
+	if i.states.len() > 0 && v.S.Before(i.states.lastTime()) {
+		// already generated
+		return nil
+	}
+	anchor := i.anchorStart(v.S)
+	cur, _ := i.states.prior(v.S)
+	if !cur.Anchor.Equal(anchor) {
+		cur = vwapState{Anchor: anchor}
+	}
+	tp := (v.H + v.L + v.C) / 3
+	cur.Time = v.S
+	cur.SumV += v.V
+	cur.SumPV += tp * v.V
+	cur.SumP2V += tp * tp * v.V
+	i.states.set(v.S, cur)
+	if cur.SumV == 0 {
+		// no volume since anchor so vwap is na
+		i.genvalues.set(v.S, vwapValue{VWAP: math.NaN()})
+		return nil
+	}
+	avg := cur.SumPV / cur.SumV
+	i.genvalues.set(v.S, vwapValue{
+		VWAP:   avg,
+		StdDev: math.Sqrt(math.Max(cur.SumP2V/cur.SumV-avg*avg, 0)),
+	})
+	return nil
+}
+
+// anchorStart returns the start of the anchor period t belongs to
+func (i *vwap) anchorStart(t time.Time) time.Time {
+	lt := t.In(i.opts.Location)
+	if i.opts.Anchor == VWAPAnchorSession {
+		lt = lt.Add(-i.opts.SessionStart)
+	}
+	year, month, day := lt.Date()
+	switch i.opts.Anchor {
+	case VWAPAnchorSession:
+		return time.Date(year, month, day, 0, 0, 0, 0, i.opts.Location).Add(i.opts.SessionStart)
+	case VWAPAnchorWeek:
+		// weeks start on Monday
+		offset := (int(lt.Weekday()) + 6) % 7
+		return time.Date(year, month, day-offset, 0, 0, 0, 0, i.opts.Location)
+	case VWAPAnchorMonth:
+		return time.Date(year, month, 1, 0, 0, 0, 0, i.opts.Location)
+	default:
+		return time.Date(year, month, day, 0, 0, 0, 0, i.opts.Location)
+	}
+}
+
+func (i *vwap) ApplyOpts(opts SeriesOpts) error {
+	if i.opts.Anchor < VWAPAnchorSession || i.opts.Anchor > VWAPAnchorMonth {
+		return errors.New("unsupported VWAP anchor")
+	}
+	if i.genvalues.capacity() != opts.Max {
+		i.genvalues = newSeriesRing[vwapValue](opts)
+	}
+	i.states = historyRing(i.states, opts)
+	return nil
+}
+
+func (i *vwap) reset() {
+	i.states.clear()
+	i.genvalues.clear()
+}
+
+func (i *vwap) rewind(t time.Time) error {
+	i.states.rewind(t)
+	i.genvalues.rewind(t)
+	return nil
+}
+
+func (i *vwap) encodeState(e *encoder) {
+	encodeRing(e, i.states, func(e *encoder, s vwapState) {
+		s.encode(e)
+	})
+	encodeRing(e, i.genvalues, func(e *encoder, v vwapValue) {
+		e.float(v.VWAP)
+		e.float(v.StdDev)
+	})
+}
+
+func (i *vwap) decodeState(d *decoder) {
+	decodeRing(d, i.states, func(d *decoder) vwapState {
+		var s vwapState
+		s.decode(d)
+		return s
+	})
+	decodeRing(d, i.genvalues, func(d *decoder) vwapValue {
+		return vwapValue{
+			VWAP:   d.float(),
+			StdDev: d.float(),
+		}
+	})
+}
+
+func (s vwapState) encode(e *encoder) {
+	e.time(s.Time)
+	e.time(s.Anchor)
+	e.float(s.SumV)
+	e.float(s.SumPV)
+	e.float(s.SumP2V)
+}
+
+func (s *vwapState) decode(d *decoder) {
+	s.Time = d.time()
+	s.Anchor = d.time()
+	s.SumV = d.float()
+	s.SumPV = d.float()
+	s.SumP2V = d.float()
+}
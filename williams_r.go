@@ -0,0 +1,99 @@
+package pine
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+type wpr struct {
+	valueStore
+	lookback int
+	last     time.Time
+	high     Indicator
+	low      Indicator
+}
+
+// NewWilliamsR creates a new Williams %R indicator reading high, low and close
+// of OHLCV like Pine's ta.wpr. Values range from -100 to 0
+func NewWilliamsR(lookback int) Indicator {
+	return &wpr{
+		lookback: lookback,
+		high:     NewHighest(NewOHLCProp(OHLCPropHigh), lookback),
+		low:      NewLowest(NewOHLCProp(OHLCPropLow), lookback),
+	}
+}
+
+func (i *wpr) Update(v OHLCV) error {
+	if err := i.high.Update(v); err != nil {
+		return fmt.Errorf("error updating high in WilliamsR: %w", err)
+	}
+	if err := i.low.Update(v); err != nil {
+		return fmt.Errorf("error updating low in WilliamsR: %w", err)
+	}
+	return i.updateNode(v)
+}
+
+func (i *wpr) inputs() []node {
+	return nodesOf(i.high, i.low)
+}
+
+func (i *wpr) updateNode(v OHLCV) error {
+	if !i.last.IsZero() && v.S.Before(i.last) {
+		// already generated
+		return nil
+	}
+	i.last = v.S
+	h, ok := valueOf(i.high, v.S)
+	l, ok2 := valueOf(i.low, v.S)
+	if !ok || !ok2 || h == l {
+		// division by zero is na in Pine
+		i.unsetValue(v.S)
+		return nil
+	}
+	i.setValue(v.S, 100*(v.C-h)/(h-l))
+	return nil
+}
+
+func (i *wpr) ApplyOpts(opts SeriesOpts) error {
+	if opts.Max < i.lookback {
+		return errors.New("SeriesOpts max cannot be less than WilliamsR lookback value")
+	}
+	if err := i.high.ApplyOpts(opts); err != nil {
+		return fmt.Errorf("error applying opts in high: %w", err)
+	}
+	if err := i.low.ApplyOpts(opts); err != nil {
+		return fmt.Errorf("error applying opts in low: %w", err)
+	}
+	i.initStore(opts)
+	return nil
+}
+
+func (i *wpr) reset() {
+	resetIndicator(i.high)
+	resetIndicator(i.low)
+	i.resetStore()
+	i.last = time.Time{}
+}
+
+func (i *wpr) rewind(t time.Time) error {
+	if err := rewindIndicator(i.high, t); err != nil {
+		return err
+	}
+	if err := rewindIndicator(i.low, t); err != nil {
+		return err
+	}
+	i.rewindStore(t)
+	i.last = time.Time{}
+	return nil
+}
+
+func (i *wpr) encodeState(e *encoder) {
+	i.valueStore.encode(e)
+	e.time(i.last)
+}
+
+func (i *wpr) decodeState(d *decoder) {
+	i.valueStore.decode(d)
+	i.last = d.time()
+}